@@ -149,3 +149,17 @@ func main int {
 
 	return 0
 }
+
+
+# the escape time of a point deep in the set, which takes every iteration
+bench "mandelconverge" {
+	mandelconverge(-0.9250001355432285, 0.2660002226258663, 512);
+}
+
+bench "mandelconverge grid" {
+	for y = -1.0; y < 1.0; y += 0.125 {
+		for x = -2.0; x < 1.0; x += 0.125 {
+			mandelconverge(x, y, 64);
+		}
+	}
+}
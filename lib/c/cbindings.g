@@ -3,14 +3,14 @@ is c
 
 class FILE {}
 
-func tmpfile FILE* ...
-func fopen(string path, string mode) FILE* ...
+func tmpfile FILE*? ...
+func fopen(string path, string mode) FILE*? ...
 func fseek(FILE* handle, int offset, int whence) int ...
 func ftell(FILE* handle) long ...
 func rewind(FILE* handle) ...
-func fread(string where, int size, int nmemb, FILE* handle) long ...
-func fwrite(string what, int size, int nmemb, FILE* handle) ...
-func fclose(FILE* handle) ...
+func fread(string where, long size, long nmemb, FILE* handle) long ...
+func fwrite(string what, long size, long nmemb, FILE* handle) long ...
+func fclose(FILE* handle) int ...
 func getenv(string what) string ...
 func fgetc(FILE* handle) string ...
 func fflush(FILE* handle) int ...
@@ -0,0 +1,28 @@
+#ifndef __GEODEgc__
+#define __GEODEgc__
+
+// Programs built with --sanitize use malloc in place of the garbage
+// collector, because the sanitizers can't track memory that the collector
+// hands out. WASI programs do too, as the collector isn't ported to wasm.
+// Nothing is collected in these builds, so only the parts of the collector
+// the runtime uses are stood in for.
+#if defined(GEODE_NO_GC) || defined(__wasi__)
+
+#include <stdlib.h>
+
+#define GC_PTR void *
+#define GC_MALLOC(size) malloc(size)
+#define GC_REALLOC(ptr, size) realloc(ptr, size)
+#define GC_FREE(ptr) free(ptr)
+#define GC_register_finalizer(obj, fn, data, oldfn, olddata) ((void)(fn))
+
+void GC_init(void);
+void GC_gcollect(void);
+
+#else
+
+#include <gc/gc.h>
+
+#endif
+
+#endif
@@ -1,6 +1,6 @@
 #ifndef __MEM__
 #define __MEM__
 
-#include <gc/gc.h>
+#include "geodegc.h"
 
 #endif
\ No newline at end of file
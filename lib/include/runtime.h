@@ -2,6 +2,10 @@
 #define __GEODEruntime__
 
 #include "xmalloc.h"
-#include <gc/gc.h>
+#include "geodegc.h"
+
+// trace.c
+void __trace_install(void);
+void __trace_print(int skip);
 
 #endif
\ No newline at end of file
@@ -3,7 +3,7 @@
 
 #include <stdlib.h>
 
-#include <gc/gc.h>
+#include "geodegc.h"
 
 typedef struct {
   long size;
@@ -21,6 +21,8 @@ typedef struct {
 
 xmalloc_stat_t xmemstat();
 long xmalloc_size(void *ptr);
+long bytes_used();
+long blocks_used();
 void xfree(void *ptr);
 void *xmalloc(size_t size);
 void *xcalloc(unsigned count, unsigned size);
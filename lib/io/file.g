@@ -20,6 +20,45 @@ c:FILE* stdout = get_default_file_descriptor(1)
 c:FILE* stderr = get_default_file_descriptor(2)
 
 
+# Checked file functions. The c ones they wrap return NULL, EOF or a short
+# count when they fail, which is easy to miss, so these return a result
+# with an error saying why instead
+
+# open opens the file at a path with a mode like fopen's, "r" to read it
+# or "w" to write it
+func open(string path, string mode) c:FILE*! {
+	c:FILE*? f = c:fopen(path, mode);
+	if f == nil {
+		return syserror(path);
+	}
+	return f;
+}
+
+# read reads up to size bytes from a file into buf and returns how many it
+# read, which is less than size at the end of the file
+func read(c:FILE* f, byte* buf, long size) long! {
+	long n = c:fread(buf, 1, size, f);
+	if c:ferror(f) != 0 {
+		return syserror("read");
+	}
+	return n;
+}
+
+# write writes size bytes from buf to a file
+func write(c:FILE* f, byte* buf, long size) void! {
+	if c:fwrite(buf, 1, size, f) < size {
+		return syserror("write");
+	}
+}
+
+# close flushes what was written to a file and closes it
+func close(c:FILE* f) void! {
+	if c:fclose(f) != 0 {
+		return syserror("close");
+	}
+}
+
+
 # func fgets(byte* buf, int len, FILE_DESCRIPTOR* fd) ...
 
 
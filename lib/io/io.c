@@ -5,7 +5,7 @@
 #include "../include/mem.h"
 #include "../include/xmalloc.h"
 #include "io.h"
-#include <gc/gc.h>
+#include "../include/geodegc.h"
 
 // the print function wrapper.
 void print(char *fmt, ...) {
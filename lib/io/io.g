@@ -7,7 +7,7 @@ link "io.c"
 
 # Printing bindings
 func print(string format, ...) ...
-func fprintf(FILE* handle, string format, ...) ...
+func fprintf(c:FILE* handle, string format, ...) ...
 
 
 func println(string message) {
@@ -2,4 +2,4 @@
 #include <stdio.h>
 #include <stdlib.h>
 
-#include <gc/gc.h>
+#include "../include/geodegc.h"
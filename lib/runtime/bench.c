@@ -0,0 +1,81 @@
+// clock_gettime is POSIX, and the runtime is built as plain c99
+#define _POSIX_C_SOURCE 199309L
+
+#include "../include/runtime.h"
+
+#include <stdio.h>
+#include <stdlib.h>
+#include <time.h>
+
+// The benchmark harness that the generated bench main calls. It runs
+// the loop over a bench block with more and more iterations until the
+// loop takes at least the benchmark time, then reports the last run.
+
+#define BENCH_MAX_ITERATIONS 1000000000L
+
+static long bench_time = 1000000000L; // nanoseconds
+static long bench_start_ns = 0;
+static long bench_start_bytes = 0;
+static long bench_start_blocks = 0;
+
+long nanotime() {
+  struct timespec ts;
+  clock_gettime(CLOCK_MONOTONIC, &ts);
+  return ts.tv_sec * 1000000000L + ts.tv_nsec;
+}
+
+static void bench_reset() {
+  // Collect first so garbage from the last run isn't timed
+  GC_gcollect();
+  bench_start_bytes = bytes_used();
+  bench_start_blocks = blocks_used();
+  bench_start_ns = nanotime();
+}
+
+long __bench_start(int argc, char **argv) {
+  if (argc > 2) {
+    bench_time = atol(argv[2]);
+  }
+  bench_reset();
+  return 1;
+}
+
+long __bench_stop(char *name, long n) {
+  long elapsed = nanotime() - bench_start_ns;
+  long bytes = bytes_used() - bench_start_bytes;
+  long blocks = blocks_used() - bench_start_blocks;
+
+  if (elapsed < bench_time && n < BENCH_MAX_ITERATIONS) {
+    // Predict how many iterations would take the benchmark time, with a
+    // bit extra, but don't grow too fast in case the first runs were noisy
+    long last = n;
+    long per = elapsed / n;
+    if (per <= 0) {
+      per = 1;
+    }
+    n = bench_time / per;
+    n += n / 5;
+    if (n > last * 100) {
+      n = last * 100;
+    }
+    if (n <= last) {
+      n = last + 1;
+    }
+    if (n > BENCH_MAX_ITERATIONS) {
+      n = BENCH_MAX_ITERATIONS;
+    }
+    bench_reset();
+    return n;
+  }
+
+  if (bytes < 0) {
+    bytes = 0;
+  }
+  if (blocks < 0) {
+    blocks = 0;
+  }
+  printf("%s\t%ld\t%.2f ns/op\t%ld B/op\t%ld allocs/op\n", name, n,
+         (double)elapsed / n, bytes / n, blocks / n);
+  fflush(stdout);
+  return 0;
+}
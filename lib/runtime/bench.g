@@ -0,0 +1,19 @@
+is runtime
+
+link "bench.c"
+
+# the benchmarking section of runtime has the clock and the harness
+# that the bench blocks of a package are run with
+
+# nanotime returns the time of a monotonic clock in nanoseconds. It
+#          is only useful for measuring how long something took
+func nanotime() long ...
+
+# __bench_start reads the benchmark time from the arguments of the
+# program and starts timing. It returns the first iteration count
+func __bench_start(int argc, byte** argv) long ...
+
+# __bench_stop stops timing a run of n iterations and returns how many
+# iterations to run next, or reports the benchmark and returns 0 once
+# a run took long enough
+func __bench_stop(byte* name, long n) long ...
@@ -0,0 +1,40 @@
+#include <stdio.h>
+#include <stdlib.h>
+
+// The counters of a program built with --cover. Each counts the runs
+// of the statement at the same index in the locations.
+
+static long **cover_counters = NULL;
+static char **cover_locations = NULL;
+static long cover_count = 0;
+static char *cover_profile = "geode.cover";
+
+// cover_dump writes the counters to the profile named by the
+// GEODECOVERPROFILE environment variable, or the one passed to
+// --coverprofile when the program was built
+static void cover_dump(void) {
+  char *path = getenv("GEODECOVERPROFILE");
+  if (path == NULL || *path == '\0') {
+    path = cover_profile;
+  }
+
+  FILE *f = fopen(path, "w");
+  if (f == NULL) {
+    fprintf(stderr, "cover: unable to write the profile to %s\n", path);
+    return;
+  }
+  fputs("mode: count\n", f);
+  for (long i = 0; i < cover_count; i++) {
+    fprintf(f, "%s %ld\n", cover_locations[i], *cover_counters[i]);
+  }
+  fclose(f);
+}
+
+void __cover_register(long **counters, char **locations, long n,
+                      char *profile) {
+  cover_counters = counters;
+  cover_locations = locations;
+  cover_count = n;
+  cover_profile = profile;
+  atexit(cover_dump);
+}
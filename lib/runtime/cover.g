@@ -0,0 +1,11 @@
+is runtime
+
+link "cover.c"
+
+# the coverage section of runtime keeps the statement counters of
+# a program built with --cover and writes them to a profile at exit
+
+# __cover_register is called by main with the counters and the
+# locations of the statements they count, and the profile the program
+# was built to write them to
+func __cover_register(long** counters, byte** locations, long n, byte* profile) ...
@@ -0,0 +1,41 @@
+#include <errno.h>
+#include <stdarg.h>
+#include <stdio.h>
+#include <string.h>
+
+#include "../include/runtime.h"
+
+// The layout of the Error class in error.g
+struct error {
+  char *message;
+  int code;
+};
+
+static struct error *error_new(char *message, int code) {
+  struct error *e = xmalloc(sizeof(struct error));
+  e->message = message;
+  e->code = code;
+  return e;
+}
+
+struct error *errorf(char *fmt, ...) {
+  va_list args;
+  va_start(args, fmt);
+  long size = vsnprintf(NULL, 0, fmt, args);
+  va_end(args);
+  char *message = xmalloc(size + 1);
+  va_start(args, fmt);
+  vsnprintf(message, size + 1, fmt, args);
+  va_end(args);
+  return error_new(message, 0);
+}
+
+struct error *syserror(char *what) {
+  // Making the message could change errno
+  int code = errno;
+  char *reason = strerror(code);
+  long size = strlen(what) + strlen(reason) + 2;
+  char *message = xmalloc(size + 1);
+  snprintf(message, size + 1, "%s: %s", what, reason);
+  return error_new(message, code);
+}
@@ -0,0 +1,26 @@
+is runtime
+
+link "error.c"
+
+# the error section of runtime is what functions that return a result,
+# like int!, fail with. A result holds either its value or an error,
+# which `try` passes on to the caller and `or` replaces with a fallback
+
+# Error is why a function that returns a result failed
+class Error {
+	# what went wrong, which is shown to the user
+	string message
+
+	# the errno of the call to the operating system that failed, or 0
+	# if the error didn't come from one
+	int code
+}
+
+# errorf returns a new error with a message made from a format and its
+# arguments, like printf
+func errorf(string format, ...) Error* ...
+
+# syserror returns an error for the call to the operating system that
+# just failed, from errno. The message is what was being done followed
+# by why it failed, like "config.txt: No such file or directory"
+func syserror(string what) Error* ...
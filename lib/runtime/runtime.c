@@ -11,6 +11,7 @@ void __init_c_runtime() {
   atexit(exit_handle);
   GC_init();
   // GC_enable_incremental();
+  __trace_install();
 }
 
 void fatalf(int err, char *fmt, ...) {
@@ -18,11 +19,28 @@ void fatalf(int err, char *fmt, ...) {
   va_list vargs;
   va_start(vargs, fmt);
   vfprintf(stderr, fmt, vargs);
-  printf("\n");
+  fputs("\n", stderr);
   va_end(vargs);
+  __trace_print(1);
   exit(err);
 }
 
+void __panic_at(char *location, char *msg) {
+  fprintf(stderr, "panic: %s\n", msg);
+  if (*location != '\0') {
+    fprintf(stderr, "\tat %s\n", location);
+  }
+  __trace_print(1);
+  exit(2);
+}
+
+int __test_selected(int argc, char **argv) {
+  if (argc < 2) {
+    return -1;
+  }
+  return atoi(argv[1]);
+}
+
 char *__runtime_str_format(char *fmt, ...) {
   va_list checkArgs;
   va_start(checkArgs, fmt);
@@ -50,11 +50,23 @@ func __init_runtime() {
 # typeinfo is what is returned from the info(T) call.
 # The instance contains information about the type T
 class TypeInfo {
-	# The size in bytes of the type
+	# The size in bytes of the type, with the padding at the end that keeps
+	# the next one in an array aligned, like sizeof in C
 	int size
 
 	# the name of the type
 	string name
+
+	# The alignment in bytes of the type
+	int align
+
+	# How many fields the type has, if it is a class
+	int fieldcount
+
+	# The names of the fields, and their offsets in bytes from the start of
+	# an instance. Both are null if the type isn't a class
+	string* fieldnames
+	int* offsets
 }
 
 
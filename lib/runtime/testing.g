@@ -18,3 +18,15 @@ func assert(byte* msg, bool case) {
 		fatalf(-1, "Assertion Failed: %s", msg) # simply fatally log to stderr
 	}
 }
+
+# __assert_at is what calls to assert are compiled to. It also
+# logs the file and line of the assertion that failed
+func __assert_at(byte* location, byte* msg, bool case) {
+	if !case {
+		fatalf(-1, "%s: Assertion Failed: %s", location, msg)
+	}
+}
+
+# __test_selected returns the index of the test block the generated
+# test main should run, or -1 if it should run all of them
+func __test_selected(int argc, byte** argv) int ...
@@ -0,0 +1,260 @@
+// The runtime is built as C99, which leaves out the signal handling of
+// POSIX that this needs
+#define _DEFAULT_SOURCE
+
+#include <execinfo.h>
+#include <signal.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "../include/runtime.h"
+
+// The stack traces of panics and crashes. The compiler registers a table of
+// the geode functions in a program or library before main runs, and the
+// return addresses of a trace are looked up in it. A function is the one
+// that starts closest before an address, up to the end of the table.
+//
+// Return addresses don't say which line a call was made on, so the geode
+// functions that call others also push a frame with the location of the
+// call they are making, which a trace shows instead of where they were
+// declared.
+
+#define TRACE_DEPTH 64
+
+struct trace_func {
+  char *addr;
+  char *name;
+  char *location;
+};
+
+struct trace_table {
+  struct trace_func *funcs; // sorted by address
+  long count;
+  char *end; // where the last of the functions ends, or NULL if unknown
+  struct trace_table *next;
+};
+
+static struct trace_table *trace_tables = NULL;
+
+struct trace_frame {
+  struct trace_frame *prev;
+  char *func;
+  char *location;
+};
+
+// The innermost frame of the thread, which the compiled functions push and
+// pop themselves
+__thread struct trace_frame *__trace_top = NULL;
+
+static int trace_compare(const void *a, const void *b) {
+  char *x = ((struct trace_func *)a)->addr;
+  char *y = ((struct trace_func *)b)->addr;
+  return x < y ? -1 : x > y;
+}
+
+void __trace_register(void **funcs, char **names, char **locations, long n,
+                      void *end) {
+  struct trace_table *t = malloc(sizeof(struct trace_table));
+  t->funcs = malloc((n + 1) * sizeof(struct trace_func));
+  for (long i = 0; i < n; i++) {
+    t->funcs[i].addr = funcs[i];
+    t->funcs[i].name = names[i];
+    t->funcs[i].location = locations[i];
+  }
+  qsort(t->funcs, n, sizeof(struct trace_func), trace_compare);
+  t->count = n;
+  t->end = end;
+  // The linker is free to move the end elsewhere, in which case the last
+  // function is taken to go on until the next table
+  if (n > 0 && t->end <= t->funcs[n - 1].addr) {
+    t->end = NULL;
+  }
+  t->next = trace_tables;
+  trace_tables = t;
+}
+
+// trace_lookup returns the geode function an address is in, or NULL
+static struct trace_func *trace_lookup(char *pc) {
+  for (struct trace_table *t = trace_tables; t != NULL; t = t->next) {
+    if (t->count == 0 || pc < t->funcs[0].addr ||
+        (t->end != NULL && pc >= t->end)) {
+      continue;
+    }
+    long lo = 0, hi = t->count - 1;
+    while (lo < hi) {
+      long mid = (lo + hi + 1) / 2;
+      if (t->funcs[mid].addr <= pc) {
+        lo = mid;
+      } else {
+        hi = mid - 1;
+      }
+    }
+    return &t->funcs[lo];
+  }
+  return NULL;
+}
+
+// trace_location returns the location of the call a function was making,
+// from the first of the frames that is the function's, and moves the
+// frames past it. Functions that don't call others have no frame, and
+// ones that were inlined have a frame but no return address, so frames
+// that aren't the function's are skipped. Without a frame, it is where the
+// function was declared
+static char *trace_location(struct trace_frame **frames,
+                            struct trace_func *fn) {
+  for (struct trace_frame *f = *frames; f != NULL; f = f->prev) {
+    if (f->func == fn->addr) {
+      *frames = f->prev;
+      return f->location;
+    }
+  }
+  return fn->location;
+}
+
+// trace_symbol writes the name the dynamic linker has for an address that
+// isn't in a geode function, like a function of libc
+static void trace_symbol(void *pc) {
+  char **symbols = backtrace_symbols(&pc, 1);
+  char *name = NULL;
+  if (symbols != NULL) {
+    // Symbols look like /lib/libc.so.6(strlen+0x1d) [0x7f...]
+    char *start = strchr(symbols[0], '(');
+    char *stop = start == NULL ? NULL : strpbrk(start, "+)");
+    if (stop != NULL && stop > start + 1) {
+      name = start + 1;
+      *stop = '\0';
+    }
+  }
+  if (name != NULL) {
+    fprintf(stderr, "\t%s\n", name);
+  } else {
+    fprintf(stderr, "\t?? %p\n", pc);
+  }
+  free(symbols);
+}
+
+void __trace_print(int skip) {
+  void *pcs[TRACE_DEPTH];
+  int n = backtrace(pcs, TRACE_DEPTH);
+
+  // The frames to leave out are counted from the one that called this,
+  // as the sanitizers wrap backtrace in frames of their own
+  int start = skip + 1;
+  for (int i = 0; i < n; i++) {
+    if (pcs[i] == __builtin_return_address(0)) {
+      start = i + skip;
+      break;
+    }
+  }
+
+  // Return addresses are just past the call, which can be the start of the
+  // next function, so the address before them is looked up
+  struct trace_func *funcs[TRACE_DEPTH];
+  int last = -1;
+  for (int i = start; i < n; i++) {
+    funcs[i] = trace_lookup((char *)pcs[i] - 1);
+    if (funcs[i] != NULL) {
+      last = i;
+    }
+  }
+
+  // The frames that called main, like __libc_start_main, are left out
+  struct trace_frame *frames = __trace_top;
+  fputs("\nstack trace:\n", stderr);
+  for (int i = start; i <= last; i++) {
+    if (funcs[i] == NULL) {
+      trace_symbol(pcs[i]);
+      continue;
+    }
+    char *location = trace_location(&frames, funcs[i]);
+    fprintf(stderr, "\t%s\n", funcs[i]->name);
+    if (*location != '\0') {
+      fprintf(stderr, "\t\t%s\n", location);
+    }
+    // Recursion is shown once, which keeps a stack overflow readable
+    int repeats = 0;
+    while (i < last && funcs[i + 1] == funcs[i]) {
+      trace_location(&frames, funcs[i]);
+      repeats++;
+      i++;
+    }
+    if (repeats > 0) {
+      fprintf(stderr, "\t\t(called itself %d more times)\n", repeats);
+    }
+  }
+  if (last < 0) {
+    fputs("\t(no geode functions)\n", stderr);
+  } else if (n == TRACE_DEPTH && last == n - 1) {
+    // The trace was cut short
+    fputs("\t...\n", stderr);
+  }
+}
+
+static const char *trace_signal_name(int sig) {
+  switch (sig) {
+  case SIGSEGV:
+    return "segmentation fault";
+  case SIGBUS:
+    return "bus error";
+  case SIGFPE:
+    return "arithmetic exception";
+  case SIGILL:
+    return "illegal instruction";
+  case SIGABRT:
+    return "aborted";
+  }
+  return "signal";
+}
+
+static void trace_signal(int sig, siginfo_t *info, void *context) {
+  fprintf(stderr, "fatal error: %s", trace_signal_name(sig));
+  if (sig == SIGSEGV || sig == SIGBUS) {
+    fprintf(stderr, " at address 0x%lx",
+            (unsigned long)(uintptr_t)info->si_addr);
+    if ((uintptr_t)info->si_addr < 4096) {
+      fputs(" (nil dereference)", stderr);
+    }
+  }
+  fputs("\n", stderr);
+  // Above the frame that crashed are this handler and the kernel's
+  // trampoline that called it
+  __trace_print(2);
+  fflush(stderr);
+
+  // The handler was reset when it was called, so the program dies of the
+  // signal as it would have without it once this returns
+  raise(sig);
+}
+
+// The handlers run on a stack of their own, so overflowing the stack
+// also gets a trace
+static char trace_stack[64 * 1024];
+
+// Programs built with --sanitize leave crashes to the sanitizers, which
+// report them in more detail
+void __trace_install(void) {
+#ifndef GEODE_NO_GC
+  void *pcs[1];
+  // backtrace loads the unwinder the first time it is called, which isn't
+  // safe to do from a signal handler
+  backtrace(pcs, 1);
+
+  stack_t ss;
+  ss.ss_sp = trace_stack;
+  ss.ss_size = sizeof(trace_stack);
+  ss.ss_flags = 0;
+  sigaltstack(&ss, NULL);
+
+  struct sigaction sa;
+  memset(&sa, 0, sizeof(sa));
+  sa.sa_sigaction = trace_signal;
+  sa.sa_flags = SA_SIGINFO | SA_ONSTACK | SA_RESETHAND;
+  sigemptyset(&sa.sa_mask);
+  int signals[] = {SIGSEGV, SIGBUS, SIGFPE, SIGILL, SIGABRT};
+  for (unsigned i = 0; i < sizeof(signals) / sizeof(signals[0]); i++) {
+    sigaction(signals[i], &sa, NULL);
+  }
+#endif
+}
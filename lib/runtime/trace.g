@@ -0,0 +1,20 @@
+is runtime
+
+link "trace.c"
+
+# the trace section of runtime prints the stack trace of a program when
+# it panics, calls fatalf or crashes with a signal like a nil dereference
+
+# panic logs a message and the stack trace to stderr then exits the
+# program with the status 2
+func panic(byte* msg) {
+	__panic_at("", msg)
+}
+
+# __panic_at is what calls to panic are compiled to. It also logs the
+# file and line panic was called on
+func __panic_at(byte* location, byte* msg) ...
+
+# __trace_register hands the runtime the address, signature and location
+# of the functions of a program, which the compiler lists in __trace_init
+func __trace_register(byte** funcs, byte** names, byte** locations, long n, byte* end) ...
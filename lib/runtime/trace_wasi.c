@@ -0,0 +1,14 @@
+#include <stdio.h>
+
+#include "../include/runtime.h"
+
+// The stack traces of WASI programs, which replaces trace.c. WASI has no
+// signals, and a webassembly module can't walk its own stack, so panics only
+// print their message and crashes are left to the runtime that runs it.
+
+void __trace_register(void **funcs, char **names, char **locations, long n,
+                      void *end) {}
+
+void __trace_print(int skip) {}
+
+void __trace_install(void) {}
@@ -5,10 +5,19 @@
 #include <string.h>
 
 #include "../include/xmalloc.h"
-#include <gc/gc.h>
+#include "../include/geodegc.h"
 
 // #define DEBUG_XMALLOC
 
+#ifdef GEODE_NO_GC
+// Without the collector nothing frees most of the memory geode programs
+// allocate, so leaks are only reported when ASAN_OPTIONS asks for them
+const char *__asan_default_options(void) { return "detect_leaks=0"; }
+
+void GC_init(void) {}
+void GC_gcollect(void) {}
+#endif
+
 static pthread_mutex_t mutex = PTHREAD_MUTEX_INITIALIZER;
 
 static void xmalloc_lock() { pthread_mutex_lock(&mutex); }
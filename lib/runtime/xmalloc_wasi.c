@@ -0,0 +1,89 @@
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "../include/xmalloc.h"
+#include "../include/geodegc.h"
+
+// The allocator of WASI programs, which replaces xmalloc.c. Memory comes
+// from the malloc of wasi-libc, which grows the module's linear memory,
+// and is never collected. WASI modules only have a single thread, so
+// nothing needs a lock either.
+
+void GC_init(void) {}
+void GC_gcollect(void) {}
+
+static long memoryused = 0;
+static int blocksallocated = 0;
+static long allocationindex = 0;
+
+long bytes_used() { return memoryused; }
+long blocks_used() { return blocksallocated; }
+
+static xmalloc_prelude_t *xmalloc_getprelude(void *ptr) {
+  return (xmalloc_prelude_t *)(ptr - PRELUDE_SIZE);
+}
+
+long xmalloc_size(void *ptr) { return xmalloc_getprelude(ptr)->size; }
+
+long heap_size() { return memoryused; }
+
+void xfree(void *ptr) {
+  // Don't free a null pointer
+  if (ptr == NULL) {
+    return;
+  }
+  xmalloc_prelude_t *prelude = xmalloc_getprelude(ptr);
+  memoryused -= prelude->size;
+  blocksallocated--;
+  free(prelude);
+}
+
+void *xmalloc(size_t size) {
+  xmalloc_prelude_t *prelude = malloc(size + PRELUDE_SIZE);
+  if (prelude == NULL) {
+    fprintf(stderr, "Fatal: memory exhausted (xmalloc of %zu bytes).\n", size);
+    exit(EXIT_FAILURE);
+  }
+
+  memoryused += size;
+  blocksallocated++;
+
+  prelude->size = size;
+  prelude->alloc_count = 1;
+  prelude->alloc_index = allocationindex++;
+
+  return (void *)prelude + PRELUDE_SIZE;
+}
+
+void *xrealloc(void *ptr, size_t newsize) {
+  // Give them a new block of memory if there isnt anything to reallocate
+  if (ptr == NULL)
+    return xmalloc(newsize);
+
+  xmalloc_prelude_t *prelude = xmalloc_getprelude(ptr);
+  size_t oldsize = prelude->size;
+
+  xmalloc_prelude_t *new_prelude = realloc(prelude, newsize + PRELUDE_SIZE);
+  if (new_prelude == NULL) {
+    fprintf(stderr,
+            "Fatal: Memory reallocation of %p to %zu bytes from %zu bytes "
+            "failed.\n",
+            ptr, newsize, oldsize);
+    exit(EXIT_FAILURE);
+  }
+  new_prelude->size = newsize;
+  new_prelude->alloc_count++;
+
+  memoryused += newsize - oldsize;
+
+  return (void *)new_prelude + PRELUDE_SIZE;
+}
+
+void *xcalloc(unsigned count, unsigned size) {
+  unsigned int n = count * size;
+  // Errors should be handled in the xmalloc function
+  void *new_mem = xmalloc(n);
+  memset(new_mem, '\0', n);
+  return new_mem;
+}
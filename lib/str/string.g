@@ -99,21 +99,65 @@ func hash(string str) long {
 # split str by all characters in sset and return
 # a NULL terminated string buffer
 func split(string str, string sset) string* {
-	strSize = info(string).size
-	count = 0
-	splits = mem:get(strSize * count)
+	int size = len(str)
+	int count = 0
+	int start = 0
+	int sep = 0
 
+	# there can never be more parts than bytes, plus the NULL terminator
+	string* splits = mem:zero((size + 2) * info(string).size)
 
-	string ptr = str;
-	while str[i] {
-		char = str[i]
+	for i = 0; i <= size; i += 1 {
+		# the end of the string always ends the last part
+		sep = i == size
 
 		# check if the char is in the subset
 		for c = 0; c < len(sset); c += 1 {
-			if 
+			if str[i] == sset[c] {
+				sep = 1
+			}
 		}
-	}
 
+		if sep {
+			if i > start {
+				string part = mem:zero(i - start + 1)
+				for j = 0; j < i - start; j += 1 {
+					part[j] = str[start + j]
+				}
+				splits[count] = part
+				count += 1
+			}
+			start = i + 1
+		}
+	}
 
 	return splits
-}
\ No newline at end of file
+}
+
+
+test "len counts the bytes before the null byte" {
+	assert("len of empty string", len("") == 0);
+	assert("len of hello", len("hello") == 5);
+}
+
+test "eq compares the bytes of strings" {
+	assert("equal strings", eq("geode", "geode"));
+	assert("different lengths", !eq("geode", "geo"));
+	assert("different bytes", !eq("geode", "gecko"));
+}
+
+test "concat joins two strings" {
+	assert("concat", eq(concat("foo", "bar"), "foobar"));
+	assert("concat empty", eq(concat("", "bar"), "bar"));
+}
+
+test "split drops empty parts" {
+	string* parts = split("a,b,,c", ",");
+	assert("first part", eq(parts[0], "a"));
+	assert("second part", eq(parts[1], "b"));
+	assert("third part", eq(parts[2], "c"));
+}
+
+bench "concat" {
+	concat("geode", "lang");
+}
@@ -2,6 +2,9 @@ package arg
 
 import (
 	"os"
+	"runtime"
+	"strconv"
+	"strings"
 
 	"gopkg.in/alecthomas/kingpin.v2"
 )
@@ -23,7 +26,16 @@ var (
 	EmitObject            = App.Flag("obj", "Emit the object file of the program to the current directory. (will not produce binary)").Bool()
 	DumpScopeTree         = App.Flag("dump-scope-tree", "Dump a tree representation of the scope to stdout").Bool()
 	ClangFlags            = App.Flag("clang-flags", "flags to pass into the clang compiler/linker").String()
+	DisableOptimization   = App.Flag("no-opt", "Disable the ir optimization passes run before emission").Bool()
 	EnableDebug           = App.Flag("debug", "(NOT WORKING) Enable debug information").Short('g').Bool()
+	WarningsAsErrors      = App.Flag("Werror", "Treat warnings as errors").Bool()
+	Toolchain             = App.Flag("toolchain", "Toolchain to build with: clang, cc (llc and a C compiler) or llc (llc, a C compiler and ld). The first one installed is used by default").String()
+	ToolchainFile         = App.Flag("toolchain-file", "TOML file that picks the toolchain and its tools. Defaults to ~/.geode/toolchain.toml if there is one").String()
+	Target                = App.Flag("target", "Target triple to build for, like aarch64-linux-gnu or wasm32-wasi. Defaults to the toolchain's own target").String()
+	Sanitize              = App.Flag("sanitize", "Comma separated sanitizers to build with, like address,undefined. The runtime uses malloc instead of the garbage collector so they can track memory").String()
+	Cover                 = App.Flag("cover", "Count how many times each statement runs and write a coverage profile when the program exits").Bool()
+	CoverProfile          = App.Flag("coverprofile", "File programs built with --cover write their coverage profile to, unless GEODECOVERPROFILE names another one when they run").Default("geode.cover").String()
+	NilChecks             = App.Flag("nil-checks", "Check pointers against nil before they are dereferenced and panic with the file and line if they are. auto checks in debug builds, which are the ones built without -O").Default("auto").Enum("auto", "on", "off")
 )
 
 // Global arguments accessable throughout the program
@@ -32,12 +44,56 @@ var (
 
 	BuildCMD   = App.Command("build", "Build an executable.")
 	BuildInput = BuildCMD.Arg("input", "Geode source file or package").Default(".").String()
+	BuildLib   = BuildCMD.Flag("lib", "Build a static or shared library of the functions marked with export, and a C header that declares them").Enum("static", "shared")
 
 	RunCMD   = App.Command("run", "Build and run an executable, clean up afterwards").Default()
 	RunInput = RunCMD.Arg("input", "Geode source file or package").String()
 	RunArgs  = RunCMD.Arg("args", "Arguments to be passed into the program after building").Strings()
 
-	TestCMD = App.Command("test", "Run tests in the ./tests/ directory")
+	CheckCMD   = App.Command("check", "Parse and type check a package or library without building it")
+	CheckInput = CheckCMD.Arg("input", "Geode source file or package").Default(".").String()
+
+	TestCMD     = App.Command("test", "Run tests in the ./tests/ directory")
+	TestUnit    = TestCMD.Flag("unit", "Run the test blocks in a package instead of the ./tests/ directory").Bool()
+	TestInput   = TestCMD.Arg("input", "Package to run the test blocks of when using --unit").Default(".").String()
+	TestJobs    = TestCMD.Flag("jobs", "Number of tests to build and run at the same time").Short('j').Default(strconv.Itoa(runtime.NumCPU())).Int()
+	TestRun     = TestCMD.Flag("run", "Only run tests with a name or directory that contains the text or matches the glob").String()
+	TestTimeout = TestCMD.Flag("timeout", "How long a test may run when its test.toml doesn't set a Timeout").Default("10s").Duration()
+	TestJUnit   = TestCMD.Flag("junit", "Write the results to a file as JUnit XML").String()
+	TestJSON    = TestCMD.Flag("json", "Write the results to a file as JSON").String()
+	TestUpdate  = TestCMD.Flag("update", "Rewrite the expected outputs of the tests that don't match what they actually output").Bool()
+
+	BenchCMD   = App.Command("bench", "Run the bench blocks in a package and report how long they take")
+	BenchInput = BenchCMD.Arg("input", "Package to run the bench blocks of").Default(".").String()
+	BenchRun   = BenchCMD.Flag("run", "Only run benchmarks with a name that contains the text or matches the glob").String()
+	BenchTime  = BenchCMD.Flag("benchtime", "How long to run each benchmark for").Default("1s").Duration()
+	BenchCount = BenchCMD.Flag("count", "Number of times to run each benchmark").Default("1").Int()
+
+	CoverCMD          = App.Command("cover", "Work with the coverage profiles written by programs built with --cover")
+	CoverReportCMD    = CoverCMD.Command("report", "Render coverage profiles as an html page or an lcov file")
+	CoverReportInputs = CoverReportCMD.Arg("profiles", "Coverage profiles to merge into the report. Defaults to the --coverprofile file").Strings()
+	CoverReportFormat = CoverReportCMD.Flag("format", "Format of the report").Default("html").Enum("html", "lcov")
+	CoverReportOutput = CoverReportCMD.Flag("out", "File to write the report to. Defaults to coverage.html or lcov.info").String()
+
+	FmtCMD   = App.Command("fmt", "Rewrite geode source files in the canonical format")
+	FmtFiles = FmtCMD.Arg("files", "Geode source files or directories to format").Strings()
+	FmtCheck = FmtCMD.Flag("check", "List files that are not formatted and exit with an error instead of rewriting them").Bool()
+	FmtDiff  = FmtCMD.Flag("diff", "Print a diff of the changes instead of rewriting the files").Bool()
+	FmtASCII = FmtCMD.Flag("ascii", "Rewrite unicode operator aliases like ≠ to their ascii spelling").Bool()
+
+	DocCMD      = App.Command("doc", "Generate html or markdown documentation for packages from their comments")
+	DocPackages = DocCMD.Arg("packages", "Packages or directories to document. Defaults to every package in the standard library").Strings()
+	DocOutput   = DocCMD.Flag("out", "Directory to write the documentation into, or - to print it").Default("doc").String()
+	DocFormat   = DocCMD.Flag("format", "Format of the documentation").Default("html").Enum("html", "markdown")
+
+	BindgenCMD     = App.Command("bindgen", "Generate a geode package that binds the functions, structs and constants of a C header")
+	BindgenHeader  = BindgenCMD.Arg("header", "Path to a C header, or the name of a system header like stdio.h").Required().String()
+	BindgenPackage = BindgenCMD.Flag("package", "Name of the package. Defaults to the header's name").String()
+	BindgenOut     = BindgenCMD.Flag("out", "File to write the package to. Defaults to stdout").String()
+	BindgenCFlags  = BindgenCMD.Flag("cflags", "Flags to preprocess the header with, like -I and -D flags").String()
+
+	DemangleCMD   = App.Command("demangle", "Rewrite mangled symbol names, like the ones in nm, perf or gdb output, as the functions they name")
+	DemangleNames = DemangleCMD.Arg("names", "Mangled names to demangle. Defaults to filtering stdin").Strings()
 
 	NewTestCMD  = App.Command("new-test", "Create a new test")
 	NewTestName = NewTestCMD.Arg("name", "the name of the test").Required().String()
@@ -50,7 +106,65 @@ var (
 
 // Parse returns the kingpin command returned by kingpin.MustParse
 func Parse() string {
-	return kingpin.MustParse(App.Parse(os.Args[1:]))
+	args := os.Args[1:]
+	for i, a := range args {
+		// Allow the single dash spelling C compilers use
+		if a == "-Werror" {
+			args[i] = "--Werror"
+		}
+	}
+	return kingpin.MustParse(App.Parse(args))
+}
+
+// SetGlobals returns the global flags that were given values other than their
+// defaults, as arguments, so commands that run the compiler in a child
+// process can pass them on. The flags named in skip are left out
+func SetGlobals(skip ...string) []string {
+	var args []string
+flags:
+	for _, f := range App.Model().Flags {
+		for _, name := range skip {
+			if f.Name == name {
+				continue flags
+			}
+		}
+		value := f.Value.String()
+		if value == strings.Join(f.Default, ",") || f.IsBoolFlag() && value == "false" {
+			continue
+		}
+		if f.IsBoolFlag() {
+			args = append(args, "--"+f.Name)
+		} else {
+			args = append(args, "--"+f.Name+"="+value)
+		}
+	}
+	return args
+}
+
+// FlagNames returns the long names of the flags in some arguments, so the
+// ones they set can be left out of SetGlobals. Flags can't be repeated
+func FlagNames(args []string) []string {
+	short := make(map[rune]string)
+	for _, f := range App.Model().Flags {
+		short[f.Short] = f.Name
+	}
+	var names []string
+	for _, a := range args {
+		switch {
+		case a == "--":
+			return names
+		case a == "-Werror":
+			names = append(names, "Werror")
+		case strings.HasPrefix(a, "--"):
+			name := strings.SplitN(a[2:], "=", 2)[0]
+			names = append(names, name, strings.TrimPrefix(name, "no-"))
+		case strings.HasPrefix(a, "-") && len(a) > 1:
+			if name, ok := short[[]rune(a[1:])[0]]; ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
 }
 
 // Commands related to the pkg subcommand
@@ -30,8 +30,6 @@ func (n ArrayNode) GenAccess(prog *Program) (value.Value, error) {
 // Codegen implements Node.Codegen for ArrayNode
 func (n ArrayNode) Codegen(prog *Program) (value.Value, error) {
 
-	block := prog.Compiler.CurrentBlock()
-
 	var elementType types.Type
 	values := make([]value.Value, 0)
 	for _, el := range n.Elements {
@@ -54,6 +52,7 @@ func (n ArrayNode) Codegen(prog *Program) (value.Value, error) {
 		}
 		values = append(values, val)
 	}
+	block := prog.Compiler.CurrentBlock()
 	typ := prog.Compiler.PopType()
 
 	if typ == nil {
@@ -68,7 +67,7 @@ func (n ArrayNode) Codegen(prog *Program) (value.Value, error) {
 	var alloca value.Value
 	// alloca = block.NewAlloca(arrayType)
 
-	length := constant.NewInt(types.I32, int64(n.Length*gtypes.ByteCount(arrayType)))
+	length := constant.NewInt(types.I32, int64(gtypes.ByteCount(arrayType)))
 
 	dyn, err := prog.NewRuntimeFunctionCall("xmalloc", length)
 	if err != nil {
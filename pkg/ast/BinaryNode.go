@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/enum"
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
@@ -165,6 +166,8 @@ func (n BinaryNode) Codegen(prog *Program) (value.Value, error) {
 		add.TokenReference = n.TokenReference
 		add.NodeType = nodeBinary
 		return add.Codegen(prog)
+	case "or":
+		return n.orElse(prog)
 	}
 
 	if n.Left == nil || n.Right == nil {
@@ -176,6 +179,9 @@ func (n BinaryNode) Codegen(prog *Program) (value.Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	if (n.OP == "&&" || n.OP == "||") && types.Equal(l.Type(), types.I1) {
+		return n.shortCircuit(prog, l)
+	}
 	r, err := n.Right.Codegen(prog)
 	if err != nil {
 		return nil, err
@@ -231,6 +237,52 @@ func (n BinaryNode) Codegen(prog *Program) (value.Value, error) {
 
 }
 
+// shortCircuit generates a && or || with a boolean on the left, which only
+// evaluates the right side when it can change the result. That keeps
+// `p != nil && p.x > 0` from dereferencing p when it is nil
+func (n BinaryNode) shortCircuit(prog *Program, left value.Value) (value.Value, error) {
+	entry := prog.Compiler.CurrentBlock()
+	rightBlk := entry.Parent.NewBlock(mangleName("logical.right"))
+	endBlk := entry.Parent.NewBlock(mangleName("logical.end"))
+
+	// The result when the right side is skipped
+	skipped := constant.False
+	if n.OP == "&&" {
+		entry.NewCondBr(left, rightBlk, endBlk)
+	} else {
+		entry.NewCondBr(left, endBlk, rightBlk)
+		skipped = constant.True
+	}
+
+	var right value.Value
+	var rightEnd *ir.Block
+	err := prog.Compiler.genInBlock(rightBlk, func() error {
+		r, err := n.Right.Codegen(prog)
+		if err != nil {
+			return err
+		}
+		if !types.Equal(r.Type(), types.I1) {
+			c, err := createTypeCast(prog, r, types.I64)
+			if err != nil {
+				return err
+			}
+			r = prog.Compiler.CurrentBlock().NewICmp(enum.IPredNE, c, constant.NewInt(types.I64, 0))
+		}
+		right = r
+		// The right side can end in a block of its own, like when it is
+		// another && or ||
+		rightEnd = prog.Compiler.CurrentBlock()
+		rightEnd.NewBr(endBlk)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prog.Compiler.PushBlock(endBlk)
+	return endBlk.NewPhi(ir.NewIncoming(skipped, entry), ir.NewIncoming(right, rightEnd)), nil
+}
+
 func binaryCast(prog *Program, left, right value.Value) (value.Value, value.Value, types.Type, types.Type) {
 
 	var resultcast types.Type
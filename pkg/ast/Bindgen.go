@@ -0,0 +1,56 @@
+package ast
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/bindgen"
+	"github.com/geode-lang/geode/pkg/util"
+	"github.com/geode-lang/geode/pkg/util/log"
+)
+
+// BindC generates the package that binds a C header an include c names, and
+// returns the directory it is written to. The header is looked up next to
+// the file that includes it, then in the C compiler's include path. The
+// package is named after the header, like stdio for stdio.h
+func (p *Program) BindC(base string, header string) (string, error) {
+	path := header
+	if local := filepath.Join(base, header); util.FileExists(local) {
+		path, _ = filepath.Abs(local)
+	}
+
+	name := bindgen.PackageName(header)
+	res, err := bindgen.Generate(path, bindgen.Options{
+		Target:  p.Target.Triple,
+		Flags:   strings.Fields(*arg.ClangFlags),
+		Package: name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to bind C header %s: %s", header, err)
+	}
+	for _, s := range res.Skipped {
+		log.Verbose("%s: skipped %s\n", header, s)
+	}
+
+	// Each header has a directory of its own, as packages are directories,
+	// which is named after the package
+	sum := sha1.Sum([]byte(path + "\x00" + p.Target.Triple + "\x00" + *arg.ClangFlags))
+	dir := filepath.Join(util.HomeDir(), ".geode/build/bindgen", hex.EncodeToString(sum[:8]), name)
+	file := filepath.Join(dir, name+".g")
+
+	// The package is only rewritten when the header changes
+	if old, err := ioutil.ReadFile(file); err == nil && bytes.Equal(old, []byte(res.Source)) {
+		return dir, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, ioutil.WriteFile(file, []byte(res.Source), 0644)
+}
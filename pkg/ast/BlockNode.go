@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/geode-lang/geode/pkg/lexer"
 	"github.com/llir/llvm/ir/value"
 )
 
@@ -14,6 +15,7 @@ type BlockNode struct {
 	NodeType
 	TokenReference
 	Nodes []Node
+	Close lexer.Token // the closing curly brace
 }
 
 // NameString implements Node.NameString
@@ -24,6 +26,7 @@ func (n BlockNode) Codegen(prog *Program) (value.Value, error) {
 	prog.ScopeDown(n.Token)
 
 	for _, node := range n.Nodes {
+		prog.coverStatement(node)
 
 		_, err := node.Codegen(prog)
 		if err != nil {
@@ -0,0 +1,1283 @@
+package ast
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/gtypes"
+	"github.com/geode-lang/geode/pkg/lexer"
+	"github.com/geode-lang/geode/pkg/util"
+	"github.com/llir/llvm/ir/types"
+)
+
+// Checker is the semantic analysis pass. It walks the body of every
+// function and the initializer of every global in a congealed program
+// and reports type errors without generating any code. Unlike codegen,
+// which only visits functions reachable from main, the checker visits
+// every function, so errors in uncalled code are found too.
+type Checker struct {
+	Program     *Program
+	Diagnostics Diagnostics
+
+	// Inferred maps the token of every `name := value` declaration
+	// to the type that was inferred for the variable
+	Inferred map[lexer.Token]types.Type
+
+	fn      *FunctionNode
+	retType types.Type
+	scope   *checkScope
+	stmt    lexer.Token // the statement being checked, for nodes without a token
+	root    string      // warnings are only reported for packages in this directory
+
+	nonNil      nilFacts                 // the locals known not to be nil
+	nulls       map[lexer.Token]nullness // the nullness of the calls and fields checked
+	globalNulls map[string]nullness      // the nullness globals were declared with
+}
+
+// checkVar is a local variable the checker knows about
+type checkVar struct {
+	Name  string
+	Type  types.Type // nil if the type could not be determined
+	Token lexer.Token
+	Used  bool
+	Null  nullness // the levels of pointer that may be nil, as declared
+}
+
+// checkScope is a block level scope of local variables
+type checkScope struct {
+	parent *checkScope
+	vars   map[string]*checkVar
+}
+
+// NewChecker returns a checker for a program. The program must already be congealed
+func NewChecker(prog *Program) *Checker {
+	c := &Checker{}
+	c.Program = prog
+	c.Diagnostics = make(Diagnostics, 0)
+	c.Inferred = make(map[lexer.Token]types.Type)
+	c.nulls = make(map[lexer.Token]nullness)
+	c.globalNulls = make(map[string]nullness)
+	if prog.Entry != "" {
+		c.root, _ = filepath.Abs(ReduceToDir(prog.Entry))
+	}
+	return c
+}
+
+// Check runs semantic analysis over the whole program and returns the diagnostics found
+func (c *Checker) Check() Diagnostics {
+	prog := c.Program
+	previousPackage, previousScope := prog.Package, prog.Scope
+	defer func() {
+		prog.Package, prog.Scope = previousPackage, previousScope
+	}()
+
+	for _, pkg := range c.packages() {
+		for _, node := range pkg.Nodes {
+			if n, ok := node.(GlobalVariableDeclNode); ok {
+				c.globalNulls[fmt.Sprintf("%s:%s", pkg.Name, n.Name)] = n.Type.nullness()
+			}
+		}
+	}
+
+	for _, pkg := range c.packages() {
+		for _, node := range pkg.Nodes {
+			switch n := node.(type) {
+			case GlobalVariableDeclNode:
+				c.checkGlobal(pkg, n)
+			case ClassNode:
+				c.checkClass(pkg, n)
+			}
+		}
+	}
+
+	for _, fn := range c.functions() {
+		c.checkFunction(fn)
+	}
+
+	// Only now that every function was checked is it known which includes were needed
+	for _, pkg := range c.packages() {
+		for _, dpath := range pkg.UnusedDependencies() {
+			if c.warns(pkg) {
+				c.report(SeverityWarning, WarnUnusedInclude, pkg.Includes[dpath], "package %q is included but never used", filepath.Base(dpath))
+			}
+		}
+	}
+
+	c.Diagnostics = c.Diagnostics.Unsuppressed()
+	c.Diagnostics.Sort()
+	return c.Diagnostics
+}
+
+// packages returns the program's packages in a stable order
+func (c *Checker) packages() []*Package {
+	paths := make([]string, 0, len(c.Program.Packages))
+	for path := range c.Program.Packages {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	pkgs := make([]*Package, 0, len(paths))
+	for _, path := range paths {
+		pkgs = append(pkgs, c.Program.Packages[path])
+	}
+	return pkgs
+}
+
+// functions returns every function in the program in source order
+func (c *Checker) functions() []*FunctionNode {
+	seen := make(map[*FunctionNode]bool)
+	fns := make([]*FunctionNode, 0, len(c.Program.Functions))
+	for _, fn := range c.Program.Functions {
+		if !seen[fn] {
+			seen[fn] = true
+			fns = append(fns, fn)
+		}
+	}
+	sort.SliceStable(fns, func(i, j int) bool {
+		a, b := fns[i].Token, fns[j].Token
+		if a.Path() != b.Path() {
+			return a.Path() < b.Path()
+		}
+		if a.Pos != b.Pos {
+			return a.Pos < b.Pos
+		}
+		return fns[i].Name.Value < fns[j].Name.Value
+	})
+	return fns
+}
+
+// enter switches the program into the context of a package so
+// types and functions are resolved the same way codegen would
+func (c *Checker) enter(pkg *Package) {
+	prog := c.Program
+	scope := NewScope()
+	scope.Parent = prog.Scope.GetRoot()
+	if pkg != nil {
+		prog.Package = pkg
+		scope.PackageName = pkg.Name
+	}
+	prog.Scope = scope
+}
+
+// warns returns if warnings should be reported for a package. Only the
+// packages being built get warnings, not their dependencies
+func (c *Checker) warns(pkg *Package) bool {
+	if c.root == "" || pkg == nil {
+		return true
+	}
+	for path := range pkg.Files {
+		if dir, _ := filepath.Abs(filepath.Dir(path)); dir == c.root {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Checker) errorf(tok lexer.Token, format string, args ...interface{}) {
+	c.report(SeverityError, "", tok, format, args...)
+}
+
+func (c *Checker) warnf(code string, tok lexer.Token, format string, args ...interface{}) {
+	if c.warns(c.Program.Package) {
+		c.report(SeverityWarning, code, tok, format, args...)
+	}
+}
+
+func (c *Checker) report(sev Severity, code string, tok lexer.Token, format string, args ...interface{}) {
+	if !tok.HasSource() {
+		tok = c.stmt
+	}
+	c.Diagnostics = append(c.Diagnostics, Diagnostic{
+		Severity: sev,
+		Code:     code,
+		Token:    tok,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// usePackage marks the package a namespaced name like `io:print`
+// refers to as used by the package the name appears in
+func (c *Checker) usePackage(pkg *Package, name string) {
+	if ns, _ := ParseName(name); ns != "" && pkg != nil {
+		pkg.HasAccessToPackage(ns)
+	}
+}
+
+// tokenOf returns the token a node was parsed from
+func tokenOf(n Node) lexer.Token {
+	if ref, ok := n.(interface{ GetToken() lexer.Token }); ok {
+		return ref.GetToken()
+	}
+	return lexer.Token{}
+}
+
+// startOf returns the first token of a statement, so
+// a diagnostic about it points at the start of the line
+func startOf(n Node) lexer.Token {
+	var first lexer.Token
+	switch n := n.(type) {
+	case FunctionCallNode:
+		if name, ok := n.Name.(Node); ok {
+			first = startOf(name)
+		}
+	case BinaryNode:
+		first = startOf(n.Left)
+	}
+	if first.HasSource() {
+		return first
+	}
+	return tokenOf(n)
+}
+
+// typeName returns the geode name of a type for use in diagnostics
+func (c *Checker) typeName(t types.Type) string {
+	if t == nil {
+		return "unknown"
+	}
+	if res, ok := t.(*gtypes.ResultType); ok {
+		return c.typeName(res.ElemType) + "!"
+	}
+	stars := ""
+	for {
+		if name, err := c.Program.Scope.FindTypeName(t); err == nil {
+			return name + stars
+		}
+		ptr, ok := t.(*types.PointerType)
+		if !ok {
+			break
+		}
+		t = ptr.ElemType
+		stars += "*"
+	}
+	return t.String() + stars
+}
+
+func (c *Checker) push() {
+	c.scope = &checkScope{parent: c.scope, vars: make(map[string]*checkVar)}
+}
+
+// pop leaves a scope, warning about every variable in it that was never read
+func (c *Checker) pop() {
+	unused := make([]*checkVar, 0)
+	for _, v := range c.scope.vars {
+		if !v.Used && !strings.HasPrefix(v.Name, "_") {
+			unused = append(unused, v)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].Token.Pos < unused[j].Token.Pos
+	})
+	for _, v := range unused {
+		c.warnf(WarnUnusedVariable, v.Token, "%s declared but never used", v.Name)
+	}
+	c.scope = c.scope.parent
+}
+
+func (c *Checker) declare(name string, t types.Type, tok lexer.Token) *checkVar {
+	v := &checkVar{Name: name, Type: t, Token: tok}
+	c.scope.vars[name] = v
+	return v
+}
+
+// define declares a variable from an explicit declaration,
+// warning if it hides a variable from an outer scope
+func (c *Checker) define(name string, t types.Type, tok lexer.Token) *checkVar {
+	if _, inner := c.scope.vars[name]; !inner {
+		if outer := c.lookup(name); outer != nil {
+			c.warnf(WarnShadow, tok, "declaration of %s shadows the variable declared at %s", name, outer.Token.Position())
+		}
+	}
+	return c.declare(name, t, tok)
+}
+
+// lookup finds the nearest local variable with a name
+func (c *Checker) lookup(name string) *checkVar {
+	for s := c.scope; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// global returns the type of a global variable visible from the current package
+func (c *Checker) global(name string) (types.Type, bool) {
+	prog := c.Program
+	paths := []string{name, fmt.Sprintf("%s:%s", prog.Package.Name, name)}
+	item, found := prog.Scope.GetRoot().Find(paths)
+	if !found {
+		return nil, false
+	}
+	v, ok := item.(VariableScopeItem)
+	if !ok {
+		return nil, false
+	}
+	c.usePackage(prog.Package, name)
+	return v.Value().Type().(*types.PointerType).ElemType, true
+}
+
+// similarName returns the local variable with the name closest to the one given
+func (c *Checker) similarName(name string) (string, bool) {
+	best, bestDist := "", 0.0
+	for s := c.scope; s != nil; s = s.parent {
+		for other := range s.vars {
+			if dist := util.Jaro(name, other); dist > bestDist {
+				best, bestDist = other, dist
+			}
+		}
+	}
+	return best, bestDist >= 0.8
+}
+
+func (c *Checker) checkGlobal(pkg *Package, n GlobalVariableDeclNode) {
+	c.enter(pkg)
+	c.stmt = n.Token
+	c.scope = nil
+	c.nonNil = make(nilFacts)
+	c.push()
+	defer c.pop()
+
+	c.usePackage(pkg, n.Type.Name)
+	target, err := n.Type.GetType(c.Program)
+	if err != nil {
+		c.errorf(n.Token, "unknown type %q for global variable %s", n.Type, n.Name)
+		return
+	}
+	if n.Body != nil {
+		c.assignable(c.expr(n.Body), target, n.Body)
+		c.storable(n.Body, n.Type.nullness(), n.Type.String())
+	} else if !n.External {
+		c.unset(n.Name.Value, n.Type, target, n.Token)
+	}
+}
+
+// checkClass checks the defaults of a class's fields, which have to be
+// constants as they are copied into every instance of it
+func (c *Checker) checkClass(pkg *Package, n ClassNode) {
+	c.enter(pkg)
+	for _, field := range n.Variables {
+		c.usePackage(pkg, field.Typ.Name)
+		if !field.HasValue || field.Body == nil {
+			continue
+		}
+		if !isConstant(field.Body) {
+			c.errorf(tokenOf(field.Body), "the default of field %s of class %s must be a constant, not %s", field.Name, n.Name, field.Body)
+			continue
+		}
+		t, err := field.Typ.GetType(c.Program)
+		if err != nil {
+			continue
+		}
+		if _, err := constantDefault(c.Program, field.Body, t); err != nil {
+			c.errorf(tokenOf(field.Body), "cannot use %s as the default of field %s of type %s", field.Body, field.Name, field.Typ)
+			continue
+		}
+		c.storable(field.Body, field.Typ.nullness(), field.Typ.String())
+	}
+}
+
+func (c *Checker) checkFunction(fn *FunctionNode) {
+	prog := c.Program
+	c.enter(fn.Package)
+	c.fn = fn
+	c.stmt = fn.Token
+
+	if err := fn.Check(prog); err != nil {
+		c.errorf(fn.Token, "%s", err)
+	}
+
+	for _, arg := range fn.Args {
+		c.usePackage(fn.Package, arg.Type.Name)
+	}
+	c.usePackage(fn.Package, fn.ReturnType.Name)
+
+	// The body of a function that takes unknown types can only be
+	// checked once the types are known at a call site, but the
+	// packages it names still count as used
+	if fn.HasUnknownType {
+		if fn.BodyParser != nil {
+			for _, tok := range fn.BodyParser.tokens {
+				if tok.Is(lexer.TokIdent, lexer.TokType) {
+					c.usePackage(fn.Package, tok.Value)
+				}
+			}
+		}
+		return
+	}
+
+	_, argTypes, err := fn.Arguments(prog)
+	if err != nil {
+		c.errorf(fn.Token, "unable to find type for an argument of function %s", fn.Name)
+		return
+	}
+	ret, err := fn.ReturnType.GetType(prog)
+	if err != nil {
+		c.errorf(fn.Token, "unable to find return type %q for function %s", fn.ReturnType, fn.Name)
+		return
+	}
+
+	if fn.External {
+		return
+	}
+
+	body := fn.Body
+	if fn.BodyParser != nil {
+		state := fn.BodyParser.Save()
+		body = fn.BodyParser.parseBlockStmt()
+		fn.BodyParser.Restore(state)
+	}
+
+	c.retType = ret
+	c.scope = nil
+	c.nonNil = make(nilFacts)
+	c.push()
+	for i, arg := range fn.Args {
+		v := c.declare(arg.Name, argTypes[i], fn.Token)
+		v.Used = true
+		v.Null = arg.Type.nullness()
+	}
+	if !c.block(body) && !types.Equal(ret, types.Void) && !isVoidResult(ret) {
+		c.errorf(body.Close, "missing return on some path in function %s", fn.Name)
+	}
+	c.pop()
+}
+
+// block checks every statement in a block and returns if
+// control can never reach the end of it
+func (c *Checker) block(n BlockNode) bool {
+	c.push()
+	returns, reported := false, false
+	for _, node := range n.Nodes {
+		// Only the first unreachable statement is reported, but the rest are still checked
+		if returns && !reported {
+			c.warnf(WarnUnreachable, startOf(node), "unreachable code after return")
+			reported = true
+		}
+		if c.statement(node) {
+			returns = true
+		}
+	}
+	c.pop()
+	return returns
+}
+
+// statement checks a statement and returns if every path through it returns
+func (c *Checker) statement(node Node) bool {
+	if node == nil {
+		return false
+	}
+	if tok := tokenOf(node); tok.HasSource() {
+		c.stmt = tok
+	}
+
+	switch n := node.(type) {
+	case BlockNode:
+		return c.block(n)
+	case ReturnNode:
+		c.returnStmt(n)
+		return true
+	case IfNode:
+		c.condition(n.If, types.I32, "if")
+		whenTrue, whenFalse := c.narrow(n.If)
+		before := c.nonNil
+		c.nonNil = before.with(whenTrue)
+		then := c.statement(n.Then)
+		afterThen := c.nonNil
+		c.nonNil = before.with(whenFalse)
+		otherwise := c.statement(n.Else)
+		c.nonNil = join(then, afterThen, otherwise, c.nonNil)
+		return then && otherwise
+	case WhileNode:
+		c.forget(n.If, n.Body)
+		c.condition(n.If, types.I1, "while")
+		whenTrue, whenFalse := c.narrow(n.If)
+		head := c.nonNil
+		c.nonNil = head.with(whenTrue)
+		c.statement(n.Body)
+		c.nonNil = head.with(whenFalse)
+		return alwaysTrue(n.If)
+	case ForNode:
+		c.push()
+		c.statement(n.Init)
+		c.forget(n.Cond, n.Step, n.Body)
+		c.condition(n.Cond, types.I1, "for")
+		whenTrue, whenFalse := c.narrow(n.Cond)
+		head := c.nonNil
+		c.nonNil = head.with(whenTrue)
+		c.statement(n.Body)
+		c.expr(n.Step)
+		c.nonNil = head.with(whenFalse)
+		c.pop()
+		return n.Cond == nil || alwaysTrue(n.Cond)
+	default:
+		c.ignored(node, c.expr(node))
+	}
+	return false
+}
+
+// alwaysTrue returns if a loop condition is a constant that is never false.
+// Geode has no break statement, so such a loop can only be left by returning
+func alwaysTrue(cond Node) bool {
+	switch n := cond.(type) {
+	case IntNode:
+		return n.Value != 0
+	case BooleanNode:
+		return n.Value == "true"
+	}
+	return false
+}
+
+func (c *Checker) condition(node Node, to types.Type, keyword string) {
+	if node == nil {
+		return
+	}
+	t := c.expr(node)
+	if t != nil && !canCast(t, to) {
+		c.errorf(tokenOf(node), "%s condition must be a number or a pointer, not %s", keyword, c.typeName(t))
+	}
+}
+
+func (c *Checker) returnStmt(n ReturnNode) {
+	if res, ok := c.retType.(*gtypes.ResultType); ok {
+		c.returnResult(n, res)
+		return
+	}
+	name := c.fn.Name.Value
+	void := types.Equal(c.retType, types.Void)
+
+	if n.Value == nil {
+		if !void {
+			c.errorf(n.Token, "function %s must return a value of type %s", name, c.typeName(c.retType))
+		}
+		return
+	}
+
+	given := c.expr(n.Value)
+	if given == nil {
+		return
+	}
+	if void {
+		if !types.Equal(given, types.Void) {
+			c.errorf(n.Token, "function %s returns void but a value of type %s is returned", name, c.typeName(given))
+		}
+		return
+	}
+	if !types.Equal(given, c.retType) && !(types.IsInt(given) && types.IsInt(c.retType)) && !(isNilType(given) && types.IsPointer(c.retType)) {
+		c.errorf(n.Token, "incorrect return value for function %s. expected: %s, given: %s", name, c.typeName(c.retType), c.typeName(given))
+		return
+	}
+	c.storable(n.Value, c.fn.ReturnType.nullness(), c.fn.ReturnType.String())
+}
+
+// canCast mirrors the conversions createTypeCast is able to make
+func canCast(from, to types.Type) bool {
+	switch {
+	case types.Equal(from, to), types.Equal(to, types.Void):
+		return true
+	case types.IsPointer(from) && types.IsPointer(to):
+		return true
+	case gtypes.IsNumber(from) && gtypes.IsNumber(to):
+		return true
+	case types.IsPointer(from) && types.IsInt(to):
+		return true
+	case types.IsInt(from) && types.IsPointer(to):
+		return true
+	case gtypes.IsResult(to):
+		// Either the value or the error of a result can be stored in it
+		res := to.(*gtypes.ResultType)
+		return types.Equal(from, res.ErrorType()) || (!res.IsVoid() && canCast(from, res.ElemType))
+	}
+	return false
+}
+
+// assignable reports an error if a value of one type can't be stored in another
+func (c *Checker) assignable(from, to types.Type, at Node) {
+	if from == nil || to == nil {
+		return
+	}
+	if !canCast(from, to) {
+		c.errorf(tokenOf(at), "cannot use a value of type %s as %s", c.typeName(from), c.typeName(to))
+	}
+}
+
+// expr checks an expression and returns its type. A nil type means
+// the type could not be determined, and an error was already reported
+func (c *Checker) expr(node Node) types.Type {
+	switch n := node.(type) {
+	case nil:
+		return nil
+	case IntNode:
+		return types.I64
+	case FloatNode:
+		return types.Double
+	case BooleanNode:
+		return types.I1
+	case CharNode:
+		return types.I8
+	case StringNode:
+		return types.NewPointer(types.I8)
+	case NilNode:
+		return nilType
+	case StringFormatNode:
+		for _, arg := range n.Args {
+			c.expr(arg)
+		}
+		return types.NewPointer(types.I8)
+	case IdentNode:
+		return c.ident(n)
+	case VariableDefnNode:
+		return c.variableDefn(n)
+	case BinaryNode:
+		return c.binary(n)
+	case UnaryNode:
+		return c.unary(n)
+	case FunctionCallNode:
+		return c.call(n)
+	case DotReference:
+		return c.field(n)
+	case SubscriptNode:
+		return c.subscript(n)
+	case *SubscriptNode:
+		return c.subscript(*n)
+	case ArrayNode:
+		return c.array(n)
+	case CastNode:
+		return c.cast(n)
+	case TypeInfoNode:
+		return c.typeInfo(n)
+	case ClassLiteralNode:
+		return c.classLiteral(n)
+	case BlockNode, IfNode, WhileNode, ForNode, ReturnNode:
+		c.statement(n)
+		return nil
+	}
+	return nil
+}
+
+func (c *Checker) ident(n IdentNode) types.Type {
+	if v := c.lookup(n.Value); v != nil {
+		v.Used = true
+		return v.Type
+	}
+	if t, ok := c.global(n.Value); ok {
+		return t
+	}
+	if meant, ok := c.similarName(n.Value); ok {
+		c.errorf(n.Token, "unknown identifier %s (did you mean %s?)", n.Value, meant)
+	} else {
+		c.errorf(n.Token, "unknown identifier %s", n.Value)
+	}
+	return nil
+}
+
+// declToken returns the token of the name in a declaration
+func declToken(n VariableDefnNode) lexer.Token {
+	if n.Name.Token.HasSource() {
+		return n.Name.Token
+	}
+	return n.Token
+}
+
+// declType resolves the declared type of a variable without declaring it
+func (c *Checker) declType(n VariableDefnNode) (types.Type, bool) {
+	c.usePackage(c.Program.Package, n.Typ.Name)
+	t, err := n.Typ.GetType(c.Program)
+	if err != nil {
+		c.errorf(n.Token, "unknown type %q in declaration of %s", n.Typ, n.Name)
+		return nil, false
+	}
+	return t, true
+}
+
+func (c *Checker) variableDefn(n VariableDefnNode) types.Type {
+	if n.NeedsInference {
+		t := c.expr(n.Body)
+		if t != nil && types.Equal(t, types.Void) {
+			c.errorf(n.Token, "unable to infer the type of %s from a void value", n.Name)
+			t = nil
+		}
+		if t != nil {
+			c.Inferred[n.Token] = t
+		}
+		c.define(n.Name.Value, t, n.Token).Null = c.nullness(n.Body)
+		return t
+	}
+
+	t, _ := c.declType(n)
+	hasValue := n.HasValue && n.Body != nil
+	if hasValue {
+		c.assignable(c.expr(n.Body), t, n.Body)
+		c.storable(n.Body, n.Typ.nullness(), n.Typ.String())
+	} else {
+		c.unset(n.Name.Value, n.Typ, t, declToken(n))
+	}
+	v := c.define(n.Name.Value, t, declToken(n))
+	v.Null = n.Typ.nullness()
+	if hasValue {
+		c.refine(v, n.Body)
+	}
+	return t
+}
+
+func (c *Checker) assign(n BinaryNode) types.Type {
+	if _, ok := n.Left.(Assignable); !ok {
+		c.errorf(tokenOf(n.Left), "attempt to assign to a non assignable value '%s'", n.Left)
+		c.expr(n.Right)
+		return nil
+	}
+	if _, ok := n.Right.(Accessable); !ok {
+		c.errorf(tokenOf(n.Right), "attempt to assign with a non accessable value '%s'", n.Right)
+		return nil
+	}
+
+	switch lhs := n.Left.(type) {
+	case VariableDefnNode:
+		// the value is checked before the variable exists, so `int x = x` is an error
+		target, _ := c.declType(lhs)
+		c.assignable(c.expr(n.Right), target, n.Right)
+		c.storable(n.Right, lhs.Typ.nullness(), lhs.Typ.String())
+		v := c.define(lhs.Name.Value, target, declToken(lhs))
+		v.Null = lhs.Typ.nullness()
+		c.refine(v, n.Right)
+		return target
+
+	case IdentNode:
+		if v := c.lookup(lhs.Value); v != nil {
+			c.assignable(c.expr(n.Right), v.Type, n.Right)
+			c.storable(n.Right, v.Null, c.typeName(v.Type))
+			c.refine(v, n.Right)
+			return v.Type
+		}
+		if target, ok := c.global(lhs.Value); ok {
+			c.assignable(c.expr(n.Right), target, n.Right)
+			c.storable(n.Right, c.globalNullness(lhs.Value), c.typeName(target))
+			return target
+		}
+		// assigning to a name that doesn't exist yet defines it
+		t := c.expr(n.Right)
+		if t != nil && types.Equal(t, types.Void) {
+			c.errorf(lhs.Token, "unable to assign a void value to %s", lhs.Value)
+			t = nil
+		}
+		c.declare(lhs.Value, t, lhs.Token).Null = c.nullness(n.Right)
+		return t
+	}
+
+	target := c.expr(n.Left)
+	c.assignable(c.expr(n.Right), target, n.Right)
+	c.storable(n.Right, c.nullness(n.Left), c.typeName(target))
+	return target
+}
+
+func (c *Checker) binary(n BinaryNode) types.Type {
+	switch n.OP {
+	case "=":
+		return c.assign(n)
+	case "or":
+		return c.orElse(n)
+	case "+=", "-=", "*=", "/=":
+		if _, ok := n.Left.(Assignable); !ok {
+			c.errorf(n.Token, "left hand side of compound assignment %q is not assignable", n.OP)
+			c.expr(n.Right)
+			return nil
+		}
+		lt := c.expr(n.Left)
+		rt := c.expr(n.Right)
+		res := c.binaryOp(strings.TrimSuffix(n.OP, "="), lt, rt, n.Token)
+		c.assignable(res, lt, n)
+		return lt
+	}
+
+	if n.Left == nil || n.Right == nil {
+		c.errorf(n.Token, "invalid binary expression")
+		return nil
+	}
+	lt := c.expr(n.Left)
+
+	// The right side of && and || only runs when the left side didn't
+	// decide the result, so it knows what that shows about nil
+	before := c.nonNil
+	whenTrue, whenFalse := c.narrow(n.Left)
+	switch n.OP {
+	case "&&":
+		c.nonNil = before.with(whenTrue)
+	case "||":
+		c.nonNil = before.with(whenFalse)
+	}
+	rt := c.expr(n.Right)
+	c.nonNil = before
+	return c.binaryOp(n.OP, lt, rt, n.Token)
+}
+
+// binaryOp mirrors the casting rules binary operations use in codegen
+func (c *Checker) binaryOp(op string, lt, rt types.Type, tok lexer.Token) types.Type {
+	if lt == nil || rt == nil {
+		return nil
+	}
+
+	l, r := lt, rt
+	var ptr types.Type
+	if types.IsPointer(l) {
+		ptr, l = l, types.I64
+	}
+	if types.IsPointer(r) {
+		ptr, r = r, types.I64
+	}
+
+	if !gtypes.IsNumber(l) || !gtypes.IsNumber(r) {
+		c.errorf(tok, "invalid operands to binary %s: %s and %s", op, c.typeName(lt), c.typeName(rt))
+		return nil
+	}
+
+	t := r
+	if c.Program.CastPrecidence(l) > c.Program.CastPrecidence(r) {
+		t = l
+	}
+
+	if _, ok := booleanComparisonOperatorMap[op]; ok {
+		return types.I1
+	}
+	if _, ok := binaryOperatorTypeMap[op]; !ok {
+		c.errorf(tok, "invalid binary operator %s", op)
+		return nil
+	}
+
+	if op == "+" || op == "-" {
+		// pointer arithmetic results in a long
+		if ptr != nil {
+			return types.I64
+		}
+		return t
+	}
+
+	if types.IsFloat(t) {
+		switch op {
+		case ">>", "<<", "||", "&&", "^":
+			c.errorf(tok, "operator %s is not defined on floating point values", op)
+			return nil
+		}
+	}
+	if ptr != nil {
+		return ptr
+	}
+	return t
+}
+
+func (c *Checker) unary(n UnaryNode) types.Type {
+	if n.Operator == "&" {
+		if _, ok := n.Operand.(Reference); !ok {
+			c.errorf(n.Token, "'&' operator called on non-addressable operand")
+			return nil
+		}
+		t := c.expr(n.Operand)
+		if t == nil {
+			return nil
+		}
+		// The variable can be changed through the pointer
+		if ident, ok := n.Operand.(IdentNode); ok {
+			if v := c.lookup(ident.Value); v != nil && c.nonNil[v] {
+				c.nonNil = c.nonNil.with(nil)
+				delete(c.nonNil, v)
+			}
+		}
+		return types.NewPointer(t)
+	}
+
+	t := c.expr(n.Operand)
+	if t == nil {
+		return nil
+	}
+
+	switch n.Operator {
+	case "try":
+		return c.try(n, t)
+	case "-":
+		if !gtypes.IsNumber(t) {
+			c.errorf(n.Token, "unable to negate a value of type %s", c.typeName(t))
+			return nil
+		}
+	case "!":
+		if !types.IsInt(t) {
+			c.errorf(n.Token, "unable to '!' (not) a value of type %s", c.typeName(t))
+			return nil
+		}
+		return types.I32
+	case "*":
+		ptr, ok := t.(*types.PointerType)
+		if !ok {
+			c.errorf(n.Token, "attempt to dereference a non-pointer value of type %s", c.typeName(t))
+			return nil
+		}
+		c.derefable(n.Operand, n, "dereferenced")
+		return ptr.ElemType
+	}
+	return t
+}
+
+// structOf follows pointers from a type down to the class or result they
+// point at
+func (c *Checker) structOf(t types.Type) (*gtypes.StructType, bool) {
+	for types.IsPointer(t) {
+		t = t.(*types.PointerType).ElemType
+	}
+	return gtypes.FieldsOf(t)
+}
+
+func (c *Checker) field(n DotReference) types.Type {
+	base, ok := n.Base.(Node)
+	if !ok {
+		return nil
+	}
+	bt := c.expr(base)
+	if bt == nil {
+		return nil
+	}
+	st, ok := c.structOf(bt)
+	if !ok {
+		c.errorf(n.Token, "unable to access field %s of non-class type %s", n.Field, c.typeName(bt))
+		return nil
+	}
+	index := st.FieldIndex(n.Field.String())
+	if index == -1 {
+		c.errorf(n.Token, "class %s has no field %s", c.typeName(st), n.Field)
+		return nil
+	}
+	if types.IsPointer(bt) {
+		c.derefable(base, n, fmt.Sprintf("used to access the field %s", n.Field))
+	}
+	c.nulls[n.Token] = c.fieldNullness(st, n.Field.String())
+	if res, ok := c.resultOf(bt); ok && index == res.ErrorIndex() {
+		// The error of a result is nil when it succeeded
+		c.nulls[n.Token] = 1
+	}
+	return st.Fields[index]
+}
+
+func (c *Checker) subscript(n SubscriptNode) types.Type {
+	var st, it types.Type
+	if src, ok := n.Source.(Node); ok {
+		st = c.expr(src)
+	}
+	if idx, ok := n.Index.(Node); ok {
+		it = c.expr(idx)
+	}
+
+	if it != nil && !types.IsInt(it) {
+		c.errorf(n.Token, "array index must be an integer, not %s", c.typeName(it))
+	}
+	if st == nil {
+		return nil
+	}
+	if slice, ok := st.(*gtypes.SliceType); ok {
+		return slice.ElemType
+	}
+	ptr, ok := st.(*types.PointerType)
+	if !ok {
+		c.errorf(n.Token, "unable to index into a value of type %s", c.typeName(st))
+		return nil
+	}
+	if src, ok := n.Source.(Node); ok {
+		c.derefable(src, n, "indexed")
+	}
+	return ptr.ElemType
+}
+
+func (c *Checker) array(n ArrayNode) types.Type {
+	var elem types.Type
+	for _, el := range n.Elements {
+		t := c.expr(el)
+		if elem == nil {
+			elem = t
+			continue
+		}
+		c.assignable(t, elem, el)
+	}
+	if elem == nil {
+		return nil
+	}
+	return types.NewPointer(elem)
+}
+
+func (c *Checker) cast(n CastNode) types.Type {
+	from := c.expr(n.Source)
+	c.usePackage(c.Program.Package, n.Type.Name)
+	to, err := c.Program.FindType(n.Type.Name)
+	if err != nil {
+		c.errorf(n.Token, "unknown type %q in cast", n.Type.Name)
+		return nil
+	}
+	for i := 0; i < n.Type.PointerLevel; i++ {
+		to = types.NewPointer(to)
+	}
+	if from != nil && !canCast(from, to) {
+		c.errorf(n.Token, "unable to cast a value of type %s to %s", c.typeName(from), c.typeName(to))
+	}
+	return to
+}
+
+func (c *Checker) typeInfo(n TypeInfoNode) types.Type {
+	c.usePackage(c.Program.Package, n.T.Name)
+	if _, err := n.T.GetType(c.Program); err != nil {
+		c.errorf(n.Token, "unknown type %q in info()", n.T)
+		return nil
+	}
+	t, err := c.Program.FindType("TypeInfo")
+	if err != nil {
+		return nil
+	}
+	// info() evaluates to the global that holds the type's info
+	return types.NewPointer(t)
+}
+
+// classLiteral checks that a class literal only gives values to fields its
+// class has, and only once each
+func (c *Checker) classLiteral(n ClassLiteralNode) types.Type {
+	c.usePackage(c.Program.Package, n.Type.Name)
+	t, err := n.Type.GetType(c.Program)
+	if err != nil {
+		c.errorf(n.Token, "unknown type %q in class literal", n.Type)
+	}
+	st, ok := t.(*gtypes.StructType)
+	if t != nil && !ok {
+		c.errorf(n.Token, "%s is not a class, so it can't be made with a class literal", c.typeName(t))
+	}
+
+	given := make(map[string]bool)
+	for _, f := range n.Fields {
+		ft := c.expr(f.Value)
+		if st == nil {
+			continue
+		}
+		name := f.Name.Value
+		index := st.FieldIndex(name)
+		if index == -1 {
+			c.errorf(f.Name.Token, "class %s has no field %s", c.typeName(st), name)
+			continue
+		}
+		if given[name] {
+			c.errorf(f.Name.Token, "field %s is given more than once in class literal", name)
+			continue
+		}
+		given[name] = true
+		c.assignable(ft, st.Fields[index], f.Value)
+		if field, ok := c.classField(st, name); ok {
+			c.storable(f.Value, field.Typ.nullness(), field.Typ.String())
+		}
+	}
+	if st == nil {
+		return nil
+	}
+	for i, name := range st.Names {
+		field, ok := c.classField(st, name)
+		if given[name] || !ok || field.HasValue {
+			continue
+		}
+		if field.Typ.nonNilPointer() {
+			c.errorf(n.Token, "class literal must give field %s, as %s can't be nil", name, field.Typ)
+		} else if path, typ, ok := c.unsetPointer(st.Fields[i]); ok {
+			c.errorf(n.Token, "class literal must give field %s, as its field %s is a %s that can't be nil", name, path, typ)
+		}
+	}
+	return st
+}
+
+func (c *Checker) call(n FunctionCallNode) types.Type {
+	given := make([]types.Type, 0, len(n.Args))
+	known := true
+	for _, arg := range n.Args {
+		if _, ok := arg.(Accessable); !ok {
+			c.errorf(tokenOf(arg), "argument to function call to '%s' is not accessable", n.Name)
+			known = false
+			continue
+		}
+		t := c.expr(arg)
+		if t == nil {
+			known = false
+		}
+		given = append(given, t)
+	}
+
+	var fn *FunctionNode
+	var name string
+	switch callee := n.Name.(type) {
+	case IdentNode:
+		fn, name = c.function(callee)
+	case DotReference:
+		var this types.Type
+		fn, name, this = c.method(callee)
+		given = append([]types.Type{this}, given...)
+	default:
+		c.errorf(n.Token, "unable to call %s", n.Name)
+	}
+	if fn == nil {
+		return nil
+	}
+	if !known && fn.HasUnknownType {
+		return nil
+	}
+	c.nulls[n.Token] = fn.ReturnType.nullness()
+	ret := c.checkCall(fn, name, given, n.Token)
+	c.nilArguments(fn, n)
+	return ret
+}
+
+// nilArguments reports the arguments of a call that may be nil but are
+// passed as a pointer that can't be. C functions are left out, as they
+// say nothing about which of their pointers can be NULL
+func (c *Checker) nilArguments(fn *FunctionNode, n FunctionCallNode) {
+	if fn.External {
+		return
+	}
+	// Methods take the class they are called on first
+	offset := 0
+	if _, isMethod := n.Name.(DotReference); isMethod {
+		offset = 1
+	}
+	for i, arg := range n.Args {
+		if i+offset >= len(fn.Args) {
+			break
+		}
+		param := fn.Args[i+offset].Type
+		if !param.Unknown {
+			c.storable(arg, param.nullness(), param.String())
+		}
+	}
+}
+
+// function resolves the function an identifier calls using the same
+// search order as IdentNode.GetFunc
+func (c *Checker) function(n IdentNode) (*FunctionNode, string) {
+	prog := c.Program
+	ns, nm := ParseName(n.Value)
+	if ns == "" {
+		ns = prog.Scope.PackageName
+	} else if !prog.Package.HasAccessToPackage(ns) {
+		c.errorf(n.Token, "package %s doesn't load package %s but attempts to call %s:%s", prog.Package.Name, ns, ns, nm)
+		return nil, ""
+	}
+
+	searchNames := []string{
+		fmt.Sprintf("%s:%s", ns, nm),
+		fmt.Sprintf("%s:%s", prog.Package.Name, nm),
+		nm,
+	}
+	for _, name := range searchNames {
+		if fn, ok := prog.Functions[name]; ok {
+			return fn, name
+		}
+	}
+	c.errorf(n.Token, "unknown function %s", n.Value)
+	return nil, ""
+}
+
+// method resolves the method a dot reference calls, returning
+// the type of the `this` argument the call passes to it
+func (c *Checker) method(n DotReference) (*FunctionNode, string, types.Type) {
+	base, ok := n.Base.(Node)
+	if !ok {
+		return nil, "", nil
+	}
+	bt := c.expr(base)
+	if bt == nil {
+		return nil, "", nil
+	}
+	st, ok := c.structOf(bt)
+	if !ok {
+		c.errorf(n.Token, "unable to call method %s on non-class type %s", n.Field, c.typeName(bt))
+		return nil, "", nil
+	}
+	if types.IsPointer(bt) {
+		c.derefable(base, n, fmt.Sprintf("used to call the method %s", n.Field))
+	}
+	class, err := c.Program.Scope.FindTypeName(st)
+	if err != nil {
+		return nil, "", nil
+	}
+
+	searchNames := []string{
+		fmt.Sprintf("%s.%s", class, n.Field),
+		fmt.Sprintf("runtime:%s.%s", class, n.Field),
+	}
+	for _, name := range searchNames {
+		if fn, ok := c.Program.Functions[name]; ok {
+			return fn, name, types.NewPointer(st)
+		}
+	}
+	c.errorf(n.Token, "class %s has no method %s", class, n.Field)
+	return nil, "", nil
+}
+
+// checkCall validates the arguments of a call the way Program.GetFunction
+// does and returns the type the call results in
+func (c *Checker) checkCall(fn *FunctionNode, name string, given []types.Type, tok lexer.Token) types.Type {
+	params, ret, err := c.signature(fn, given)
+	if err != nil {
+		c.errorf(tok, "%s", err)
+		return nil
+	}
+
+	if len(params) != len(given) {
+		if !fn.Variadic {
+			c.errorf(tok, "incorrect number of arguments passed to function %s. expected %d, given %d", name, len(params), len(given))
+			return ret
+		}
+		if len(params) > len(given) {
+			c.errorf(tok, "variadic function %s expects a minimum of %d arguments. given: %d", name, len(params), len(given))
+			return ret
+		}
+	}
+
+	if !fn.Variadic {
+		for i, expected := range params {
+			got := given[i]
+			if expected == nil || got == nil || fn.Args[i].Type.Unknown {
+				continue
+			}
+			if !types.Equal(expected, got) && !typesAreLooselyEqual(got, expected) {
+				c.errorf(tok, "incorrect type passed into function %s. given: %s, expected: %s", name, c.typeName(got), c.typeName(expected))
+			}
+		}
+	}
+	return ret
+}
+
+// signature resolves the parameter and return types of a function from
+// inside its own package, binding any unknown types to the given arguments
+func (c *Checker) signature(fn *FunctionNode, given []types.Type) ([]types.Type, types.Type, error) {
+	prog := c.Program
+	previousPackage, previousScope := prog.Package, prog.Scope
+	defer func() {
+		prog.Package, prog.Scope = previousPackage, previousScope
+	}()
+	c.enter(fn.Package)
+
+	for i, arg := range fn.Args {
+		if arg.Type.Unknown && i < len(given) && given[i] != nil {
+			prog.Scope.RegisterType(arg.Type.Name, given[i], 0)
+		}
+	}
+
+	params := make([]types.Type, 0, len(fn.Args))
+	for _, arg := range fn.Args {
+		t, err := arg.Type.GetType(prog)
+		if err != nil {
+			if arg.Type.Unknown {
+				params = append(params, nil)
+				continue
+			}
+			return nil, nil, fmt.Errorf("unable to find type with name %q for function %s", arg.Type.Name, fn.Name)
+		}
+		params = append(params, t)
+	}
+
+	ret, err := fn.ReturnType.GetType(prog)
+	if err != nil {
+		if fn.HasUnknownType {
+			return params, nil, nil
+		}
+		return nil, nil, fmt.Errorf("unable to find return type %q for function %s", fn.ReturnType, fn.Name)
+	}
+	return params, ret, nil
+}
@@ -0,0 +1,79 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/geode-lang/geode/pkg/gtypes"
+	"github.com/llir/llvm/ir/value"
+)
+
+// ClassLiteralNode is a class made with the values of some of its fields,
+// like `Point{x: 1, y: 2}`. The fields it leaves out are their default
+type ClassLiteralNode struct {
+	NodeType
+	TokenReference
+
+	Type   TypeNode
+	Fields []ClassLiteralField
+}
+
+// ClassLiteralField is a field given a value in a class literal
+type ClassLiteralField struct {
+	Name  IdentNode
+	Value Node
+}
+
+// NameString implements Node.NameString
+func (n ClassLiteralNode) NameString() string { return "ClassLiteralNode" }
+
+// Codegen implements Node.Codegen for ClassLiteralNode
+func (n ClassLiteralNode) Codegen(prog *Program) (value.Value, error) {
+	t, err := n.Type.GetType(prog)
+	if err != nil {
+		return nil, err
+	}
+	st, ok := t.(*gtypes.StructType)
+	if !ok {
+		return nil, errorAt(n.Token, "%s is not a class", n.Type)
+	}
+
+	init, err := prog.defaultValue(st)
+	if err != nil {
+		return nil, err
+	}
+	alloc := createBlockAlloca(prog.Compiler.CurrentFunc(), st, "")
+	prog.Compiler.CurrentBlock().NewStore(init, alloc)
+
+	// The values are generated in the order they were written
+	for _, f := range n.Fields {
+		if st.FieldIndex(f.Name.Value) == -1 {
+			return nil, errorAt(f.Name.Token, "class %s has no field %s", n.Type, f.Name)
+		}
+		val, err := f.Value.Codegen(prog)
+		if err != nil {
+			return nil, err
+		}
+		GenStructFieldAssignment(prog, alloc, f.Name.Value, val)
+	}
+
+	return prog.Compiler.CurrentBlock().NewLoad(st, alloc), nil
+}
+
+// GenAccess implements Accessable.GenAccess
+func (n ClassLiteralNode) GenAccess(prog *Program) (value.Value, error) {
+	return n.Codegen(prog)
+}
+
+func (n ClassLiteralNode) String() string {
+	buff := &bytes.Buffer{}
+	fmt.Fprintf(buff, "%s{", n.Type)
+	for i, f := range n.Fields {
+		if i > 0 {
+			buff.WriteString(", ")
+		}
+		fmt.Fprintf(buff, "%s: %s", f.Name, f.Value)
+	}
+	buff.WriteString("}")
+	return buff.String()
+}
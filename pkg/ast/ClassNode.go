@@ -1,11 +1,9 @@
 package ast
 
 import (
-	"bytes"
 	"fmt"
 
 	"github.com/geode-lang/geode/pkg/gtypes"
-	"github.com/geode-lang/geode/pkg/util/color"
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
@@ -21,6 +19,8 @@ type ClassNode struct {
 	Name      string
 	Methods   []FunctionNode
 	Variables []VariableDefnNode
+	Doc       string // the comment written above the class
+	Export    bool   // declared in the header of libraries built with --lib
 }
 
 // NameString implements Node.NameString
@@ -64,18 +64,18 @@ func (n ClassNode) VerifyCorrectness(prog *Program) error {
 		if gtypes.IsStruct(ty) {
 			// If the type is a direct reference back to the base class, it is invalid. It must be a pointer type
 			if types.Equal(base, ty) {
-				return fmt.Errorf("class '%s' has a circular reference in it's fields. Field '%s' should be a pointer to a '%s' instead", n.Name, f.Name, n.Name)
+				return errorAt(f.Token, "class '%s' has a circular reference in it's fields. Field '%s' should be a pointer to a '%s' instead", n.Name, f.Name, n.Name)
 			}
 
 			// Now we need to check if the struct has a non-pointer reference back to this class.
-			// that has the same effect.
-			structT := ty.(*gtypes.StructType)
+			// that has the same effect. Results hold their value in a struct of their own
+			structT, ok := gtypes.FieldsOf(ty)
+			if !ok {
+				continue
+			}
 
 			if contains, _, _ := structContainsTypeAnywhere(structT, base, structT); contains {
-				buff := &bytes.Buffer{}
-				fmt.Fprintf(buff, "* class %s has a field %s of type %s which eventually back references %s (would consume 'infinite' stack memory)\n", color.Green(n.Name), color.Blue(fieldName), color.Red(t), color.Green(n.Name))
-				fmt.Fprintf(buff, "  Either change %s to a pointer or remove the back-reference from %s\n\n", color.Blue(fieldName), color.Red(t))
-				return fmt.Errorf("%s", buff)
+				return errorAt(f.Token, "class %s has a circular reference through field %s of type %s, which eventually contains a %s (would consume 'infinite' stack memory). Either change %s to a pointer or remove the back-reference from %s", n.Name, fieldName, t, n.Name, fieldName, t)
 			}
 		}
 	}
@@ -87,8 +87,7 @@ func structContainsTypeAnywhere(s *gtypes.StructType, t types.Type, path ...*gty
 		if types.Equal(field, t) {
 			return true, i, path
 		}
-		if gtypes.IsStruct(field) {
-			structType := field.(*gtypes.StructType)
+		if structType, ok := gtypes.FieldsOf(field); ok {
 			if contains, index, p := structContainsTypeAnywhere(structType, t, append(path, structType)...); contains {
 				return true, index, p
 			}
@@ -108,7 +107,7 @@ func (n ClassNode) Declare(prog *Program) (value.Value, error) {
 	name := fmt.Sprintf("class.%s:%s", prog.Scope.PackageName, n.Name)
 	structDefn.SetName(name)
 
-	prog.Module.NewTypeDef(n.Name, structDefn)
+	prog.Module.NewTypeDef(name, structDefn)
 
 	scopeName := n.Name
 	if prog.Package.Name != "runtime" {
@@ -169,7 +168,7 @@ func (n ClassNode) Codegen(prog *Program) (value.Value, error) {
 		// Prepend the "this" argument to the function
 		fn.Args = append([]FunctionArg{thisArg}, fn.Args...)
 		fn.Name.Value = fmt.Sprintf("%s:%s.%s", prog.Package.Name, n.Name, fn.Name)
-		fn.Package = n.Package
+		fn.Package = prog.Package
 
 		if _, found := names[fn.Name.String()]; found {
 			return nil, fmt.Errorf("class '%s' has two fields/methods named '%s'", n.Name, fn.Name)
@@ -249,3 +248,132 @@ func GetBaseType(v value.Value) types.Type {
 	}
 	return baseType
 }
+
+// classDefault returns the value a class starts out as, with each field set
+// to the default it was declared with. Classes without defaults, and every
+// other type, start out as zero
+func (p *Program) classDefault(t types.Type) (constant.Constant, error) {
+	zero := constant.NewZeroInitializer(t)
+	st, ok := t.(*gtypes.StructType)
+	if !ok {
+		return zero, nil
+	}
+	name, err := p.Scope.FindTypeName(st)
+	if err != nil {
+		return zero, nil
+	}
+	class, ok := p.Classes[name]
+	if !ok || len(class.Variables) != len(st.Fields) {
+		return zero, nil
+	}
+
+	set := false
+	fields := make([]constant.Constant, len(st.Fields))
+	for i, f := range class.Variables {
+		if !f.HasValue || f.Body == nil {
+			if fields[i], err = p.classDefault(st.Fields[i]); err != nil {
+				return nil, err
+			}
+		} else if fields[i], err = constantDefault(p, f.Body, st.Fields[i]); err != nil {
+			return nil, errorAt(tokenOf(f.Body), "invalid default for field %s of class %s: %s", f.Name, class.Name, err)
+		}
+		if _, isZero := fields[i].(*constant.ZeroInitializer); !isZero {
+			set = true
+		}
+	}
+	if !set {
+		return zero, nil
+	}
+	return constant.NewStruct(st.StructType, fields...), nil
+}
+
+// defaultValue returns the value a variable of some type starts out as. The
+// defaults of a class are kept in a constant global they are loaded from
+func (p *Program) defaultValue(t types.Type) (value.Value, error) {
+	name, err := p.Scope.FindTypeName(t)
+	if err != nil {
+		return constant.NewZeroInitializer(t), nil
+	}
+	globl, ok := p.ClassDefaults[name]
+	if !ok {
+		init, err := p.classDefault(t)
+		if err != nil {
+			return nil, err
+		}
+		if _, isZero := init.(*constant.ZeroInitializer); isZero {
+			return init, nil
+		}
+		// The global is of the geode type, so it can be loaded as the class
+		globl = p.Module.NewGlobal(fmt.Sprintf("class_default_%s", name), t)
+		globl.Init = init
+		globl.Immutable = true
+		p.ClassDefaults[name] = globl
+	}
+	return p.Compiler.CurrentBlock().NewLoad(t, globl), nil
+}
+
+// constantDefault returns the constant a literal makes as a value of some
+// type. Only literals can be the default of a field, as the defaults of a
+// class are a constant that is copied into every instance of it
+func constantDefault(p *Program, node Node, t types.Type) (constant.Constant, error) {
+	switch n := node.(type) {
+	case IntNode:
+		switch typ := t.(type) {
+		case *types.IntType:
+			return constant.NewInt(typ, n.Value), nil
+		case *types.FloatType:
+			return constant.NewFloat(typ, float64(n.Value)), nil
+		}
+	case FloatNode:
+		if typ, ok := t.(*types.FloatType); ok {
+			return constant.NewFloat(typ, n.Value), nil
+		}
+	case CharNode:
+		if typ, ok := t.(*types.IntType); ok {
+			return constant.NewInt(typ, int64(n.Value)), nil
+		}
+	case BooleanNode:
+		if types.Equal(t, types.I1) {
+			return constant.NewBool(n.Value == "true"), nil
+		}
+	case StringNode:
+		if types.Equal(t, types.NewPointer(types.I8)) {
+			return p.constString(n.Value), nil
+		}
+	case NilNode:
+		if typ, ok := t.(*types.PointerType); ok {
+			return constant.NewNull(typ), nil
+		}
+	case UnaryNode:
+		if n.Operator != "-" {
+			break
+		}
+		switch operand := n.Operand.(type) {
+		case IntNode:
+			operand.Value = -operand.Value
+			return constantDefault(p, operand, t)
+		case FloatNode:
+			operand.Value = -operand.Value
+			return constantDefault(p, operand, t)
+		}
+	}
+	if !isConstant(node) {
+		return nil, fmt.Errorf("%s is not a constant", node)
+	}
+	return nil, fmt.Errorf("%s is not a value of type %s", node, t)
+}
+
+// isConstant returns if a node is a literal that constantDefault can make a
+// constant out of
+func isConstant(node Node) bool {
+	switch n := node.(type) {
+	case IntNode, FloatNode, CharNode, BooleanNode, StringNode, NilNode:
+		return true
+	case UnaryNode:
+		switch n.Operand.(type) {
+		case IntNode, FloatNode:
+			return n.Operator == "-"
+		}
+	}
+	return false
+}
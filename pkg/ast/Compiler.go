@@ -80,10 +80,13 @@ func (c *Compiler) NewComment(comment string) {
 
 }
 
+// genInBlock runs fn with blk as the current block. Any blocks fn moves on
+// to, like the end of an if or a short circuited &&, are dropped after it
 func (c *Compiler) genInBlock(blk *ir.Block, fn func() error) error {
+	l := len(c.blocks)
 	c.PushBlock(blk)
 	err := fn()
-	c.PopBlock()
+	c.blocks = c.blocks[:l]
 	return err
 }
 
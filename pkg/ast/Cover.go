@@ -0,0 +1,163 @@
+package ast
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/lexer"
+	"github.com/geode-lang/geode/pkg/util"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+)
+
+// coverCounter is a global that counts how many times a statement ran
+// in a program built with --cover
+type coverCounter struct {
+	global   *ir.Global
+	location string // file:line.column of the statement
+}
+
+// coverStatement increments the counter of a statement before it runs
+func (p *Program) coverStatement(node Node) {
+	if !*arg.Cover {
+		return
+	}
+	tok := startOf(node)
+	if !p.covers(tok) {
+		return
+	}
+	blk := p.Compiler.CurrentBlock()
+	if blk == nil || blk.Term != nil {
+		return
+	}
+
+	counter := p.Module.NewGlobalDef(fmt.Sprintf("__cover.%d", len(p.coverCounters)), constant.NewInt(types.I64, 0))
+	p.coverCounters = append(p.coverCounters, coverCounter{counter, coverLocation(tok)})
+
+	count := blk.NewLoad(types.I64, counter)
+	blk.NewStore(blk.NewAdd(count, constant.NewInt(types.I64, 1)), counter)
+}
+
+// coverLocation returns where the statement a token starts is in a profile
+func coverLocation(tok lexer.Token) string {
+	return fmt.Sprintf("%s:%d.%d", tok.Path(), tok.Line, tok.StartColumn())
+}
+
+// covers returns if the statement a token starts is counted. Statements of
+// the standard library and ones the compiler generated aren't
+func (p *Program) covers(tok lexer.Token) bool {
+	if !tok.HasSource() || p.generated[tok.Path()] {
+		return false
+	}
+	rel, err := filepath.Rel(util.StdLibDir(), tok.Path())
+	return err != nil || strings.HasPrefix(rel, "..")
+}
+
+// coverInit returns the function main calls to hand the coverage counters
+// to the runtime. It is only declared until FinishCoverage gives it a body,
+// as the counters aren't known until every function has been compiled
+func (p *Program) coverInit() *ir.Func {
+	if p.coverInitFunc == nil {
+		p.coverInitFunc = p.Module.NewFunc("__cover_init", types.Void)
+	}
+	return p.coverInitFunc
+}
+
+// coverUncompiled adds a counter that is always zero for the statements of
+// the functions that were never compiled because nothing calls them, so they
+// show up in the profile as not covered instead of not at all
+func (p *Program) coverUncompiled() {
+	names := make([]string, 0, len(p.Functions))
+	for name := range p.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var never *ir.Global
+	for _, name := range names {
+		fn := p.Functions[name]
+		if fn.External || fn.HasUnknownType || len(fn.Variants) > 0 || fn.BodyParser == nil {
+			continue
+		}
+		if !p.covers(fn.Token) {
+			continue
+		}
+
+		state := fn.BodyParser.Save()
+		body := fn.BodyParser.parseBlockStmt()
+		fn.BodyParser.Restore(state)
+
+		for _, tok := range coverStatements(body, nil) {
+			if never == nil {
+				never = p.Module.NewGlobalDef("__cover.never", constant.NewInt(types.I64, 0))
+			}
+			p.coverCounters = append(p.coverCounters, coverCounter{never, coverLocation(tok)})
+		}
+	}
+}
+
+// coverStatements returns the first tokens of the statements in a node
+// that coverStatement would count if the node was compiled
+func coverStatements(node Node, toks []lexer.Token) []lexer.Token {
+	switch n := node.(type) {
+	case BlockNode:
+		for _, stmt := range n.Nodes {
+			if tok := startOf(stmt); tok.HasSource() {
+				toks = append(toks, tok)
+			}
+			toks = coverStatements(stmt, toks)
+		}
+	case IfNode:
+		toks = coverStatements(n.Then, toks)
+		toks = coverStatements(n.Else, toks)
+	case WhileNode:
+		toks = coverStatements(n.Body, toks)
+	case ForNode:
+		toks = coverStatements(n.Body, toks)
+	}
+	return toks
+}
+
+// FinishCoverage fills in the function that registers the coverage
+// counters with the runtime, which writes them to a profile when the
+// program exits. This should be called after all the functions are compiled
+func (p *Program) FinishCoverage() error {
+	if p.coverInitFunc == nil {
+		return nil
+	}
+	p.coverUncompiled()
+
+	counterType := types.NewPointer(types.I64)
+	counters := make([]constant.Constant, 0, len(p.coverCounters))
+	locations := make([]constant.Constant, 0, len(p.coverCounters))
+	zero := constant.NewInt(types.I32, 0)
+	for i, c := range p.coverCounters {
+		loc := p.Module.NewGlobalDef(fmt.Sprintf("__cover.loc.%d", i), newCharArray(c.location))
+		loc.Immutable = true
+		counters = append(counters, c.global)
+		locations = append(locations, constant.NewGetElementPtr(loc.ContentType, loc, zero, zero))
+	}
+
+	counterTable := p.Module.NewGlobalDef("__cover.counters", constant.NewArray(types.NewArray(uint64(len(counters)), counterType), counters...))
+	locationTable := p.Module.NewGlobalDef("__cover.locations", constant.NewArray(types.NewArray(uint64(len(locations)), types.I8Ptr), locations...))
+	profile := p.Module.NewGlobalDef("__cover.profile", newCharArray(*arg.CoverProfile))
+	profile.Immutable = true
+
+	register, err := p.GetFunction("__cover_register", FunctionCompilationOptions{})
+	if err != nil {
+		return err
+	}
+
+	blk := p.coverInitFunc.NewBlock("")
+	blk.NewCall(register,
+		constant.NewGetElementPtr(counterTable.ContentType, counterTable, zero, zero),
+		constant.NewGetElementPtr(locationTable.ContentType, locationTable, zero, zero),
+		constant.NewInt(types.I64, int64(len(p.coverCounters))),
+		constant.NewGetElementPtr(profile.ContentType, profile, zero, zero))
+	blk.NewRet(nil)
+	return nil
+}
@@ -0,0 +1,156 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/lexer"
+	"github.com/geode-lang/geode/pkg/util/color"
+)
+
+// Severity is how serious a diagnostic is
+type Severity int
+
+// The severities a diagnostic can have
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// The codes of the warnings the checker can report. These are the
+// names used to suppress a warning with a `# geode:ignore` comment
+const (
+	WarnUnusedVariable = "unused-variable"
+	WarnUnusedInclude  = "unused-include"
+	WarnShadow         = "shadow"
+	WarnUnreachable    = "unreachable"
+)
+
+// suppressDirective is the comment that silences warnings on its line
+const suppressDirective = "geode:ignore"
+
+// Diagnostic is a single problem found in the source of a program
+// along with the token it was found at
+type Diagnostic struct {
+	Severity Severity
+	Code     string // the kind of warning, empty for errors
+	Token    lexer.Token
+	Message  string
+}
+
+// Suppressed returns if the line the diagnostic is on has a comment that
+// silences it. `# geode:ignore` silences every warning on the line, and
+// `# geode:ignore shadow, unused-variable` only silences the codes listed.
+// Errors can never be suppressed
+func (d Diagnostic) Suppressed() bool {
+	if d.Severity != SeverityWarning {
+		return false
+	}
+	for _, comment := range strings.Split(d.Token.LineText(), "#")[1:] {
+		comment = strings.TrimSpace(comment)
+		if !strings.HasPrefix(comment, suppressDirective) {
+			continue
+		}
+		codes := strings.FieldsFunc(comment[len(suppressDirective):], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})
+		if len(codes) == 0 {
+			return true
+		}
+		for _, code := range codes {
+			if code == d.Code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Position returns the path:line:column location of the diagnostic
+func (d Diagnostic) Position() string {
+	if !d.Token.HasSource() {
+		return "<unknown>"
+	}
+	return d.Token.Position()
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Position(), d.Severity, d.message())
+}
+
+// Error implements error, so errors found while compiling can keep their position
+func (d Diagnostic) Error() string {
+	return d.String()
+}
+
+// errorAt returns an error diagnostic at some token
+func errorAt(tok lexer.Token, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: SeverityError, Token: tok, Message: fmt.Sprintf(format, args...)}
+}
+
+// message returns the diagnostic's message, naming the warning code if it has one
+func (d Diagnostic) message() string {
+	if d.Code == "" {
+		return d.Message
+	}
+	return fmt.Sprintf("%s [%s]", d.Message, d.Code)
+}
+
+// Pretty returns the diagnostic with the severity colored for printing to a terminal
+func (d Diagnostic) Pretty() string {
+	sev := color.Red(d.Severity.String())
+	if d.Severity == SeverityWarning {
+		sev = color.Yellow(d.Severity.String())
+	}
+	return fmt.Sprintf("%s: %s: %s", d.Position(), sev, d.message())
+}
+
+// Diagnostics is a list of diagnostics
+type Diagnostics []Diagnostic
+
+// HasErrors returns if any of the diagnostics are errors
+func (ds Diagnostics) HasErrors() bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Sort orders the diagnostics by file, line, then column
+func (ds Diagnostics) Sort() {
+	sort.SliceStable(ds, func(i, j int) bool {
+		a, b := ds[i].Token, ds[j].Token
+		if a.Path() != b.Path() {
+			return a.Path() < b.Path()
+		}
+		return a.Pos < b.Pos
+	})
+}
+
+// Unsuppressed returns the diagnostics that are not silenced by a comment
+func (ds Diagnostics) Unsuppressed() Diagnostics {
+	res := make(Diagnostics, 0, len(ds))
+	for _, d := range ds {
+		if !d.Suppressed() {
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+// PromoteWarnings turns every warning into an error
+func (ds Diagnostics) PromoteWarnings() {
+	for i := range ds {
+		ds[i].Severity = SeverityError
+	}
+}
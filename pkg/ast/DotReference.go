@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/geode-lang/geode/pkg/gtypes"
+	"github.com/geode-lang/geode/pkg/util/log"
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
@@ -43,6 +44,9 @@ func (n DotReference) BaseAddr(prog *Program) value.Value {
 			curBlock := prog.Compiler.CurrentBlock()
 			curBlock.Insts = append(curBlock.Insts, load)
 			val = load
+			if err := prog.nilCheck(val, n.Base, n.Token); err != nil {
+				log.Fatal("%s\n", err)
+			}
 		} else {
 			break
 		}
@@ -103,8 +107,12 @@ func (n DotReference) Alloca(prog *Program) value.Value {
 	// If the type that the alloca points to is a pointer, we need to load from the pointer
 	if types.IsPointer(elemType) {
 		base = prog.Compiler.CurrentBlock().NewLoad(elemType, base)
+		if err := prog.nilCheck(base, n.Base, n.Token); err != nil {
+			log.Fatal("%s\n", err)
+		}
 	}
-	structType := baseType.(*gtypes.StructType)
+	// The fields of a result, value and error, are found like those of a class
+	structType, _ := gtypes.FieldsOf(baseType)
 	index = structType.FieldIndex(n.Field.String())
 
 	zero := constant.NewInt(types.I32, 0)
@@ -128,7 +136,8 @@ func (n DotReference) Load(block *ir.Block, prog *Program) *ir.InstLoad {
 	target := n.Alloca(prog).(*ir.InstGetElementPtr)
 	t, _ := n.Type(prog)
 	target.Typ = types.NewPointer(t)
-	return block.NewLoad(t, target)
+	// The base may have been checked against nil, which moves on to a new block
+	return prog.Compiler.CurrentBlock().NewLoad(t, target)
 }
 
 // GenAssign implements Assignable.GenAssign
@@ -145,7 +154,7 @@ func (n DotReference) GenAccess(prog *Program) (value.Value, error) {
 
 // Type implements Assignable.Type
 func (n DotReference) Type(prog *Program) (types.Type, error) {
-	baseType := n.BaseType(prog).(*gtypes.StructType)
+	baseType, _ := gtypes.FieldsOf(n.BaseType(prog))
 	index := baseType.FieldIndex(n.Field.String())
 	return baseType.Fields[index], nil
 }
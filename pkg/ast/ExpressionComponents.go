@@ -204,6 +204,14 @@ func (c *NumberComponent) ConstructNode(prev Node) (Node, error) {
 	if n == nil {
 		return nil, fmt.Errorf("unable to get number type from number component's value")
 	}
+	switch num := n.(type) {
+	case IntNode:
+		num.Token = c.token
+		n = num
+	case FloatNode:
+		num.Token = c.token
+		n = num
+	}
 	return n, nil
 }
 
@@ -389,6 +397,28 @@ func (c *BooleanComponent) ConstructNode(prev Node) (Node, error) {
 	return n, nil
 }
 
+// =========================== NilComponent ===========================
+
+// NilComponent is an expression component for the nil pointer
+type NilComponent struct {
+	componentChainNode
+}
+
+// Ident implements ExpComponent.Ident
+func (c *NilComponent) Ident() string {
+	return "nil"
+}
+
+// ConstructNode returns the ast node for the expression component
+func (c *NilComponent) ConstructNode(prev Node) (Node, error) {
+
+	n := NilNode{}
+	n.Token = c.token
+	n.NodeType = nodeNil
+
+	return n, nil
+}
+
 // =========================== CharComponent ===========================
 
 // CharComponent is an expression component for numbers
@@ -438,3 +468,29 @@ func (c *TypeInfoComponent) ConstructNode(prev Node) (Node, error) {
 	n.T = c.Type
 	return n, nil
 }
+
+// =========================== ClassLiteralComponent ===========================
+
+// ClassLiteralComponent is an expression component for class literals
+type ClassLiteralComponent struct {
+	componentChainNode
+
+	Type   TypeNode
+	Fields []ClassLiteralField
+}
+
+// Ident implements ExpComponent.Ident
+func (c *ClassLiteralComponent) Ident() string {
+	node, _ := c.ConstructNode(nil)
+	return fmt.Sprintf("%s", node)
+}
+
+// ConstructNode returns the ast node for the expression component
+func (c *ClassLiteralComponent) ConstructNode(prev Node) (Node, error) {
+	n := ClassLiteralNode{}
+	n.Token = c.token
+	n.NodeType = nodeClassLiteral
+	n.Type = c.Type
+	n.Fields = c.Fields
+	return n, nil
+}
@@ -51,8 +51,9 @@ func (n ForNode) Codegen(prog *Program) (value.Value, error) {
 
 	n.Init.Codegen(prog)
 
-	parentBlock.NewBr(condBlk)
+	prog.Compiler.CurrentBlock().NewBr(condBlk)
 
+	condEndBlk := condBlk
 	err = prog.Compiler.genInBlock(condBlk, func() error {
 		predicate, _ = n.Cond.Codegen(prog)
 
@@ -61,6 +62,7 @@ func (n ForNode) Codegen(prog *Program) (value.Value, error) {
 			return err
 		}
 		predicate = c
+		condEndBlk = prog.Compiler.CurrentBlock()
 		return nil
 	})
 
@@ -95,6 +97,7 @@ func (n ForNode) Codegen(prog *Program) (value.Value, error) {
 		scp := prog.Scope
 		_, err := n.Step.Codegen(prog)
 		prog.Scope = scp
+		BranchIfNoTerminator(prog.Compiler.CurrentBlock(), condBlk)
 		return err
 	})
 
@@ -102,10 +105,9 @@ func (n ForNode) Codegen(prog *Program) (value.Value, error) {
 		return nil, err
 	}
 
-	BranchIfNoTerminator(stepBlk, condBlk)
 	endBlk = parentFunc.NewBlock(namePrefix + "end")
 	prog.Compiler.PushBlock(endBlk)
-	condBlk.NewCondBr(predicate, bodyBlk, endBlk)
+	condEndBlk.NewCondBr(predicate, bodyBlk, endBlk)
 
 	if err := prog.ScopeUp(); err != nil {
 		return nil, err
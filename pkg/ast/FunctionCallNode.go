@@ -20,6 +20,13 @@ type FunctionCallNode struct {
 	Args []Node
 }
 
+// locatedFunctions are the runtime functions that calls are compiled to
+// variants of that also take the file and line they were made on
+var locatedFunctions = map[string]string{
+	"assert": "__assert_at",
+	"panic":  "__panic_at",
+}
+
 // NewRuntimeFunctionCall returns a new function call value
 
 // NameString implements Node.NameString
@@ -91,6 +98,23 @@ func (n FunctionCallNode) Codegen(prog *Program) (value.Value, error) {
 		return nil, fmt.Errorf("unknown function %q referenced at %s", n.Name, n.Token.FileInfo())
 	}
 
+	// Calls to the runtime's assert and panic also pass where they were made
+	for name, at := range locatedFunctions {
+		if !prog.isRuntimeFunction(name, callee) || !n.Token.HasSource() {
+			continue
+		}
+		location, err := StringNode{Value: n.Token.FileInfo()}.Codegen(prog)
+		if err != nil {
+			return nil, err
+		}
+		callee, err = prog.GetFunction(at, FunctionCompilationOptions{})
+		if err != nil {
+			return nil, err
+		}
+		args = append([]value.Value{location}, args...)
+		break
+	}
+
 	// Attempt to typecast all the args into the correct type
 	for i, paramType := range callee.Sig.Params {
 		args[i], _ = createTypeCast(prog, args[i], paramType)
@@ -131,7 +155,9 @@ func (n FunctionCallNode) Codegen(prog *Program) (value.Value, error) {
 		arguments = append(arguments, arg)
 	}
 
-	return prog.Compiler.CurrentBlock().NewCall(callee, arguments...), nil
+	call := prog.Compiler.CurrentBlock().NewCall(callee, arguments...)
+	prog.traceCall(call, n.Token)
+	return call, nil
 }
 
 // Alloca implements Reference.Alloca
@@ -5,7 +5,9 @@ import (
 	"fmt"
 
 	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/gtypes"
 	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
 )
@@ -41,12 +43,16 @@ type FunctionNode struct {
 	External       bool
 	Variadic       bool
 	Nomangle       bool
+	Export         bool // exported to C under its own name by libraries built with --lib
 	ReturnType     TypeNode
 	DeclKeyword    FuncDeclKeywordType
 	ImplicitReturn bool
 	HasUnknownType bool
 	Package        *Package
 	IsMethod       bool
+	Doc            string // the comment written above the function
+	Test           string // the name of the test if the function is a test block
+	Bench          string // the name of the benchmark if the function is a bench block
 
 	// A cache so we can remember the name of the function to codegen
 	// This is because between the Program.GetFunction, where we
@@ -238,16 +244,22 @@ func (n FunctionNode) Codegen(prog *Program) (value.Value, error) {
 		// if the block we ended on does not return, we need to either error or return a new void
 		if block.Term == nil {
 
-			retType, err := prog.FindType(n.ReturnType.Name)
-			if err != nil {
-				return nil, err
-			}
+			retType := function.Sig.RetType
 			if retType.Equal(types.Void) {
 				// Automatically return void from the function
 				// new ret interpets a nil value as returning void
 				block.NewRet(nil)
+			} else if res, ok := retType.(*gtypes.ResultType); ok && res.IsVoid() {
+				// as does a void result, which succeeded
+				ret, _ := wrapResult(prog, nil, res)
+				block.NewRet(ret)
+			} else if reachable(function, block) {
+				// The checker makes sure every path returns, but it only sees the
+				// bodies of generic functions once they are compiled for some types
+				return nil, errorAt(n.Body.Close, "missing return on some path in function %s", n.Name)
 			} else {
-				return nil, fmt.Errorf("Function %s does not end in a return statement", namestring)
+				// A block nothing branches to, like the end of an if where both sides return
+				block.NewUnreachable()
 			}
 
 		}
@@ -260,6 +272,57 @@ func (n FunctionNode) Codegen(prog *Program) (value.Value, error) {
 	return function, nil
 }
 
+// reachable returns if a block can be reached from the entry of its function.
+// A branch on a constant only reaches the side it takes, so the end of a
+// `while true` loop that is only left by returning can't be reached
+func reachable(f *ir.Func, target *ir.Block) bool {
+	seen := map[*ir.Block]bool{f.Blocks[0]: true}
+	work := []*ir.Block{f.Blocks[0]}
+	for len(work) > 0 {
+		blk := work[len(work)-1]
+		work = work[:len(work)-1]
+		if blk == target {
+			return true
+		}
+		if blk.Term == nil {
+			continue
+		}
+		succs := blk.Term.Succs()
+		if br, ok := blk.Term.(*ir.TermCondBr); ok {
+			if cond, ok := br.Cond.(*constant.Int); ok {
+				succs = []*ir.Block{br.TargetFalse.(*ir.Block)}
+				if cond.X.Sign() != 0 {
+					succs = []*ir.Block{br.TargetTrue.(*ir.Block)}
+				}
+			}
+		}
+		for _, succ := range succs {
+			if !seen[succ] {
+				seen[succ] = true
+				work = append(work, succ)
+			}
+		}
+	}
+	return false
+}
+
+// Signature returns the declaration of the function without its body
+func (n FunctionNode) Signature() string {
+	buff := &bytes.Buffer{}
+	fmt.Fprintf(buff, "func %s(", n.Name)
+	for i, arg := range n.Args {
+		fmt.Fprintf(buff, "%s %s", arg.Type, arg.Name)
+		if i < len(n.Args)-1 || n.Variadic {
+			fmt.Fprintf(buff, ", ")
+		}
+	}
+	if n.Variadic {
+		fmt.Fprintf(buff, "...")
+	}
+	fmt.Fprintf(buff, ") %s", n.ReturnType)
+	return buff.String()
+}
+
 func createInitializationPrelude(prog *Program, n FunctionNode) {
 
 	// if the user disabled the runtime, we should just not do anything special
@@ -270,6 +333,10 @@ func createInitializationPrelude(prog *Program, n FunctionNode) {
 	if prog.Compiler.CurrentFunc().Name() == "main" {
 
 		prog.NewRuntimeFunctionCall("__init_runtime")
+		prog.Compiler.CurrentBlock().NewCall(prog.traceInit())
+		if *arg.Cover {
+			prog.Compiler.CurrentBlock().NewCall(prog.coverInit())
+		}
 
 		prog.Compiler.NewComment("User Code:")
 	}
@@ -290,17 +357,7 @@ func createInitializationPrelude(prog *Program, n FunctionNode) {
 
 func (n FunctionNode) String() string {
 	buff := &bytes.Buffer{}
-	fmt.Fprintf(buff, "func %s(", n.Name)
-	for i, arg := range n.Args {
-		fmt.Fprintf(buff, "%s", arg)
-		if i < len(n.Args)-1 || n.Variadic {
-			fmt.Fprintf(buff, ", ")
-		}
-	}
-	if n.Variadic {
-		fmt.Fprintf(buff, "...")
-	}
-	fmt.Fprintf(buff, ") %s ", n.ReturnType)
+	fmt.Fprintf(buff, "%s ", n.Signature())
 	if n.External {
 		fmt.Fprintf(buff, "...")
 	} else {
@@ -5,7 +5,7 @@ import (
 	"fmt"
 
 	"github.com/llir/llvm/ir"
-	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
 	"github.com/llir/llvm/ir/value"
 )
 
@@ -21,6 +21,7 @@ type GlobalVariableDeclNode struct {
 
 	GlobalDecl *ir.Global
 	Package    *Package
+	Doc        string // the comment written above the global
 }
 
 // NameString implements Node.NameString
@@ -41,11 +42,19 @@ func (n GlobalVariableDeclNode) Declare(prog *Program) (value.Value, error) {
 		return nil, err
 	}
 
-	init := constant.NewZeroInitializer(varType)
-
-	decl := prog.Module.NewGlobalDef(name, init)
-
-	if !n.External {
+	var decl *ir.Global
+	if n.External {
+		// External globals are defined by the C code the program links with
+		decl = prog.Module.NewGlobal(name, varType)
+		decl.Linkage = enum.LinkageExternal
+	} else {
+		init, err := prog.classDefault(varType)
+		if err != nil {
+			return nil, err
+		}
+		// The global is of the geode type, even if its defaults aren't
+		decl = prog.Module.NewGlobal(name, varType)
+		decl.Init = init
 		decl.SetName(MangleVariableName(name))
 	}
 
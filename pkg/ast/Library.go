@@ -0,0 +1,308 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/gtypes"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+)
+
+// export is a function a library exports, compiled under its own name
+type export struct {
+	node *FunctionNode
+	fn   *ir.Func
+}
+
+// CompileExports compiles the functions marked with export, as a library
+// has no main function that calls them. It returns how many there are
+func (p *Program) CompileExports() (int, error) {
+	names := make([]string, 0, len(p.Functions))
+	for name, fn := range p.Functions {
+		if fn.Export {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	exported := make(map[string]*FunctionNode)
+	for _, name := range names {
+		node := p.Functions[name]
+		if node.External {
+			return 0, errorAt(node.Token, "exported function %s has no body", node.Name)
+		}
+		for _, a := range node.Args {
+			if a.Type.Unknown {
+				return 0, errorAt(node.Token, "exported function %s can't take an argument of unknown type %s", node.Name, a.Type)
+			}
+		}
+		if other, ok := exported[node.Name.Value]; ok {
+			return 0, errorAt(node.Token, "function %s is already exported from %s", node.Name, other.Token.FileInfo())
+		}
+		exported[node.Name.Value] = node
+
+		fn, err := p.GetFunction(name, FunctionCompilationOptions{})
+		if err != nil {
+			return 0, err
+		}
+		p.exports = append(p.exports, export{node, fn})
+	}
+	return len(p.exports), nil
+}
+
+// AddLibraryInit initializes the runtime when a library is loaded, which
+// the main function of a program does otherwise. The function that does it
+// is run as a global constructor
+func (p *Program) AddLibraryInit() error {
+	if *arg.DisableRuntime {
+		return nil
+	}
+	initRuntime, err := p.GetFunction("__init_runtime", FunctionCompilationOptions{})
+	if err != nil {
+		return err
+	}
+
+	init := p.Module.NewFunc("__geode_init", types.Void)
+	init.Linkage = enum.LinkageInternal
+	blk := init.NewBlock("")
+	blk.NewCall(initRuntime)
+	blk.NewCall(p.traceInit())
+	if *arg.Cover {
+		blk.NewCall(p.coverInit())
+	}
+	blk.NewRet(nil)
+
+	ctorType := types.NewStruct(types.I32, types.NewPointer(init.Sig), types.I8Ptr)
+	ctor := constant.NewStruct(ctorType, constant.NewInt(types.I32, 65535), init, constant.NewNull(types.I8Ptr))
+	ctors := p.Module.NewGlobalDef("llvm.global_ctors", constant.NewArray(types.NewArray(1, ctorType), ctor))
+	ctors.Linkage = enum.LinkageAppending
+	return nil
+}
+
+// CHeader returns a C header that declares the functions a library exports
+// and the classes they use, as well as the classes marked with export. The
+// guard is the name of the macro that keeps it from being included twice
+func (p *Program) CHeader(guard string) (string, error) {
+	h := &cHeader{structs: make(map[string]*gtypes.StructType)}
+
+	classNames := make([]string, 0, len(p.Classes))
+	for name, cls := range p.Classes {
+		if cls.Export {
+			classNames = append(classNames, name)
+		}
+	}
+	sort.Strings(classNames)
+	for _, name := range classNames {
+		found := p.Scope.GetRoot().FindType(name)
+		if found == nil {
+			return "", fmt.Errorf("unable to find the type of exported class %s", name)
+		}
+		if _, err := h.typeName(found.Type); err != nil {
+			return "", errorAt(p.Classes[name].Token, "class %s can't be exported: %s", name, err)
+		}
+	}
+
+	decls := &bytes.Buffer{}
+	for _, e := range p.exports {
+		decl, err := h.function(e)
+		if err != nil {
+			return "", errorAt(e.node.Token, "function %s can't be exported: %s", e.node.Name, err)
+		}
+		if e.node.Doc != "" {
+			fmt.Fprintf(decls, "\n%s", cComment(e.node.Doc))
+		}
+		fmt.Fprintf(decls, "%s;\n", decl)
+	}
+
+	structs, err := h.structDefinitions()
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by geode build --lib. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "#ifndef %s\n#define %s\n\n", guard, guard)
+	fmt.Fprintf(buf, "#include <stdbool.h>\n#include <stdint.h>\n\n")
+	fmt.Fprintf(buf, "#ifdef __cplusplus\nextern \"C\" {\n#endif\n")
+	if structs != "" {
+		fmt.Fprintf(buf, "\n%s", structs)
+	}
+	buf.Write(decls.Bytes())
+	fmt.Fprintf(buf, "\n#ifdef __cplusplus\n}\n#endif\n\n#endif\n")
+	return buf.String(), nil
+}
+
+// cHeader collects the structs the declarations of a header use
+type cHeader struct {
+	structs map[string]*gtypes.StructType
+}
+
+// cComment turns a doc comment into a C one
+func cComment(doc string) string {
+	buf := &bytes.Buffer{}
+	for _, line := range strings.Split(strings.TrimRight(doc, "\n"), "\n") {
+		fmt.Fprintf(buf, "// %s\n", line)
+	}
+	return buf.String()
+}
+
+// function returns the C declaration of an exported function
+func (h *cHeader) function(e export) (string, error) {
+	params := make([]string, 0, len(e.fn.Params))
+	for i, param := range e.fn.Params {
+		name := param.Name()
+		if i < len(e.node.Args) {
+			name = e.node.Args[i].Name
+		}
+		decl, err := h.declaration(param.Type(), name)
+		if err != nil {
+			return "", err
+		}
+		params = append(params, decl)
+	}
+	if len(params) == 0 {
+		params = append(params, "void")
+	}
+	return h.declaration(e.fn.Sig.RetType, fmt.Sprintf("%s(%s)", e.fn.Name(), strings.Join(params, ", ")))
+}
+
+// declaration returns the C declaration of a name with a type
+func (h *cHeader) declaration(t types.Type, name string) (string, error) {
+	if arr, ok := t.(*types.ArrayType); ok {
+		return h.declaration(arr.ElemType, fmt.Sprintf("%s[%d]", name, arr.Len))
+	}
+	typ, err := h.typeName(t)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", typ, name), nil
+}
+
+// typeName returns the C name of a type. Classes are declared as structs
+// with the name of the class
+func (h *cHeader) typeName(t types.Type) (string, error) {
+	switch t := t.(type) {
+	case *types.VoidType:
+		return "void", nil
+	case *types.IntType:
+		switch t.BitSize {
+		case 1:
+			return "bool", nil
+		case 8:
+			return "char", nil
+		case 16, 32, 64:
+			return fmt.Sprintf("int%d_t", t.BitSize), nil
+		case 128:
+			return "__int128", nil
+		}
+	case *types.FloatType:
+		switch t.Kind {
+		case types.FloatKindFloat:
+			return "float", nil
+		case types.FloatKindDouble:
+			return "double", nil
+		}
+	case *types.PointerType:
+		if _, ok := t.ElemType.(*types.FuncType); ok {
+			break
+		}
+		if _, ok := t.ElemType.(*types.ArrayType); ok {
+			break
+		}
+		elem, err := h.typeName(t.ElemType)
+		if err != nil {
+			return "", err
+		}
+		return elem + "*", nil
+	case *gtypes.StructType:
+		name := cStructName(t)
+		if _, ok := h.structs[name]; !ok {
+			h.structs[name] = t
+			// The fields can use other classes that need to be declared
+			for _, field := range t.Fields {
+				if _, err := h.declaration(field, "_"); err != nil {
+					return "", err
+				}
+			}
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("type %s has no C equivalent", t)
+}
+
+// cStructName returns the name a class is declared with in C, which is
+// the class's name without its package
+func cStructName(t *gtypes.StructType) string {
+	name := strings.TrimPrefix(t.Name(), "class.")
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// structDefinitions returns the typedefs of the structs a header uses and
+// their definitions, in an order where a struct comes after the ones it
+// contains. Classes without fields are left opaque
+func (h *cHeader) structDefinitions() (string, error) {
+	names := make([]string, 0, len(h.structs))
+	for name := range h.structs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	for _, name := range names {
+		fmt.Fprintf(buf, "typedef struct %s %s;\n", name, name)
+	}
+
+	defined := make(map[string]bool)
+	var define func(name string) error
+	define = func(name string) error {
+		t := h.structs[name]
+		if defined[name] || len(t.Fields) == 0 {
+			return nil
+		}
+		defined[name] = true
+		for _, field := range t.Fields {
+			for {
+				arr, ok := field.(*types.ArrayType)
+				if !ok {
+					break
+				}
+				field = arr.ElemType
+			}
+			if inner, ok := field.(*gtypes.StructType); ok {
+				if err := define(cStructName(inner)); err != nil {
+					return err
+				}
+			}
+		}
+
+		fmt.Fprintf(buf, "\nstruct %s {\n", name)
+		for i, field := range t.Fields {
+			fieldName := fmt.Sprintf("field%d", i)
+			if i < len(t.Names) {
+				fieldName = t.Names[i]
+			}
+			decl, err := h.declaration(field, fieldName)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(buf, "\t%s;\n", decl)
+		}
+		fmt.Fprintf(buf, "};\n")
+		return nil
+	}
+	for _, name := range names {
+		if err := define(name); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
@@ -31,6 +31,9 @@ type Linker struct {
 	buildDir    string
 	objectPaths []string
 	optimize    int
+	sanitizers  []string
+	toolchain   Toolchain
+	library     string
 }
 
 // NewLinker constructs a linker with an outpu
@@ -80,6 +83,43 @@ func (l *Linker) SetOptimize(o int) {
 	l.optimize = o
 }
 
+// Sanitizers are the names --sanitize accepts
+var Sanitizers = []string{"address", "undefined", "leak"}
+
+// ParseSanitizers splits the comma separated list of sanitizers passed to
+// --sanitize, and returns an error if one of them isn't supported
+func ParseSanitizers(list string) ([]string, error) {
+	if list == "" {
+		return nil, nil
+	}
+	names := strings.Split(list, ",")
+	for _, name := range names {
+		known := false
+		for _, s := range Sanitizers {
+			known = known || name == s
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown sanitizer %q, expected one of %s", name, strings.Join(Sanitizers, ", "))
+		}
+	}
+	return names, nil
+}
+
+// SetSanitizers sets the sanitizers the program and the C files it links are built with
+func (l *Linker) SetSanitizers(s []string) {
+	l.sanitizers = s
+}
+
+// SetToolchain sets the toolchain the linker builds the program with
+func (l *Linker) SetToolchain(tc Toolchain) {
+	l.toolchain = tc
+}
+
+// SetLibrary makes the linker build a static or shared library instead of a program
+func (l *Linker) SetLibrary(kind string) {
+	l.library = kind
+}
+
 // Cleanup removes all the
 func (l *Linker) Cleanup() {
 	for _, objFile := range l.objectPaths {
@@ -92,140 +132,147 @@ func (l *Linker) Cleanup() {
 	}
 }
 
+// options returns how the toolchain should build the objects
+func (l *Linker) options() BuildOptions {
+	opts := BuildOptions{
+		Optimize:   l.optimize,
+		Sanitizers: l.sanitizers,
+		Debug:      *arg.EnableDebug,
+		Library:    l.library,
+	}
+	if *arg.ClangFlags != "" {
+		opts.Flags = strings.Split(*arg.ClangFlags, " ")
+	}
+	return opts
+}
+
+// emit compiles the ir the compiler generated into files in the current
+// directory, for --asm, --llvm and --obj. The C files aren't emitted, as
+// we only want to leave user generated files in the filesystem
+func (l *Linker) emit(kind OutputKind, ext string) {
+	for _, obj := range l.objectPaths {
+		if strings.HasSuffix(obj, ".ll") {
+			out := path.Base(strings.Replace(obj, path.Ext(obj), ext, -1))
+			if err := l.toolchain.CompileIR(obj, out, kind, l.options()); err != nil {
+				log.Error("%s\n", err)
+			}
+		}
+	}
+}
+
+// compileC compiles a C file into an object in the build directory, and
+// returns the object's path. The object is reused while the file and the
+// options and target it is built with stay the same. A package's
+// replacement of the file for the target is compiled in its place
+func (l *Linker) compileC(file string) string {
+	triple := l.toolchain.Triple()
+	// Objects built for each target are kept apart
+	outbase := path.Join(l.buildDir, triple, file)
+	file = targetFile(file, triple)
+	outbase = outbase[0 : len(outbase)-len(filepath.Ext(outbase))]
+	// Objects built for the sanitizers are kept apart from the normal ones
+	if len(l.sanitizers) > 0 {
+		outbase += ".sanitize-" + strings.Join(l.sanitizers, "-")
+	}
+	// So are the position independent ones libraries are built from
+	if l.library != "" {
+		outbase += ".pic"
+	}
+
+	cachefile := outbase + ".cache"
+	objFile := outbase + ".o"
+
+	hash := l.toolchain.Name() + " " + util.HashFile(file)
+
+	cachedat, err := ioutil.ReadFile(cachefile)
+	if err != nil || string(cachedat) != hash {
+		os.MkdirAll(path.Dir(outbase), os.ModePerm)
+
+		// the object doesnt exist or is out of date, we need to compile it.
+		// It is renamed into place, as other builds may be linking the old one
+		tmpFile := fmt.Sprintf("%s.%d.o", outbase, os.Getpid())
+		if err := l.toolchain.CompileC(file, tmpFile, l.options()); err != nil {
+			os.Remove(tmpFile)
+			log.Fatal("%s\n", err)
+		}
+		if err := os.Rename(tmpFile, objFile); err != nil {
+			log.Fatal("%s\n", err)
+		}
+		ioutil.WriteFile(cachefile, []byte(hash), os.ModePerm)
+	}
+	return objFile
+}
+
 // Run a list of objects through a linker and build
 // into a single outfile with the given target
 func (l *Linker) Run() {
-	linker := "clang"
-	linkArgs := make([]string, 0)
-
-	optString := fmt.Sprintf("-O%d", l.optimize)
+	if l.toolchain == nil {
+		log.Fatal("No toolchain to build with\n")
+	}
 
-	if l.optimize > 0 && l.optimize <= 3 {
-		linkArgs = append(linkArgs, optString)
+	if len(l.sanitizers) > 0 && IsWASI(l.toolchain.Triple()) {
+		log.Fatal("The sanitizers aren't supported on %s\n", l.toolchain.Triple())
+	}
+	if l.library == "shared" && targetArch(l.toolchain.Triple()) == "wasm32" {
+		log.Fatal("Shared libraries aren't supported on %s, build a static one\n", l.toolchain.Triple())
 	}
 
-	filename := l.output
+	// Only the clang driver runs the sanitizers' instrumentation over llvm ir
+	if len(l.sanitizers) > 0 && l.toolchain.Name() != "clang" {
+		log.Fatal("The %s toolchain can't instrument geode code for the sanitizers, build with --toolchain=clang\n", l.toolchain.Name())
+	}
 
 	hadAlternateEmission := false
 
 	if *arg.EmitASM {
 		hadAlternateEmission = true
 		log.Timed("Assembly Generation", func() {
-			// We want to only write intel syntax. AT&T Sucks
-			asmArgs := append(linkArgs, "-S", "-masm=intel", "-Wno-everything")
-			// Compile each of the objects to a .s file.
-			for _, obj := range l.objectPaths {
-				// We only want to leave user generated files in the filesystem
-				if strings.HasSuffix(obj, ".ll") {
-					// ext := path.Ext(obj)
-					out := path.Base(strings.Replace(obj, path.Ext(obj), ".s", -1))
-					util.RunCommandStr(linker, append(asmArgs, "-o", out, obj)...)
-				}
-			}
+			l.emit(AssemblyOutput, ".s")
 		})
-
 	}
 
 	if *arg.EmitLLVM {
 		hadAlternateEmission = true
-
 		log.Timed("LLVM Generation", func() {
-			// Compile each of the objects to a .s file.
-			for _, obj := range l.objectPaths {
-				// We only want to leave user generated files in the filesystem
-				if strings.HasSuffix(obj, ".ll") {
-					out := path.Base(strings.Replace(obj, path.Ext(obj), ".ll", -1))
-
-					res, err := util.RunCommandStr(linker, append(linkArgs, "-S", "-emit-llvm", "-o", out, obj)...)
-					if err != nil {
-						log.Error("Failed with llvm generation:\n")
-						fmt.Println(res)
-					}
-				}
-			}
+			l.emit(IROutput, ".ll")
 		})
 	}
 
 	if *arg.EmitObject {
 		hadAlternateEmission = true
-
 		log.Timed("Object File Generation", func() {
-			// Compile each of the objects to a .s file.
-			for _, obj := range l.objectPaths {
-
-				// We only want to leave user generated files in the filesystem
-				if strings.HasSuffix(obj, ".ll") {
-
-					out := path.Base(strings.Replace(obj, path.Ext(obj), ".o", -1))
-					util.RunCommandStr(linker, append(linkArgs, "-c", "-o", out, obj)...)
-				}
-			}
+			l.emit(ObjectOutput, ".o")
 		})
 	}
 
-	linkArgs = append(linkArgs, "--std=c99", "-lm", "-lc", "-lgc", "-pthread", "-DREDIRECT_MALLOC=xmalloc", "-DIGNORE_FREE")
-
-	if !hadAlternateEmission {
-		for i, obj := range l.objectPaths {
-			outbase := path.Join(l.buildDir, obj)
-
-			extension := filepath.Ext(outbase)
-			if extension == ".ll" {
-			}
-
-			if extension == ".a" {
-
-			}
-
-			if extension == ".c" {
-				outbase = outbase[0 : len(outbase)-len(extension)]
-
-				cachefile := outbase + ".cache"
-				objFile := outbase + ".o"
-
-				hash := util.HashFile(obj)
-
-				cachedat, err := ioutil.ReadFile(cachefile)
-				if err != nil || strings.Compare(string(cachedat), hash) != 0 {
-
-					os.MkdirAll(path.Dir(outbase), os.ModePerm)
+	if hadAlternateEmission {
+		return
+	}
 
-					// fmt.Printf("\tCC\t%s\n", path.Base(obj))
-					// the file doesnt exist, we need to compile it
-					out, err := util.RunCommand("clang", "-O3", "--std=c99", "-c", "-o", objFile, obj)
-					if err != nil {
-						log.Fatal("(%s) %s\n", err, string(out))
-					}
-					ioutil.WriteFile(cachefile, []byte(hash), os.ModePerm)
-				}
-				l.objectPaths[i] = objFile
+	objects := make([]string, 0, len(l.objectPaths))
+	for _, obj := range l.objectPaths {
+		switch filepath.Ext(obj) {
+		case ".c":
+			objects = append(objects, l.compileC(obj))
+		case ".ll":
+			// The C file a package links can have the same name as the package
+			out := obj + ".o"
+			if err := l.toolchain.CompileIR(obj, out, ObjectOutput, l.options()); err != nil {
+				log.Fatal("%s\n", err)
 			}
-
-		}
-
-		// return
-
-		// Append input files to the end of the command
-		linkArgs = append(linkArgs, l.objectPaths...)
-
-		if *arg.EnableDebug {
-			linkArgs = append(linkArgs, "-g")
-		}
-
-		// set the output filename
-		linkArgs = append(linkArgs, "-o", filename)
-
-		if *arg.ClangFlags != "" {
-			userArgs := strings.Split(*arg.ClangFlags, " ")
-			linkArgs = append(linkArgs, userArgs...)
-		}
-
-		out, err := util.RunCommand(linker, linkArgs...)
-		if err != nil {
-			log.Fatal("failed to run command `%s %s`: `%s`\n\n%s",
-				linker, strings.Join(linkArgs, " "),
-				err.Error(), string(out))
+			objects = append(objects, out)
+		default:
+			objects = append(objects, obj)
 		}
 	}
 
+	var err error
+	if l.library == "static" {
+		err = l.toolchain.Archive(objects, l.output)
+	} else {
+		err = l.toolchain.Link(objects, l.output, l.options())
+	}
+	if err != nil {
+		log.Fatal("%s\n", err)
+	}
 }
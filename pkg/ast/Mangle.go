@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/llir/llvm/ir/types"
@@ -156,7 +157,7 @@ func GetMangleParts(mangled string) ([]ManglePart, error) {
 		'G': GenericMangle,
 	}
 
-	rawParts := strings.Split(mangled, separator)
+	rawParts := splitMangled(mangled)
 	parts := make([]ManglePart, 0, len(rawParts))
 
 	if rawParts[0] == functionNamePrefix {
@@ -173,6 +174,9 @@ func GetMangleParts(mangled string) ([]ManglePart, error) {
 	}
 
 	for _, rawPart := range rawParts {
+		if rawPart == "" {
+			return nil, fmt.Errorf("empty part in mangled name %s", mangled)
+		}
 		typeChar := rawPart[0]
 
 		typ, ok := typeCharRefs[typeChar]
@@ -189,6 +193,24 @@ func GetMangleParts(mangled string) ([]ManglePart, error) {
 	return parts, nil
 }
 
+// splitMangled splits a mangled name into its parts. Class types are
+// quoted, like %"class.io:File"*, so separators in quotes don't split
+func splitMangled(mangled string) []string {
+	parts := make([]string, 0)
+	quoted := false
+	start := 0
+	for i := 0; i < len(mangled); i++ {
+		switch {
+		case mangled[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(mangled[i:], separator):
+			parts = append(parts, mangled[start:i])
+			start = i + len(separator)
+		}
+	}
+	return append(parts, mangled[start:])
+}
+
 // UnmangleFunctionName takes some mangled name and returns the unmangled one
 func UnmangleFunctionName(mangled string) (string, error) {
 	if mangled == "main" || !strings.HasPrefix(mangled, functionNamePrefix) {
@@ -229,3 +251,153 @@ func ParseName(combined string) (string, string) {
 
 	return namespace, name
 }
+
+// demangledTypes are the geode names of the llvm types in mangled names
+var demangledTypes = map[string]string{
+	"i1":     "bool",
+	"i8":     "byte",
+	"i16":    "short",
+	"i32":    "int",
+	"i64":    "long",
+	"i128":   "big",
+	"double": "float",
+}
+
+// llvmTypeName matches the named types and the words in an llvm type
+var llvmTypeName = regexp.MustCompile(`%"[^"]*"|%[\w.:]+|\b[a-z]\w*\b`)
+
+// llvmResultType matches the struct a result is, like
+// { i32, %"class.runtime:Error"* } for an int!
+var llvmResultType = regexp.MustCompile(`\{ (?:([^{}]*), )?%"class\.runtime:Error"\* \}`)
+
+// demangleType returns the geode spelling of an llvm type, like
+// io:File* for %"class.io:File"*
+func demangleType(t string) string {
+	t = llvmResultType.ReplaceAllStringFunc(t, func(res string) string {
+		elem := llvmResultType.FindStringSubmatch(res)[1]
+		if elem == "" {
+			elem = "void"
+		}
+		return elem + "!"
+	})
+	return llvmTypeName.ReplaceAllStringFunc(t, func(name string) string {
+		if strings.HasPrefix(name, "%") {
+			return strings.TrimPrefix(strings.Trim(name[1:], `"`), "class.")
+		}
+		if geode, ok := demangledTypes[name]; ok {
+			return geode
+		}
+		return name
+	})
+}
+
+// Demangle returns the signature a mangled name stands for, like
+// io:print(byte*) void for _X:Mio:Nprint:Ti8*:Rvoid. Global variables
+// demangle to their name, and names that aren't mangled are returned as is
+func Demangle(mangled string) (string, error) {
+	function := strings.HasPrefix(mangled, functionNamePrefix+separator)
+	if !function && !strings.HasPrefix(mangled, globalVariableNamePrefix+separator) {
+		return mangled, nil
+	}
+
+	names := make([]string, 0)
+	args := make([]string, 0)
+	ret := ""
+	for i, part := range splitMangled(mangled)[1:] {
+		if part == "" || part[0] == 'G' {
+			continue
+		}
+		value := part[1:]
+		valid := value != ""
+		switch part[0] {
+		case 'M':
+			valid = valid && i == 0
+			names = append(names, value)
+		case 'N':
+			valid = valid && len(names) > 0 && len(args) == 0 && ret == ""
+			names = append(names, value)
+		case 'T':
+			valid = valid && function && len(names) > 0 && ret == ""
+			args = append(args, demangleType(value))
+		case 'R':
+			valid = valid && function && len(names) > 0 && ret == ""
+			ret = demangleType(value)
+		default:
+			valid = false
+		}
+		if !valid {
+			return "", fmt.Errorf("invalid part in mangled name %s: %s", mangled, part)
+		}
+	}
+	if len(names) == 0 || function && ret == "" {
+		return "", fmt.Errorf("incomplete mangled name %s", mangled)
+	}
+
+	// The first name is the package, and the rest are the names in it,
+	// like the class and the method
+	name := names[0]
+	if len(names) > 1 {
+		name += separator + strings.Join(names[1:], ".")
+	}
+	if !function {
+		return name, nil
+	}
+	return fmt.Sprintf("%s(%s) %s", name, strings.Join(args, ", "), ret), nil
+}
+
+// mangledStart matches where a mangled name starts
+var mangledStart = regexp.MustCompile(`_[XV]:M`)
+
+// DemangleText replaces the mangled names in some text, like the output of
+// nm, perf or a debugger, with the signatures they stand for. Anything that
+// only looks like a mangled name is left alone
+func DemangleText(text string) string {
+	buff := &bytes.Buffer{}
+	for {
+		loc := mangledStart.FindStringIndex(text)
+		if loc == nil {
+			break
+		}
+		end := loc[0] + mangledLen(text[loc[0]:])
+		name := strings.TrimRight(text[loc[0]:end], separator+".")
+		demangled, err := Demangle(name)
+		if err != nil {
+			buff.WriteString(text[:loc[1]])
+			text = text[loc[1]:]
+			continue
+		}
+		buff.WriteString(text[:loc[0]])
+		buff.WriteString(demangled)
+		text = text[loc[0]+len(name):]
+	}
+	buff.WriteString(text)
+	return buff.String()
+}
+
+// mangledLen returns the length of the mangled name some text starts with.
+// It ends at a space or at punctuation outside of a quoted class type or a
+// struct type, like the { i32, %"class.runtime:Error"* } of an int!
+func mangledLen(text string) int {
+	quoted := false
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\n':
+			return i
+		case c == '"' && quoted:
+			quoted = false
+		case c == '"' && i > 0 && text[i-1] == '%':
+			quoted = true
+		case quoted:
+		case c == '{' && (depth > 0 || strings.HasSuffix(text[:i], separator+"T") || strings.HasSuffix(text[:i], separator+"R")):
+			depth++
+		case c == '}' && depth > 0:
+			depth--
+		case depth > 0:
+		case c <= ' ' || strings.IndexByte("\"'`,;()<>[]{}+@", c) >= 0:
+			return i
+		}
+	}
+	return len(text)
+}
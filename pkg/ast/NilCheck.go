@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/lexer"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// NilChecksEnabled returns if pointers are checked against nil before they
+// are dereferenced, which --nil-checks=auto does in builds without -O
+func NilChecksEnabled() bool {
+	switch *arg.NilChecks {
+	case "on":
+		return true
+	case "off":
+		return false
+	}
+	return *arg.Optimize == 0
+}
+
+// nilCheck panics with the location of a dereference when the pointer it
+// goes through is nil, instead of leaving the program to crash on it. The
+// checker already rejects dereferences of nullable pointers that weren't
+// tested, so this catches the ones it can't see, like a nil from C or one
+// cast away with `as`
+func (p *Program) nilCheck(ptr value.Value, what interface{}, at lexer.Token) error {
+	if !NilChecksEnabled() || !at.HasSource() || p.generated[at.Path()] {
+		return nil
+	}
+	typ, ok := ptr.Type().(*types.PointerType)
+	if !ok {
+		return nil
+	}
+	// Programs built with --no-runtime have nothing to panic with
+	if _, exists := p.Functions["__panic_at"]; !exists {
+		return nil
+	}
+	panicAt, err := p.GetFunction("__panic_at", FunctionCompilationOptions{})
+	if err != nil {
+		return err
+	}
+
+	blk := p.Compiler.CurrentBlock()
+	nilBlk := blk.Parent.NewBlock(mangleName("nil.panic"))
+	okBlk := blk.Parent.NewBlock(mangleName("nil.ok"))
+	isNil := blk.NewICmp(enum.IPredEQ, ptr, constant.NewNull(typ))
+	blk.NewCondBr(isNil, nilBlk, okBlk)
+
+	// The strings are left constant, as the program is about to end
+	location := p.constString(at.FileInfo())
+	msg := p.constString(fmt.Sprintf("nil dereference of %s", what))
+	p.traceCall(nilBlk.NewCall(panicAt, location, msg), at)
+	nilBlk.NewUnreachable()
+
+	p.Compiler.PushBlock(okBlk)
+	return nil
+}
@@ -8,6 +8,15 @@ import (
 	"github.com/llir/llvm/ir/value"
 )
 
+// nilType is the type of nil. It is a byte* like any other, but it is the
+// only one that can be passed or returned as a pointer of another type
+var nilType = types.NewPointer(types.I8)
+
+// isNilType returns if a type is the type of nil
+func isNilType(t types.Type) bool {
+	return t == nilType
+}
+
 // NilNode -
 type NilNode struct {
 	NodeType
@@ -19,7 +28,7 @@ func (n NilNode) NameString() string { return "NilNode" }
 
 // Codegen implements Node.Codegen for NilNode
 func (n NilNode) Codegen(prog *Program) (value.Value, error) {
-	return constant.NewNull(types.NewPointer(types.I8)), nil
+	return constant.NewNull(nilType), nil
 }
 
 // GenAccess implements Accessable.GenAccess
@@ -29,6 +29,11 @@ func (t TokenReference) SyntaxError() {
 	t.Token.SyntaxError()
 }
 
+// GetToken returns the token the node was parsed from
+func (t TokenReference) GetToken() lexer.Token {
+	return t.Token
+}
+
 // Node -
 type Node interface {
 	fmt.Stringer
@@ -76,6 +81,7 @@ const (
 	nodeNil                   = "nodeNil"
 	nodeIdent                 = "nodeIdent"
 	nodeStringFormat          = "nodeStringFormat"
+	nodeClassLiteral          = "nodeClassLiteral"
 )
 
 //
@@ -140,7 +146,12 @@ type UnaryNode struct {
 
 func (n UnaryNode) String() string {
 	buff := &bytes.Buffer{}
-	fmt.Fprintf(buff, "UnaryNode")
+	// A keyword is kept apart from what it operates on
+	if n.Operator == "try" {
+		fmt.Fprintf(buff, "try %s", n.Operand)
+		return buff.String()
+	}
+	fmt.Fprintf(buff, "%s%s", n.Operator, n.Operand)
 	return buff.String()
 }
 
@@ -163,6 +174,7 @@ type DependencyNode struct {
 
 	Paths    []string
 	CLinkage bool
+	CHeader  bool // include c "stdio.h" binds the C headers the paths name
 }
 
 func (n DependencyNode) String() string {
@@ -170,6 +182,8 @@ func (n DependencyNode) String() string {
 
 	if n.CLinkage {
 		fmt.Fprintf(buff, "link ")
+	} else if n.CHeader {
+		fmt.Fprintf(buff, "include c ")
 	} else {
 		fmt.Fprintf(buff, "include ")
 	}
@@ -263,6 +277,10 @@ const (
 	ModifierPointer TypeModifier = iota
 	ModifierSlice
 	ModifierUnknown
+	// ModifierNullable follows a pointer that can be nil, like `T*?`
+	ModifierNullable
+	// ModifierResult ends a type that is a value or an error, like `T!`
+	ModifierResult
 )
 
 // TypeNode -
@@ -286,6 +304,10 @@ func (n TypeNode) String() string {
 			fmt.Fprintf(buff, "*")
 		case ModifierSlice:
 			fmt.Fprintf(buff, "[]")
+		case ModifierUnknown, ModifierNullable:
+			fmt.Fprintf(buff, "?")
+		case ModifierResult:
+			fmt.Fprintf(buff, "!")
 		}
 	}
 
@@ -307,8 +329,13 @@ func (n TypeNode) GetType(prog *Program) (types.Type, error) {
 				ty = types.NewPointer(ty)
 			case ModifierSlice:
 				ty = gtypes.NewSlice(ty)
-			case ModifierUnknown:
-				//
+			case ModifierUnknown, ModifierNullable:
+				// Neither changes the representation of the type
+			case ModifierResult:
+				ty, err = prog.resultType(ty)
+				if err != nil {
+					return nil, err
+				}
 			default:
 				return nil, fmt.Errorf("unknown type modifier %d on type %q", mod, n)
 			}
@@ -0,0 +1,371 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/geode-lang/geode/pkg/gtypes"
+	"github.com/geode-lang/geode/pkg/lexer"
+	"github.com/llir/llvm/ir/types"
+)
+
+// nullness has a bit set for each level of pointer in a type that may be
+// nil. The lowest bit is the outermost pointer, so a `T*?*` is 0b10 as the
+// pointer it points to can be nil but it can't. A pointer is only nullable
+// when it is declared with a `?`, like `T*?`, and nil can't be stored in
+// or dereferenced out of one that isn't.
+type nullness uint
+
+// mayBeNil returns if the outermost pointer may be nil
+func (n nullness) mayBeNil() bool {
+	return n&1 != 0
+}
+
+// nullness returns which levels of pointer in a type can be nil
+func (n TypeNode) nullness() nullness {
+	var mask nullness
+	for _, mod := range n.Modifiers {
+		switch mod {
+		case ModifierPointer:
+			mask <<= 1
+		case ModifierNullable:
+			mask |= 1
+		}
+	}
+	return mask
+}
+
+// nilFacts are the local variables known not to be nil at some point in a
+// function, because they were checked against nil or just assigned a value
+// that can't be. The facts are copied when they are changed so the ones
+// from before a branch stay as they were.
+type nilFacts map[*checkVar]bool
+
+// with returns the facts with the variables added to them
+func (f nilFacts) with(vars []*checkVar) nilFacts {
+	res := make(nilFacts, len(f)+len(vars))
+	for v := range f {
+		res[v] = true
+	}
+	for _, v := range vars {
+		res[v] = true
+	}
+	return res
+}
+
+// and returns the facts that hold in both f and o
+func (f nilFacts) and(o nilFacts) nilFacts {
+	res := make(nilFacts)
+	for v := range f {
+		if o[v] {
+			res[v] = true
+		}
+	}
+	return res
+}
+
+// join returns the facts after an if statement from the facts at the end
+// of each branch. A branch that always returns doesn't get to the end, so
+// only the other one counts
+func join(thenReturns bool, then nilFacts, elseReturns bool, otherwise nilFacts) nilFacts {
+	switch {
+	case thenReturns && !elseReturns:
+		return otherwise
+	case elseReturns && !thenReturns:
+		return then
+	}
+	return then.and(otherwise)
+}
+
+// nullness returns which levels of pointer the value of an expression can be
+// nil at. The expression must already have been checked, as calls and field
+// accesses look up what checking them found
+func (c *Checker) nullness(node Node) nullness {
+	switch n := node.(type) {
+	case NilNode:
+		return 1
+	case IdentNode:
+		if v := c.lookup(n.Value); v != nil {
+			if c.nonNil[v] {
+				return v.Null &^ 1
+			}
+			return v.Null
+		}
+		return c.globalNullness(n.Value)
+	case UnaryNode:
+		switch n.Operator {
+		case "*":
+			return c.nullness(n.Operand) >> 1
+		case "&":
+			return c.nullness(n.Operand) << 1
+		case "try":
+			return c.nullness(n.Operand)
+		}
+	case BinaryNode:
+		if n.OP == "or" {
+			return c.nullness(n.Left) | c.nullness(n.Right)
+		}
+	case SubscriptNode:
+		if src, ok := n.Source.(Node); ok {
+			return c.nullness(src) >> 1
+		}
+	case FunctionCallNode:
+		return c.nulls[n.Token]
+	case DotReference:
+		return c.nulls[n.Token]
+	case CastNode:
+		// A cast is how a value that is known not to be nil, in a way the
+		// checker can't see, is made into a pointer that can't be
+		return n.Type.nullness()
+	case VariableDefnNode:
+		if n.NeedsInference {
+			return c.nullness(n.Body)
+		}
+		return n.Typ.nullness()
+	}
+	return 0
+}
+
+// globalNullness returns the nullness a global variable was declared with
+func (c *Checker) globalNullness(name string) nullness {
+	paths := []string{name, fmt.Sprintf("%s:%s", c.Program.Package.Name, name)}
+	for _, path := range paths {
+		if mask, ok := c.globalNulls[path]; ok {
+			return mask
+		}
+	}
+	return 0
+}
+
+// nonNilPointer returns if a type is written as a pointer that can't be
+// nil, like `T*` but not `T*?`
+func (n TypeNode) nonNilPointer() bool {
+	l := len(n.Modifiers)
+	return l > 0 && n.Modifiers[l-1] == ModifierPointer
+}
+
+// unset reports a variable declared without a value that would start out
+// holding nil where nil isn't allowed, either itself or in a field
+func (c *Checker) unset(name string, typ TypeNode, t types.Type, tok lexer.Token) {
+	if typ.nonNilPointer() {
+		c.errorf(tok, "%s must be given a value, as %s can't be nil (declare it as %s? to allow nil)", name, typ, typ)
+		return
+	}
+	if path, ftyp, ok := c.unsetPointer(t); ok {
+		c.errorf(tok, "%s must be given a value, as its field %s is a %s that can't be nil", name, path, ftyp)
+	}
+}
+
+// unsetPointer finds a field of a class that is a pointer that can't be
+// nil, but has no default so it is nil when the class starts out as its
+// defaults. The path to the field is returned, like `head.next`
+func (c *Checker) unsetPointer(t types.Type) (string, TypeNode, bool) {
+	st, ok := t.(*gtypes.StructType)
+	if !ok {
+		return "", TypeNode{}, false
+	}
+	for i, name := range st.Names {
+		field, ok := c.classField(st, name)
+		if !ok || field.HasValue {
+			continue
+		}
+		if field.Typ.nonNilPointer() {
+			return name, field.Typ, true
+		}
+		if path, typ, ok := c.unsetPointer(st.Fields[i]); ok {
+			return name + "." + path, typ, true
+		}
+	}
+	return "", TypeNode{}, false
+}
+
+// fieldNullness returns the nullness a field of a class was declared with
+func (c *Checker) fieldNullness(st types.Type, field string) nullness {
+	if v, ok := c.classField(st, field); ok {
+		return v.Typ.nullness()
+	}
+	return 0
+}
+
+// classField returns the declaration of a field of a class
+func (c *Checker) classField(st types.Type, field string) (VariableDefnNode, bool) {
+	name, err := c.Program.Scope.FindTypeName(st)
+	if err != nil {
+		return VariableDefnNode{}, false
+	}
+	class, ok := c.Program.Classes[name]
+	if !ok {
+		return VariableDefnNode{}, false
+	}
+	for _, v := range class.Variables {
+		if v.Name.Value == field {
+			return v, true
+		}
+	}
+	return VariableDefnNode{}, false
+}
+
+// nilHint is the advice given with an error about a value that may be nil.
+// Only local variables learn from checks against nil, so anything else has
+// to be copied into one first
+func (c *Checker) nilHint(node Node) string {
+	if ident, ok := node.(IdentNode); ok && c.lookup(ident.Value) != nil {
+		return fmt.Sprintf("check that %s != nil first", ident.Value)
+	}
+	return "copy it into a local variable and check that against nil first"
+}
+
+// derefable reports an error if the outermost pointer of a value is
+// dereferenced while it may be nil
+func (c *Checker) derefable(node Node, at Node, action string) {
+	if !c.nullness(node).mayBeNil() {
+		return
+	}
+	c.errorf(tokenOf(at), "%s may be nil and is %s here (%s)", node, action, c.nilHint(node))
+}
+
+// storable reports an error if a value that may be nil is stored where nil
+// isn't allowed, like a variable, argument or field of type `T*`
+func (c *Checker) storable(value Node, target nullness, to string) {
+	if value == nil || c.nullness(value)&^target == 0 {
+		return
+	}
+	if _, isNil := value.(NilNode); isNil {
+		c.errorf(tokenOf(value), "cannot use nil as %s, which can't be nil (declare it as %s? to allow nil)", to, to)
+		return
+	}
+	c.errorf(tokenOf(value), "%s may be nil and cannot be used as %s (%s)", value, to, c.nilHint(value))
+}
+
+// refine updates what is known about a local variable after a value is
+// stored in it
+func (c *Checker) refine(v *checkVar, value Node) {
+	if v == nil || !v.Null.mayBeNil() {
+		return
+	}
+	c.nonNil = c.nonNil.with(nil)
+	if c.nullness(value).mayBeNil() {
+		delete(c.nonNil, v)
+	} else {
+		c.nonNil[v] = true
+	}
+}
+
+// nullableVar returns the local variable an expression names if it is a
+// pointer that may be nil
+func (c *Checker) nullableVar(node Node) *checkVar {
+	ident, ok := node.(IdentNode)
+	if !ok {
+		return nil
+	}
+	if v := c.lookup(ident.Value); v != nil && v.Null.mayBeNil() {
+		return v
+	}
+	return nil
+}
+
+// narrow returns the local variables a condition shows are not nil when it
+// is true and when it is false, like `p != nil`, `p` or `p == nil || q == nil`
+func (c *Checker) narrow(cond Node) (whenTrue, whenFalse []*checkVar) {
+	switch n := cond.(type) {
+	case IdentNode:
+		if v := c.nullableVar(n); v != nil {
+			return []*checkVar{v}, nil
+		}
+	case UnaryNode:
+		if n.Operator == "!" {
+			whenTrue, whenFalse = c.narrow(n.Operand)
+			return whenFalse, whenTrue
+		}
+	case BinaryNode:
+		switch n.OP {
+		case "!=", "==":
+			var v *checkVar
+			if _, isNil := n.Right.(NilNode); isNil {
+				v = c.nullableVar(n.Left)
+			} else if _, isNil := n.Left.(NilNode); isNil {
+				v = c.nullableVar(n.Right)
+			}
+			if v == nil {
+				return nil, nil
+			}
+			if n.OP == "!=" {
+				return []*checkVar{v}, nil
+			}
+			return nil, []*checkVar{v}
+		case "&&":
+			left, _ := c.narrow(n.Left)
+			right, _ := c.narrow(n.Right)
+			return append(left, right...), nil
+		case "||":
+			_, left := c.narrow(n.Left)
+			_, right := c.narrow(n.Right)
+			return nil, append(left, right...)
+		}
+	}
+	return nil, nil
+}
+
+// forget drops what is known about the variables assigned anywhere in the
+// nodes, which a loop does before it is checked as it may run again after
+// they were assigned
+func (c *Checker) forget(nodes ...Node) {
+	names := make(map[string]bool)
+	for _, node := range nodes {
+		assignedIn(node, names)
+	}
+	facts := make(nilFacts)
+	for v := range c.nonNil {
+		if !names[v.Name] {
+			facts[v] = true
+		}
+	}
+	c.nonNil = facts
+}
+
+// assignedIn adds the names of the variables that are assigned or have
+// their address taken in a node to names
+func assignedIn(node Node, names map[string]bool) {
+	switch n := node.(type) {
+	case BlockNode:
+		for _, stmt := range n.Nodes {
+			assignedIn(stmt, names)
+		}
+	case IfNode:
+		assignedIn(n.If, names)
+		assignedIn(n.Then, names)
+		assignedIn(n.Else, names)
+	case WhileNode:
+		assignedIn(n.If, names)
+		assignedIn(n.Body, names)
+	case ForNode:
+		assignedIn(n.Init, names)
+		assignedIn(n.Cond, names)
+		assignedIn(n.Step, names)
+		assignedIn(n.Body, names)
+	case ReturnNode:
+		assignedIn(n.Value, names)
+	case VariableDefnNode:
+		names[n.Name.Value] = true
+		assignedIn(n.Body, names)
+	case BinaryNode:
+		if ident, ok := n.Left.(IdentNode); ok {
+			switch n.OP {
+			case "=", "+=", "-=", "*=", "/=":
+				names[ident.Value] = true
+			}
+		}
+		assignedIn(n.Left, names)
+		assignedIn(n.Right, names)
+	case UnaryNode:
+		if ident, ok := n.Operand.(IdentNode); ok && n.Operator == "&" {
+			names[ident.Value] = true
+		}
+		assignedIn(n.Operand, names)
+	case FunctionCallNode:
+		for _, arg := range n.Args {
+			assignedIn(arg, names)
+		}
+	case CastNode:
+		assignedIn(n.Source, names)
+	}
+}
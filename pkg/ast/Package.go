@@ -22,6 +22,11 @@ type Package struct {
 	Nodes           []Node
 	Program         *Program
 	DependencyPaths []string
+
+	// Includes maps each dependency path to the include statement it came from
+	Includes map[string]lexer.Token
+	// used is the set of dependency paths HasAccessToPackage has resolved to
+	used map[string]bool
 }
 
 // NewPackage returns a pointer to a new package
@@ -33,6 +38,8 @@ func NewPackage(name string, prog *Program) *Package {
 	p.Nodes = make([]Node, 0)
 	p.Files = make(map[string]*lexer.Sourcefile)
 	p.DependencyPaths = make([]string, 0)
+	p.Includes = make(map[string]lexer.Token)
+	p.used = make(map[string]bool)
 	return p
 }
 
@@ -44,7 +51,8 @@ func (p *Package) MergeWith(other *Package) {
 	}
 }
 
-// HasAccessToPackage -
+// HasAccessToPackage returns if the package includes a package with some name.
+// Every dependency found this way is remembered as used
 func (p *Package) HasAccessToPackage(name string) bool {
 
 	// return true
@@ -56,6 +64,7 @@ func (p *Package) HasAccessToPackage(name string) bool {
 	for path, pkg := range p.Program.Packages {
 		for _, dpath := range p.DependencyPaths {
 			if ReduceToDir(path) == ReduceToDir(dpath) && pkg.Name == name {
+				p.used[dpath] = true
 				return true
 			}
 
@@ -66,3 +75,15 @@ func (p *Package) HasAccessToPackage(name string) bool {
 
 	// for _,
 }
+
+// UnusedDependencies returns the dependency paths that have never been
+// resolved to by HasAccessToPackage, in the order they were included
+func (p *Package) UnusedDependencies() []string {
+	unused := make([]string, 0)
+	for _, dpath := range p.DependencyPaths {
+		if !p.used[dpath] {
+			unused = append(unused, dpath)
+		}
+	}
+	return unused
+}
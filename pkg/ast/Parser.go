@@ -2,6 +2,7 @@ package ast
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/geode-lang/geode/pkg/info"
 
@@ -28,6 +29,10 @@ type Parser struct {
 	isFork             bool
 	forkParent         *Parser
 	ID                 int
+
+	// docs maps the position of the first token of a declaration
+	// to the comment written on the lines directly above it
+	docs map[int]string
 }
 
 // NewQuickParser is used to lex and build a parser from tokens quickly
@@ -63,6 +68,7 @@ func (p *Parser) Fork() *Parser {
 	n.token = p.token
 	n.tokens = p.tokens
 	n.token = p.token
+	n.docs = p.docs
 	return n
 }
 
@@ -90,6 +96,8 @@ var parserOpPrec = map[string]int{
 	"/=": 0,
 	"||": 1,
 	"&&": 1,
+	// `value or fallback` is a word, so it is only an operator after a value
+	"or": 1,
 	"^":  1,
 	"==": 2,
 	"!=": 2,
@@ -117,12 +125,51 @@ func Parse(tokens []lexer.Token) []Node {
 			p.tokens = append(p.tokens, t)
 		}
 	}
+	p.docs = docComments(tokens)
 
 	p.move(0)
 	p.parse()
 	return p.topLevelNodes
 }
 
+// docComments finds the runs of comments that sit on their own lines
+// directly above a token, and maps that token's position to their text
+func docComments(tokens []lexer.Token) map[int]string {
+	docs := make(map[int]string)
+	lines := make([]string, 0)
+	lastLine, prevLine := -1, -1
+
+	for _, t := range tokens {
+		if t.Type == lexer.TokComment {
+			// Comments after code on the same line and comments
+			// separated by a blank line don't belong to the run
+			if t.Line == prevLine || t.Line != lastLine+1 {
+				lines = lines[:0]
+			}
+			if t.Line != prevLine {
+				text := strings.TrimPrefix(t.Value, "#")
+				text = strings.TrimPrefix(text, " ")
+				lines = append(lines, strings.TrimRight(text, " \t\r"))
+				lastLine = t.Line
+			}
+			prevLine = t.Line
+			continue
+		}
+
+		if len(lines) > 0 && lastLine == t.Line-1 {
+			docs[t.Pos] = strings.Join(lines, "\n")
+		}
+		lines = lines[:0]
+		prevLine = t.Line
+	}
+	return docs
+}
+
+// docComment returns the documentation comment written above a token
+func (p *Parser) docComment(tok lexer.Token) string {
+	return p.docs[tok.Pos]
+}
+
 // Context returns the context of a parser
 func (p *Parser) Context() *ParseContext {
 	// If the parser doesn't have a context, make a new one
@@ -221,6 +268,15 @@ func (p *Parser) parseTopLevelStmt() Node {
 	case lexer.TokType:
 		node := p.parseGlobalVariableDecl()
 		return node
+	case lexer.TokIdent:
+		// test and bench are only keywords at the top level, so they can still be used as names
+		if (p.token.Value == "test" || p.token.Value == "bench") && p.Peek(1).Is(lexer.TokString) {
+			return p.parseTestBlock()
+		}
+		// export is only a keyword before a function or a class
+		if p.token.Value == "export" && p.Peek(1).Is(lexer.TokFuncDefn, lexer.TokClassDefn) {
+			return p.parseExport()
+		}
 	}
 	p.token.SyntaxError()
 	p.Errorf("Invalid syntax in root\n")
@@ -13,6 +13,7 @@ import (
 
 	"github.com/geode-lang/geode/pkg/arg"
 	"github.com/geode-lang/geode/pkg/lexer"
+	"github.com/geode-lang/geode/pkg/opt"
 	"github.com/geode-lang/geode/pkg/util"
 	"github.com/geode-lang/geode/pkg/util/log"
 	"github.com/llir/llvm/ir"
@@ -32,13 +33,23 @@ type Program struct {
 	Package         *Package // the currently active package
 	CLinkages       []string
 	Entry           string
-	TargetTripple   string
+	TestDir         string // test blocks are only kept in the packages of this directory
+	Target          Target
 	TypePrecidences map[types.Type]int
 	Functions       map[string]*FunctionNode
 	Classes         map[string]*ClassNode
 	Initializations []*GlobalVariableDeclNode
 	StringDefs      map[string]*ir.Global
 	TypeInfoDefs    map[string]*TypeInfoDeclaration
+	ClassDefaults   map[string]*ir.Global      // the constant defaults of classes, by class name
+	Inferred        map[lexer.Token]types.Type // the types the checker inferred for := declarations
+
+	generated     map[string]bool // files the compiler wrote itself, like the test main
+	coverCounters []coverCounter
+	coverInitFunc *ir.Func
+	traceInitFunc *ir.Func
+	traceCalls    map[*ir.InstCall]string // where the calls in the program were made, for stack traces
+	exports       []export                // the functions a library exports, in the order they are declared in its header
 }
 
 // NewProgram creates a program and returns a pointer to it
@@ -52,6 +63,7 @@ func NewProgram() *Program {
 	p.Initializations = make([]*GlobalVariableDeclNode, 0)
 	p.StringDefs = make(map[string]*ir.Global, 0)
 	p.TypeInfoDefs = make(map[string]*TypeInfoDeclaration, 0)
+	p.ClassDefaults = make(map[string]*ir.Global)
 
 	p.TypePrecidences = make(map[types.Type]int)
 	p.TypePrecidences[types.I1] = 1
@@ -195,7 +207,7 @@ func (p *Program) ParseText(code string, path string) {
 	newPkg := NewPackage(name, p)
 	newPkg.Program = p
 	newPkg.Files[path] = src
-	newPkg.Nodes = nodes
+	newPkg.Nodes = p.dropTests(nodes, filepath.Dir(path))
 
 	_, found := p.Packages[path]
 	if !found {
@@ -208,8 +220,19 @@ func (p *Program) ParseText(code string, path string) {
 		for _, depPath := range dep.Paths {
 			if dep.CLinkage {
 				p.CLinkages = append(p.CLinkages, ResolveDepPath(base, depPath))
+			} else if dep.CHeader {
+				dpath, err := p.BindC(base, depPath)
+				if err != nil {
+					dep.SyntaxError()
+					log.Fatal("%s\n", err)
+				}
+				newPkg.DependencyPaths = append(newPkg.DependencyPaths, dpath)
+				newPkg.Includes[dpath] = dep.Token
+				p.ParsePath(dpath)
 			} else {
-				newPkg.DependencyPaths = append(newPkg.DependencyPaths, ReduceToDir(ResolveDepPath(base, depPath)))
+				dpath := ReduceToDir(ResolveDepPath(base, depPath))
+				newPkg.DependencyPaths = append(newPkg.DependencyPaths, dpath)
+				newPkg.Includes[dpath] = dep.Token
 				p.ParseDep(base, depPath)
 			}
 		}
@@ -540,6 +563,43 @@ func (p *Program) NewRuntimeFunctionCall(name string, args ...value.Value) (*ir.
 	return p.Compiler.CurrentBlock().NewCall(fn, args...), nil
 }
 
+// isRuntimeFunction returns if a compiled function is a variant of the runtime function with some name
+func (p *Program) isRuntimeFunction(name string, fn *ir.Func) bool {
+	node, exists := p.Functions[name]
+	if !exists || node.Package == nil || node.Package.Name != "runtime" {
+		return false
+	}
+	for _, variant := range node.Variants {
+		if variant == fn {
+			return true
+		}
+	}
+	return false
+}
+
+// Check runs semantic analysis over every function in the program.
+// This should be called after the program is congealed
+func (p *Program) Check() Diagnostics {
+	var diagnostics Diagnostics
+	log.Timed("Semantic analysis", func() {
+		c := NewChecker(p)
+		diagnostics = c.Check()
+		p.Inferred = c.Inferred
+	})
+	if *arg.WarningsAsErrors {
+		diagnostics.PromoteWarnings()
+	}
+	return diagnostics
+}
+
+// Optimize runs the optimization pipeline over the compiled module.
+// This should be called after all the functions are compiled
+func (p *Program) Optimize() {
+	log.Timed("Optimization", func() {
+		opt.NewPipeline("main").Run(p.Compiler.Module)
+	})
+}
+
 // Emit will emit the package as IR to a file then build it into an object file for further usage.
 // This function returns the path to the object file
 func (p *Program) Emit(buildDir string) string {
@@ -570,8 +630,11 @@ func (p *Program) String() string {
 	ir := &bytes.Buffer{}
 	// We need to build up the IR that will be emitted
 	// so we can track this information later on.
-	fmt.Fprintf(ir, "target datalayout = %q\n", "e-m:o-i64:64-f80:128-n8:16:32:64-S128")
-	fmt.Fprintf(ir, "target triple = %q\n", p.TargetTripple)
+	// Without a datalayout, llvm uses the target's default one
+	if p.Target.DataLayout != "" {
+		fmt.Fprintf(ir, "target datalayout = %q\n", p.Target.DataLayout)
+	}
+	fmt.Fprintf(ir, "target triple = %q\n", p.Target.Triple)
 
 	// Append the module information
 	fmt.Fprintf(ir, "\n%s", p.Compiler.Module.String())
@@ -0,0 +1,176 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/geode-lang/geode/pkg/gtypes"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// noreturnFunctions are the runtime functions that never return, so a call
+// to one can stand in for the value `or` falls back to
+var noreturnFunctions = map[string]bool{
+	"panic":  true,
+	"exit":   true,
+	"fatalf": true,
+}
+
+// resultType returns the type of a `T!`, which holds either a T or a
+// pointer to the runtime's Error class
+func (p *Program) resultType(elem types.Type) (*gtypes.ResultType, error) {
+	found := p.Scope.FindType("Error")
+	if found == nil {
+		return nil, fmt.Errorf("result types need the runtime's Error class, which isn't loaded")
+	}
+	return gtypes.NewResult(elem, types.NewPointer(found.Type)), nil
+}
+
+// resultField loads a field of a result. Results go through memory, as
+// llir's insertvalue and extractvalue don't know of geode types
+func resultField(prog *Program, res value.Value, index int) value.Value {
+	typ := res.Type().(*gtypes.ResultType)
+	tmp := createBlockAlloca(prog.Compiler.CurrentFunc(), typ, "")
+	blk := prog.Compiler.CurrentBlock()
+	blk.NewStore(res, tmp)
+	field := gep(tmp, constant.NewInt(types.I32, 0), constant.NewInt(types.I32, int64(index)))
+	blk.Insts = append(blk.Insts, field)
+	return blk.NewLoad(typ.Fields[index], field)
+}
+
+// wrapResult makes a result out of a value, which is its error if it is an
+// Error* and its value otherwise. A nil value makes a result with neither,
+// which is how a void result succeeds
+func wrapResult(prog *Program, in value.Value, res *gtypes.ResultType) (value.Value, error) {
+	if in == nil {
+		return constant.NewZeroInitializer(res), nil
+	}
+	index := 0
+	if types.Equal(in.Type(), res.ErrorType()) {
+		index = res.ErrorIndex()
+	} else if res.IsVoid() {
+		return nil, fmt.Errorf("a void result can only be made from an error, not %s", in.Type())
+	} else {
+		val, err := createTypeCast(prog, in, res.ElemType)
+		if err != nil {
+			return nil, err
+		}
+		in = val
+	}
+
+	tmp := createBlockAlloca(prog.Compiler.CurrentFunc(), res, "")
+	blk := prog.Compiler.CurrentBlock()
+	blk.NewStore(constant.NewZeroInitializer(res), tmp)
+	field := gep(tmp, constant.NewInt(types.I32, 0), constant.NewInt(types.I32, int64(index)))
+	blk.Insts = append(blk.Insts, field)
+	blk.NewStore(in, field)
+	return blk.NewLoad(res, tmp), nil
+}
+
+// failed returns the error of a result and a condition that is true when
+// the result holds one
+func failed(prog *Program, res value.Value) (value.Value, value.Value) {
+	typ := res.Type().(*gtypes.ResultType)
+	errVal := resultField(prog, res, typ.ErrorIndex())
+	isErr := prog.Compiler.CurrentBlock().NewICmp(enum.IPredNE, errVal, constant.NewNull(typ.ErrorType().(*types.PointerType)))
+	return errVal, isErr
+}
+
+// genTry generates `try expr`, which returns the error of a result from the
+// function it is in and otherwise is the value of the result
+func (n UnaryNode) genTry(prog *Program, res value.Value) (value.Value, error) {
+	typ, ok := res.Type().(*gtypes.ResultType)
+	if !ok {
+		return nil, fmt.Errorf("try needs a result, not %s", res.Type())
+	}
+	fnRes, ok := prog.Compiler.CurrentFunc().Sig.RetType.(*gtypes.ResultType)
+	if !ok {
+		return nil, fmt.Errorf("try can only be used in a function that returns a result")
+	}
+
+	errVal, isErr := failed(prog, res)
+	blk := prog.Compiler.CurrentBlock()
+	failBlk := blk.Parent.NewBlock(mangleName("try.fail"))
+	okBlk := blk.Parent.NewBlock(mangleName("try.ok"))
+	blk.NewCondBr(isErr, failBlk, okBlk)
+
+	err := prog.Compiler.genInBlock(failBlk, func() error {
+		ret, err := wrapResult(prog, errVal, fnRes)
+		if err != nil {
+			return err
+		}
+		prog.Compiler.CurrentBlock().NewRet(ret)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prog.Compiler.PushBlock(okBlk)
+	if typ.IsVoid() {
+		return nil, nil
+	}
+	return resultField(prog, res, 0), nil
+}
+
+// orElse generates `expr or fallback`, which is the value of a result or,
+// if it holds an error, the fallback. The fallback is only run when the
+// result failed, so it can be a call to panic or, for a void result, any
+// statement that handles the error
+func (n BinaryNode) orElse(prog *Program) (value.Value, error) {
+	res, err := n.Left.Codegen(prog)
+	if err != nil {
+		return nil, err
+	}
+	typ, ok := res.Type().(*gtypes.ResultType)
+	if !ok {
+		return nil, fmt.Errorf("the left side of or must be a result, not %s", res.Type())
+	}
+
+	var val value.Value
+	if !typ.IsVoid() {
+		val = resultField(prog, res, 0)
+	}
+	_, isErr := failed(prog, res)
+	entry := prog.Compiler.CurrentBlock()
+	fallbackBlk := entry.Parent.NewBlock(mangleName("or.fallback"))
+	endBlk := entry.Parent.NewBlock(mangleName("or.end"))
+	entry.NewCondBr(isErr, fallbackBlk, endBlk)
+
+	var fallback value.Value
+	var fallbackEnd *ir.Block
+	err = prog.Compiler.genInBlock(fallbackBlk, func() error {
+		r, err := n.Right.Codegen(prog)
+		if err != nil {
+			return err
+		}
+		blk := prog.Compiler.CurrentBlock()
+		switch {
+		case typ.IsVoid():
+		case r == nil || types.Equal(r.Type(), types.Void):
+			// A call that never returns, like panic
+			blk.NewUnreachable()
+			return nil
+		default:
+			fallback, err = createTypeCast(prog, r, typ.ElemType)
+			if err != nil {
+				return err
+			}
+		}
+		fallbackEnd = prog.Compiler.CurrentBlock()
+		fallbackEnd.NewBr(endBlk)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prog.Compiler.PushBlock(endBlk)
+	if fallback == nil {
+		return val, nil
+	}
+	return endBlk.NewPhi(ir.NewIncoming(val, entry), ir.NewIncoming(fallback, fallbackEnd)), nil
+}
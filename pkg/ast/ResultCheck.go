@@ -0,0 +1,120 @@
+package ast
+
+import (
+	"github.com/geode-lang/geode/pkg/gtypes"
+	"github.com/llir/llvm/ir/types"
+)
+
+// isVoidResult returns if a type is a `void!`, which has no value to return
+func isVoidResult(t types.Type) bool {
+	res, ok := t.(*gtypes.ResultType)
+	return ok && res.IsVoid()
+}
+
+// resultOf follows pointers from a type down to the result they point at
+func (c *Checker) resultOf(t types.Type) (*gtypes.ResultType, bool) {
+	for types.IsPointer(t) {
+		t = t.(*types.PointerType).ElemType
+	}
+	res, ok := t.(*gtypes.ResultType)
+	return res, ok
+}
+
+// returnResult checks a return from a function that returns a result,
+// which can return its value, an error, or another result of the same type
+func (c *Checker) returnResult(n ReturnNode, res *gtypes.ResultType) {
+	name := c.fn.Name.Value
+	if n.Value == nil {
+		if !res.IsVoid() {
+			c.errorf(n.Token, "function %s must return a value of type %s or an error", name, c.typeName(res.ElemType))
+		}
+		return
+	}
+
+	given := c.expr(n.Value)
+	if given == nil || types.Equal(given, res) || types.Equal(given, res.ErrorType()) {
+		return
+	}
+	if res.IsVoid() {
+		c.errorf(n.Token, "function %s returns %s so it can only return an error, not a value of type %s", name, c.typeName(res), c.typeName(given))
+		return
+	}
+	if !types.Equal(given, res.ElemType) && !(types.IsInt(given) && types.IsInt(res.ElemType)) && !(isNilType(given) && types.IsPointer(res.ElemType)) {
+		c.errorf(n.Token, "incorrect return value for function %s. expected: %s or an error, given: %s", name, c.typeName(res.ElemType), c.typeName(given))
+		return
+	}
+	elem := c.fn.ReturnType
+	elem.Modifiers = elem.Modifiers[:len(elem.Modifiers)-1]
+	c.storable(n.Value, elem.nullness(), elem.String())
+}
+
+// ignored reports a call whose result is thrown away, as nothing would
+// notice the error in it
+func (c *Checker) ignored(node Node, t types.Type) {
+	if _, isCall := node.(FunctionCallNode); !isCall || !gtypes.IsResult(t) {
+		return
+	}
+	c.errorf(tokenOf(node), "the error returned by %s is ignored (pass it on with try, or handle it with or)", node)
+}
+
+// try checks `try expr`, which is the value of a result after returning
+// its error from the function it is in
+func (c *Checker) try(n UnaryNode, t types.Type) types.Type {
+	res, ok := t.(*gtypes.ResultType)
+	if !ok {
+		c.errorf(n.Token, "try needs a result to pass on the error of, not a value of type %s", c.typeName(t))
+		return nil
+	}
+	if !gtypes.IsResult(c.retType) {
+		c.errorf(n.Token, "try can only be used in a function that returns a result, but %s returns %s", c.fn.Name, c.typeName(c.retType))
+	}
+	return res.ElemType
+}
+
+// orElse checks `expr or fallback`. The fallback is used in place of the
+// value of a result that failed, so it has to be one unless it never
+// returns, like a call to panic. A void result has no value to replace, so
+// its fallback can be anything that handles the error
+func (c *Checker) orElse(n BinaryNode) types.Type {
+	lt := c.expr(n.Left)
+	rt := c.expr(n.Right)
+	if lt == nil {
+		return nil
+	}
+	res, ok := lt.(*gtypes.ResultType)
+	if !ok {
+		c.errorf(n.Token, "the left side of or must be a result, not a value of type %s", c.typeName(lt))
+		return nil
+	}
+	if res.IsVoid() {
+		// The fallback is a statement of its own
+		c.ignored(n.Right, rt)
+		return res.ElemType
+	}
+	if rt == nil {
+		return res.ElemType
+	}
+	if types.Equal(rt, types.Void) {
+		if !c.noreturn(n.Right) {
+			c.errorf(tokenOf(n.Right), "%s has no value to use in place of the %s of %s", n.Right, c.typeName(res.ElemType), n.Left)
+		}
+		return res.ElemType
+	}
+	c.assignable(rt, res.ElemType, n.Right)
+	return res.ElemType
+}
+
+// noreturn returns if an expression is a call to a runtime function that
+// never returns
+func (c *Checker) noreturn(node Node) bool {
+	call, ok := node.(FunctionCallNode)
+	if !ok {
+		return false
+	}
+	ident, ok := call.Name.(IdentNode)
+	if !ok || !noreturnFunctions[ident.Value] {
+		return false
+	}
+	fn, ok := c.Program.Functions[ident.Value]
+	return ok && fn.Package != nil && fn.Package.Name == "runtime"
+}
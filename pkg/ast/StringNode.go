@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/geode-lang/geode/pkg/arg"
-	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
@@ -26,21 +25,8 @@ var strIndex = 0
 // Codegen implements Node.Codegen for StringNode
 func (n StringNode) Codegen(prog *Program) (value.Value, error) {
 
-	var str *ir.Global
-
-	if found, exists := prog.StringDefs[n.Value]; exists {
-		str = found
-	} else {
-		name := fmt.Sprintf(".str.%X", strIndex)
-		strIndex++
-		str = prog.Compiler.Module.NewGlobalDef(name, newCharArray(n.Value))
-		str.Immutable = true
-		prog.StringDefs[n.Value] = str
-	}
-
 	var val value.Value
-	zero := constant.NewInt(types.I32, 0)
-	val = constant.NewGetElementPtr(str.ContentType, str, zero, zero)
+	val = prog.constString(n.Value)
 
 	if !*arg.DisableStringDataCopy {
 		length := constant.NewInt(types.I32, int64(len([]byte(n.Value))+1))
@@ -54,6 +40,21 @@ func (n StringNode) Codegen(prog *Program) (value.Value, error) {
 	return val, nil
 }
 
+// constString returns a pointer to the constant data of a string, which
+// is shared by every string literal with the same value
+func (p *Program) constString(s string) constant.Constant {
+	str, exists := p.StringDefs[s]
+	if !exists {
+		name := fmt.Sprintf(".str.%X", strIndex)
+		strIndex++
+		str = p.Compiler.Module.NewGlobalDef(name, newCharArray(s))
+		str.Immutable = true
+		p.StringDefs[s] = str
+	}
+	zero := constant.NewInt(types.I32, 0)
+	return constant.NewGetElementPtr(str.ContentType, str, zero, zero)
+}
+
 // GenAccess implements Accessable.GenAccess
 func (n StringNode) GenAccess(prog *Program) (value.Value, error) {
 	return n.Codegen(prog)
@@ -38,6 +38,10 @@ func (n SubscriptNode) GenElementPtr(prog *Program) (*ir.InstGetElementPtr, erro
 		return nil, err
 	}
 
+	if err := prog.nilCheck(src, n.Source, n.Token); err != nil {
+		return nil, err
+	}
+
 	if gtypes.IsSlice(src.Type()) {
 		fmt.Println(src.Type())
 		zero := constant.NewInt(types.I64, 0)
@@ -0,0 +1,103 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/gtypes"
+	"github.com/geode-lang/geode/pkg/util"
+)
+
+// Target is a machine a program is compiled for
+type Target struct {
+	Triple     string
+	DataLayout string // how llvm lays out the target's types in memory
+}
+
+// targetLayout is the datalayout of the targets with an architecture
+// running an operating system. An empty os matches any of them
+type targetLayout struct {
+	arch   string
+	os     string
+	layout string
+}
+
+// targetLayouts are the datalayouts geode knows, which match what clang
+// uses for the same triples
+var targetLayouts = []targetLayout{
+	{"x86_64", "linux", "e-m:e-p270:32:32-p271:32:32-p272:64:64-i64:64-f80:128-n8:16:32:64-S128"},
+	{"aarch64", "linux", "e-m:e-i8:8:32-i16:16:32-i64:64-i128:128-n32:64-S128"},
+	{"riscv64", "linux", "e-m:e-p:64:64-i64:64-i128:128-n64-S128"},
+	{"wasm32", "", "e-m:e-p:32:32-i64:64-n32:64-S128"},
+	{"x86_64", "darwin", "e-m:o-i64:64-f80:128-n8:16:32:64-S128"},
+	{"aarch64", "darwin", "e-m:o-i64:64-i128:128-n32:64-S128"},
+}
+
+// ParseTarget returns the target a triple names, with its datalayout
+func ParseTarget(triple string) (Target, error) {
+	arch, os := targetArch(triple), targetOS(triple)
+	if arch == "arm64" {
+		arch = "aarch64"
+	}
+	for _, t := range targetLayouts {
+		if t.arch == arch && (t.os == "" || t.os == os) {
+			return Target{Triple: triple, DataLayout: t.layout}, nil
+		}
+	}
+
+	known := make([]string, 0, len(targetLayouts))
+	for _, t := range targetLayouts {
+		if t.os == "" {
+			known = append(known, t.arch)
+		} else {
+			known = append(known, t.arch+"-"+t.os)
+		}
+	}
+	return Target{}, fmt.Errorf("unsupported target %q, geode can build for %s", triple, strings.Join(known, ", "))
+}
+
+// targetOS returns the family of operating systems a triple is for
+func targetOS(triple string) string {
+	for _, part := range strings.Split(triple, "-")[1:] {
+		switch {
+		case part == "linux":
+			return "linux"
+		case part == "darwin" || strings.HasPrefix(part, "macos"):
+			return "darwin"
+		case strings.HasPrefix(part, "wasi"):
+			return "wasi"
+		}
+	}
+	return ""
+}
+
+// IsWASI returns if a triple is for webassembly modules that run on a
+// WASI runtime, like wasm32-wasi
+func IsWASI(triple string) bool {
+	return targetArch(triple) == "wasm32" && targetOS(triple) == "wasi"
+}
+
+// targetFile returns the file a package has in place of a C file it links
+// for the operating system of a target, which is named after it like
+// xmalloc_wasi.c, or the file itself if there isn't one
+func targetFile(file string, triple string) string {
+	os := targetOS(triple)
+	if os == "" {
+		return file
+	}
+	replacement := strings.TrimSuffix(file, ".c") + "_" + os + ".c"
+	if util.FileExists(replacement) {
+		return replacement
+	}
+	return file
+}
+
+// SetTarget sets the target the program is compiled for, which lays out
+// its types in memory
+func (p *Program) SetTarget(t Target) error {
+	if err := gtypes.SetDataLayout(t.DataLayout); err != nil {
+		return fmt.Errorf("target %s: %s", t.Triple, err)
+	}
+	p.Target = t
+	return nil
+}
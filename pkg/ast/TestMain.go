@@ -0,0 +1,175 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/geode-lang/geode/pkg/lexer"
+)
+
+// TestCase is a single test or bench block that the generated main can run
+type TestCase struct {
+	Name   string
+	Source string      // the name the block is given in the source
+	Token  lexer.Token // the `test` or `bench` keyword the block starts with
+}
+
+// Location returns the file and line the test block is written at
+func (t TestCase) Location() string {
+	return t.Token.FileInfo()
+}
+
+// dropTests removes the test and bench blocks from the nodes of a file
+// unless the file is in the directory that is being tested
+func (p *Program) dropTests(nodes []Node, dir string) []Node {
+	if p.TestDir != "" && dir == p.TestDir {
+		return nodes
+	}
+	kept := make([]Node, 0, len(nodes))
+	for _, node := range nodes {
+		if fn, is := node.(FunctionNode); is && (fn.Test != "" || fn.Bench != "") {
+			continue
+		}
+		kept = append(kept, node)
+	}
+	return kept
+}
+
+// testFunctions returns the name of the package in the test directory and
+// the functions in it, in a stable order. The package's own main function
+// is kept under another name so it is still checked, which leaves room for
+// a generated one
+func (p *Program) testFunctions() (string, []FunctionNode) {
+	paths := make([]string, 0)
+	for path := range p.Packages {
+		if filepath.Dir(path) == p.TestDir {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	name := ""
+	fns := make([]FunctionNode, 0)
+	for _, path := range paths {
+		pkg := p.Packages[path]
+		name = pkg.Name
+		for i, node := range pkg.Nodes {
+			fn, is := node.(FunctionNode)
+			if !is {
+				continue
+			}
+			if fn.Name.Value == "main" {
+				fn.Name = NewIdentNode("__main")
+				fn.Nomangle = false
+				pkg.Nodes[i] = fn
+			}
+			fns = append(fns, fn)
+		}
+	}
+	return name, fns
+}
+
+// AddTestMain replaces the main function of the packages in the test
+// directory with a generated one that runs their test blocks. The test
+// to run is picked by the index passed as the program's first argument,
+// and every test is run if there is none. The test cases are returned in
+// the order of their indexes. No main is generated if there are no tests
+func (p *Program) AddTestMain() []TestCase {
+	name, fns := p.testFunctions()
+	tests := make([]FunctionNode, 0)
+	for _, fn := range fns {
+		if fn.Test != "" {
+			tests = append(tests, fn)
+		}
+	}
+
+	if len(tests) == 0 {
+		return nil
+	}
+
+	cases := make([]TestCase, 0, len(tests))
+	src := &bytes.Buffer{}
+	fmt.Fprintf(src, "is %s\n\n", name)
+	fmt.Fprintf(src, "func main(int argc, byte** argv) int {\n")
+	fmt.Fprintf(src, "\tint which = __test_selected(argc, argv);\n")
+	for i, fn := range tests {
+		fmt.Fprintf(src, "\tif which < 0 || which == %d {\n\t\t%s();\n\t}\n", i, fn.Name)
+		cases = append(cases, TestCase{fn.Test, fn.Test, fn.Token})
+	}
+	fmt.Fprintf(src, "\treturn 0;\n}\n")
+
+	p.parseGenerated(src.String(), "__test_main.g")
+	return cases
+}
+
+// parseGenerated parses source the compiler wrote itself into the package
+// in the test directory
+func (p *Program) parseGenerated(src string, name string) {
+	path := filepath.Join(p.TestDir, name)
+	if p.generated == nil {
+		p.generated = make(map[string]bool)
+	}
+	p.generated[path] = true
+	p.ParseText(src, path)
+}
+
+// benchName turns the name of a bench block into one that benchstat can
+// read, which has to start with Benchmark and a letter that isn't lower
+// case, and can't contain spaces
+func benchName(name string) string {
+	if r, size := utf8.DecodeRuneInString(name); size > 0 {
+		name = string(unicode.ToUpper(r)) + name[size:]
+	}
+	return "Benchmark" + strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '/' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// AddBenchMain replaces the main function of the packages in the test
+// directory with a generated one that runs one of their bench blocks,
+// picked by the index passed as the program's first argument. The block
+// is run in a loop whose iteration count the runtime calibrates until the
+// loop takes as long as the benchmark time in the second argument. The
+// bench cases are returned in the order of their indexes, and their names
+// are the ones the runtime reports them under
+func (p *Program) AddBenchMain() []TestCase {
+	name, fns := p.testFunctions()
+	benches := make([]FunctionNode, 0)
+	for _, fn := range fns {
+		if fn.Bench != "" {
+			benches = append(benches, fn)
+		}
+	}
+
+	if len(benches) == 0 {
+		return nil
+	}
+
+	cases := make([]TestCase, 0, len(benches))
+	src := &bytes.Buffer{}
+	fmt.Fprintf(src, "is %s\n\n", name)
+	fmt.Fprintf(src, "func main(int argc, byte** argv) int {\n")
+	fmt.Fprintf(src, "\tint which = __test_selected(argc, argv);\n")
+	for i, fn := range benches {
+		bench := benchName(fn.Bench)
+		fmt.Fprintf(src, "\tif which == %d {\n", i)
+		fmt.Fprintf(src, "\t\tlong n = __bench_start(argc, argv);\n")
+		fmt.Fprintf(src, "\t\twhile n > 0 {\n")
+		fmt.Fprintf(src, "\t\t\tfor long i = 0; i < n; i += 1 {\n\t\t\t\t%s();\n\t\t\t}\n", fn.Name)
+		fmt.Fprintf(src, "\t\t\tn = __bench_stop(\"%s\", n);\n", bench)
+		fmt.Fprintf(src, "\t\t}\n\t}\n")
+		cases = append(cases, TestCase{bench, fn.Bench, fn.Token})
+	}
+	fmt.Fprintf(src, "\treturn 0;\n}\n")
+
+	p.parseGenerated(src.String(), "__bench_main.g")
+	return cases
+}
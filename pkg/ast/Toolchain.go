@@ -0,0 +1,609 @@
+package ast
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/geode-lang/geode/pkg/util"
+	"github.com/geode-lang/geode/pkg/util/log"
+)
+
+// OutputKind is what a toolchain compiles llvm ir into
+type OutputKind int
+
+// The kinds of output the linker can ask a toolchain for
+const (
+	ObjectOutput OutputKind = iota
+	AssemblyOutput
+	IROutput
+)
+
+// BuildOptions are how the files of a program are compiled and linked.
+// Each toolchain turns them into the arguments of its own tools
+type BuildOptions struct {
+	Optimize   int
+	Sanitizers []string
+	Debug      bool
+	Flags      []string // extra arguments for the tool that links the program
+	Library    string   // static or shared when building a library instead of a program
+}
+
+// Toolchain is a set of external tools that can build the llvm ir the
+// compiler emits and the C files that packages link into a program
+type Toolchain interface {
+	// Name is the kind of the toolchain, as written in a toolchain file
+	Name() string
+	// Triple is the target the toolchain builds for
+	Triple() string
+	CompileIR(in, out string, kind OutputKind, opts BuildOptions) error
+	CompileC(in, out string, opts BuildOptions) error
+	Link(objects []string, out string, opts BuildOptions) error
+	// Archive bundles objects into a static library
+	Archive(objects []string, out string) error
+}
+
+// ToolchainKinds are the toolchains geode can build with, in the order
+// they are tried when none is picked
+var ToolchainKinds = []string{"clang", "cc", "llc"}
+
+// minLLVMVersion is the oldest llvm that can read the ir geode emits
+const minLLVMVersion = 7
+
+// ToolchainConfig picks a toolchain and the tools in it. It is read from a
+// toolchain file, and anything it leaves out is detected from the PATH
+type ToolchainConfig struct {
+	Kind          string   `toml:"kind"`           // clang, cc or llc
+	Clang         string   `toml:"clang"`          // the clang driver
+	LLC           string   `toml:"llc"`            // compiles llvm ir for the cc and llc toolchains
+	Opt           string   `toml:"opt"`            // optimizes the ir written by --llvm, if there is one
+	CC            string   `toml:"cc"`             // compiles C files for the cc and llc toolchains, and links for cc
+	LD            string   `toml:"ld"`             // links for the llc toolchain, like ld.lld or ld
+	Triple        string   `toml:"triple"`         // the target to build for, instead of the tools' default
+	DynamicLinker string   `toml:"dynamic_linker"` // the program interpreter ld links programs with
+	LibDirs       []string `toml:"lib_dirs"`       // where ld looks for the C runtime and libraries
+	Sysroot       string   `toml:"sysroot"`        // the wasi sysroot with the libc of wasm32-wasi
+	AR            string   `toml:"ar"`             // builds static libraries, like ar or llvm-ar
+}
+
+// LoadToolchainConfig reads a toolchain file
+func LoadToolchainConfig(path string) (ToolchainConfig, error) {
+	var cfg ToolchainConfig
+	md, err := toml.DecodeFile(path, &cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %s", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return cfg, fmt.Errorf("%s: unknown key %q", path, undecoded[0].String())
+	}
+	return cfg, nil
+}
+
+// DetectToolchain finds the tools of the toolchain a config picks, or the
+// first toolchain whose tools are all installed if it doesn't pick one.
+// The versions of the llvm tools are checked to be new enough
+func DetectToolchain(cfg ToolchainConfig) (Toolchain, error) {
+	kinds := ToolchainKinds
+	if cfg.Kind != "" {
+		kinds = []string{cfg.Kind}
+	}
+
+	var errs []string
+	for _, kind := range kinds {
+		var tc Toolchain
+		var err error
+		switch kind {
+		case "clang":
+			tc, err = detectClang(cfg)
+		case "cc", "llc":
+			tc, err = detectLLC(kind, cfg)
+		default:
+			return nil, fmt.Errorf("unknown toolchain %q, expected one of %s", kind, strings.Join(ToolchainKinds, ", "))
+		}
+		if err == nil {
+			return tc, nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %s", kind, err))
+	}
+	return nil, fmt.Errorf("unable to find a toolchain to build with. Install clang, or llc with cc or ld, or describe one in a toolchain file\n%s", strings.Join(errs, "\n"))
+}
+
+// findTool returns the path of the first tool with one of some names. Tools
+// that are installed with the llvm version after their name are found too
+func findTool(configured string, names ...string) (string, error) {
+	if configured != "" {
+		return exec.LookPath(configured)
+	}
+	for _, name := range names {
+		if p, err := exec.LookPath(name); err == nil {
+			return p, nil
+		}
+	}
+	for version := 20; version >= minLLVMVersion; version-- {
+		for _, name := range names {
+			if p, err := exec.LookPath(fmt.Sprintf("%s-%d", name, version)); err == nil {
+				return p, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("%s not found", names[0])
+}
+
+var llvmVersionPattern = regexp.MustCompile(`(?:clang|LLVM) version (\d+)\.(\d+)`)
+
+// checkLLVMVersion makes sure an llvm tool is new enough to read the ir geode emits
+func checkLLVMVersion(tool string, versionOutput string) error {
+	m := llvmVersionPattern.FindStringSubmatch(versionOutput)
+	if m == nil {
+		log.Verbose("Unable to tell the llvm version of %s\n", tool)
+		return nil
+	}
+	major, _ := strconv.Atoi(m[1])
+	if major < minLLVMVersion {
+		return fmt.Errorf("%s is llvm %s.%s, but geode needs llvm %d or newer", tool, m[1], m[2], minLLVMVersion)
+	}
+	return nil
+}
+
+// runTool runs one of the tools of a toolchain, turning a failure into an
+// error with the tool's output
+func runTool(tool string, args ...string) error {
+	out, err := util.RunCommand(tool, args...)
+	if err != nil {
+		return fmt.Errorf("failed to run command `%s %s`: `%s`\n\n%s", tool, strings.Join(args, " "), err, out)
+	}
+	return nil
+}
+
+// optimizeArgs returns the optimization flag that clang and llc both take
+func optimizeArgs(opts BuildOptions) []string {
+	if opts.Optimize > 0 && opts.Optimize <= 3 {
+		return []string{fmt.Sprintf("-O%d", opts.Optimize)}
+	}
+	return nil
+}
+
+// sanitizeArgs returns the arguments that clang and cc take to compile and
+// link with the sanitizers
+func sanitizeArgs(opts BuildOptions) []string {
+	if len(opts.Sanitizers) == 0 {
+		return nil
+	}
+	return []string{"-fsanitize=" + strings.Join(opts.Sanitizers, ","), "-fno-omit-frame-pointer"}
+}
+
+// picArgs returns the argument that makes clang and cc compile position
+// independent code, which libraries are built from so they can be loaded
+// anywhere or linked into position independent executables
+func picArgs(opts BuildOptions) []string {
+	if opts.Library == "" {
+		return nil
+	}
+	return []string{"-fPIC"}
+}
+
+// cArgs returns the arguments a C compiler builds the C files of a program
+// with. The sanitizers also switch the runtime from the garbage collector to
+// malloc, which they can track
+func cArgs(opts BuildOptions) []string {
+	args := append([]string{"-O3", "--std=c99"}, picArgs(opts)...)
+	if len(opts.Sanitizers) > 0 {
+		args = append(args, sanitizeArgs(opts)...)
+		args = append(args, "-DGEODE_NO_GC")
+	}
+	if opts.Debug {
+		args = append(args, "-g")
+	}
+	return args
+}
+
+// libraries returns the libraries every program for a target is linked
+// with. WASI programs don't have the garbage collector, their runtime
+// allocates with malloc instead
+func libraries(triple string, opts BuildOptions) []string {
+	libs := []string{"-lm", "-lpthread", "-lc"}
+	if len(opts.Sanitizers) == 0 && !IsWASI(triple) {
+		libs = append([]string{"-lgc"}, libs...)
+	}
+	return libs
+}
+
+// archive bundles objects into a static library with ar, or llvm-ar, which
+// also indexes the symbols of wasm objects
+func archive(ar string, triple string, objects []string, out string) error {
+	names := []string{"ar", "llvm-ar"}
+	if targetArch(triple) == "wasm32" {
+		names = []string{"llvm-ar"}
+	}
+	tool, err := findTool(ar, names...)
+	if err != nil {
+		return err
+	}
+	// ar adds to an archive that already exists
+	os.Remove(out)
+	return runTool(tool, append([]string{"rcs", out}, objects...)...)
+}
+
+// wasiSysroots are where the libc of wasm32-wasi is usually installed,
+// by wasi-sdk or a package of wasi-libc
+func wasiSysroots() []string {
+	dirs := []string{os.Getenv("WASI_SYSROOT")}
+	if sdk := os.Getenv("WASI_SDK_PATH"); sdk != "" {
+		dirs = append(dirs, filepath.Join(sdk, "share/wasi-sysroot"))
+	}
+	return append(dirs, "/opt/wasi-sdk/share/wasi-sysroot", "/usr/local/share/wasi-sysroot", "/usr/share/wasi-sysroot")
+}
+
+// clangToolchain builds everything with the clang driver
+type clangToolchain struct {
+	clang   string
+	ar      string
+	triple  string
+	sysroot string
+}
+
+func detectClang(cfg ToolchainConfig) (Toolchain, error) {
+	clang, err := findTool(cfg.Clang, "clang")
+	if err != nil {
+		return nil, err
+	}
+	out, err := util.RunCommand(clang, "-v")
+	if err != nil {
+		return nil, fmt.Errorf("%s -v failed: %s", clang, err)
+	}
+	if err := checkLLVMVersion(clang, string(out)); err != nil {
+		return nil, err
+	}
+
+	tc := &clangToolchain{clang: clang, ar: cfg.AR, triple: cfg.Triple}
+	if tc.triple == "" {
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.HasPrefix(line, "Target: ") {
+				tc.triple = strings.TrimPrefix(line, "Target: ")
+			}
+		}
+	}
+	// clang only knows where the wasi sysroot is if it was built with it
+	if IsWASI(tc.triple) {
+		tc.sysroot = cfg.Sysroot
+		for _, dir := range wasiSysroots() {
+			if tc.sysroot == "" && dir != "" && util.FileExists(dir) {
+				tc.sysroot = dir
+			}
+		}
+	}
+	log.Verbose("Clang Version: %s\n", out)
+	return tc, nil
+}
+
+func (t *clangToolchain) Name() string   { return "clang" }
+func (t *clangToolchain) Triple() string { return t.triple }
+
+// targetArgs returns the arguments that make clang build for the toolchain's target
+func (t *clangToolchain) targetArgs() []string {
+	args := []string{"--target=" + t.triple}
+	if t.sysroot != "" {
+		args = append(args, "--sysroot="+t.sysroot)
+	}
+	return args
+}
+
+func (t *clangToolchain) CompileIR(in, out string, kind OutputKind, opts BuildOptions) error {
+	args := append(t.targetArgs(), optimizeArgs(opts)...)
+	args = append(args, sanitizeArgs(opts)...)
+	args = append(args, picArgs(opts)...)
+	if opts.Debug {
+		args = append(args, "-g")
+	}
+	switch kind {
+	case AssemblyOutput:
+		// We want to only write intel syntax. AT&T Sucks
+		args = append(args, "-S", "-masm=intel", "-Wno-everything")
+	case IROutput:
+		args = append(args, "-S", "-emit-llvm")
+	default:
+		args = append(args, "-c")
+	}
+	return runTool(t.clang, append(args, "-o", out, in)...)
+}
+
+func (t *clangToolchain) CompileC(in, out string, opts BuildOptions) error {
+	args := append(t.targetArgs(), cArgs(opts)...)
+	return runTool(t.clang, append(args, "-c", "-o", out, in)...)
+}
+
+func (t *clangToolchain) Link(objects []string, out string, opts BuildOptions) error {
+	args := append(t.targetArgs(), optimizeArgs(opts)...)
+	args = append(args, sanitizeArgs(opts)...)
+	if opts.Library == "shared" {
+		args = append(args, "-shared")
+	}
+	args = append(args, objects...)
+	args = append(args, libraries(t.triple, opts)...)
+	args = append(args, "-o", out)
+	return runTool(t.clang, append(args, opts.Flags...)...)
+}
+
+func (t *clangToolchain) Archive(objects []string, out string) error {
+	return archive(t.ar, t.triple, objects, out)
+}
+
+// llcToolchain compiles llvm ir with llc and C files with cc. The cc
+// toolchain links with cc too, while the llc toolchain calls ld itself,
+// for machines that have an llvm install but not the clang driver
+type llcToolchain struct {
+	kind   string
+	llc    string
+	opt    string
+	cc     string
+	ld     string
+	ar     string
+	triple string
+
+	dynamicLinker string
+	libDirs       []string
+}
+
+func detectLLC(kind string, cfg ToolchainConfig) (Toolchain, error) {
+	llc, err := findTool(cfg.LLC, "llc")
+	if err != nil {
+		return nil, err
+	}
+	out, err := util.RunCommand(llc, "--version")
+	if err != nil {
+		return nil, fmt.Errorf("%s --version failed: %s", llc, err)
+	}
+	if err := checkLLVMVersion(llc, string(out)); err != nil {
+		return nil, err
+	}
+
+	host := ""
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Default target: ") {
+			host = strings.TrimPrefix(line, "Default target: ")
+		}
+	}
+	tc := &llcToolchain{kind: kind, llc: llc, ar: cfg.AR, triple: cfg.Triple}
+	if tc.triple == "" {
+		tc.triple = host
+	}
+	if tc.triple == "" {
+		return nil, fmt.Errorf("unable to tell what target %s builds for, set triple in the toolchain file", llc)
+	}
+
+	// opt is only used to optimize the ir --llvm writes, so it can be missing
+	tc.opt, _ = findTool(cfg.Opt, "opt")
+
+	// llc can write wasm objects for --obj, but only clang can build the
+	// C files and link them with the wasi libc
+	if targetArch(tc.triple) == "wasm32" {
+		return tc, nil
+	}
+
+	// The tools installed for the machine geode runs on can't build for
+	// another one, but the cross compilers debian style systems install can
+	cross := targetArch(tc.triple) != targetArch(host)
+	ccNames, ldNames := []string{"cc", "gcc"}, []string{"ld.lld", "ld"}
+	if cross {
+		multiarch := multiarchName(tc.triple)
+		ccNames = []string{multiarch + "-gcc", tc.triple + "-gcc"}
+		ldNames = []string{"ld.lld", multiarch + "-ld", tc.triple + "-ld"}
+	}
+
+	if tc.cc, err = findTool(cfg.CC, ccNames...); err != nil {
+		return nil, fmt.Errorf("%s, which is needed to build the C files packages link for %s", err, tc.triple)
+	}
+
+	if kind == "llc" {
+		if tc.ld, err = findTool(cfg.LD, ldNames...); err != nil {
+			return nil, err
+		}
+		tc.dynamicLinker = cfg.DynamicLinker
+		if tc.dynamicLinker == "" {
+			tc.dynamicLinker = dynamicLinkers[targetArch(tc.triple)]
+		}
+		if tc.dynamicLinker == "" {
+			return nil, fmt.Errorf("no known dynamic linker for %s, set dynamic_linker in the toolchain file", tc.triple)
+		}
+		tc.libDirs = append(cfg.LibDirs, systemLibDirs(tc.triple, cross)...)
+	}
+	return tc, nil
+}
+
+func (t *llcToolchain) Name() string   { return t.kind }
+func (t *llcToolchain) Triple() string { return t.triple }
+
+func (t *llcToolchain) CompileIR(in, out string, kind OutputKind, opts BuildOptions) error {
+	if kind == IROutput {
+		// llc can't write ir, but opt can optimize it like clang would
+		if t.opt != "" && opts.Optimize > 0 {
+			return runTool(t.opt, append(optimizeArgs(opts), "-S", "-o", out, in)...)
+		}
+		return copyFile(in, out)
+	}
+
+	relocation := "pic"
+	if targetArch(t.triple) == "wasm32" {
+		relocation = "static"
+	}
+	args := append(optimizeArgs(opts), "-relocation-model="+relocation, "-mtriple="+t.triple)
+	if kind == AssemblyOutput {
+		args = append(args, "-filetype=asm")
+		// We want to only write intel syntax. AT&T Sucks
+		if arch := targetArch(t.triple); arch == "x86_64" || arch == "i386" || arch == "i686" {
+			args = append(args, "-x86-asm-syntax=intel")
+		}
+	} else {
+		args = append(args, "-filetype=obj")
+	}
+	return runTool(t.llc, append(args, "-o", out, in)...)
+}
+
+// errNoWasm is why the cc and llc toolchains can't build a wasm program
+func (t *llcToolchain) errNoWasm() error {
+	return fmt.Errorf("the %s toolchain can't build programs for %s, use the clang toolchain with wasm-ld and a wasi sysroot", t.kind, t.triple)
+}
+
+func (t *llcToolchain) CompileC(in, out string, opts BuildOptions) error {
+	if t.cc == "" {
+		return t.errNoWasm()
+	}
+	return runTool(t.cc, append(cArgs(opts), "-c", "-o", out, in)...)
+}
+
+func (t *llcToolchain) Link(objects []string, out string, opts BuildOptions) error {
+	if t.cc == "" {
+		return t.errNoWasm()
+	}
+	if t.kind == "cc" {
+		args := sanitizeArgs(opts)
+		if opts.Library == "shared" {
+			args = append(args, "-shared")
+		}
+		args = append(args, objects...)
+		args = append(args, libraries(t.triple, opts)...)
+		args = append(args, "-o", out)
+		return runTool(t.cc, append(args, opts.Flags...)...)
+	}
+
+	// Only a compiler driver knows how to link the sanitizer runtimes
+	if len(opts.Sanitizers) > 0 {
+		return fmt.Errorf("the llc toolchain can't link the sanitizers, use the clang or cc toolchain")
+	}
+
+	crt := func(name string) string {
+		for _, dir := range t.libDirs {
+			if p := filepath.Join(dir, name); util.FileExists(p) {
+				return p
+			}
+		}
+		return ""
+	}
+	crt1, crti, crtn := crt("crt1.o"), crt("crti.o"), crt("crtn.o")
+	begin, end := crt("crtbegin.o"), crt("crtend.o")
+	args := []string{"-o", out, "--eh-frame-hdr"}
+	if opts.Library == "shared" {
+		// Shared libraries don't start the program, and their constructors
+		// are run by the one that loads them
+		crt1 = ""
+		begin, end = crt("crtbeginS.o"), crt("crtendS.o")
+		args = append(args, "-shared")
+	} else {
+		args = append(args, "-dynamic-linker", t.dynamicLinker)
+	}
+	if crti == "" || crtn == "" || crt1 == "" && opts.Library != "shared" {
+		return fmt.Errorf("unable to find the C runtime (crt1.o, crti.o and crtn.o) in %s, set lib_dirs in the toolchain file", strings.Join(t.libDirs, ", "))
+	}
+
+	for _, obj := range []string{crt1, crti, begin} {
+		if obj != "" {
+			args = append(args, obj)
+		}
+	}
+	for _, dir := range t.libDirs {
+		args = append(args, "-L"+dir)
+	}
+	args = append(args, objects...)
+	args = append(args, libraries(t.triple, opts)...)
+	if crt("libgcc.a") != "" {
+		args = append(args, "-lgcc")
+	}
+	for _, obj := range []string{end, crtn} {
+		if obj != "" {
+			args = append(args, obj)
+		}
+	}
+	return runTool(t.ld, append(args, opts.Flags...)...)
+}
+
+func (t *llcToolchain) Archive(objects []string, out string) error {
+	return archive(t.ar, t.triple, objects, out)
+}
+
+// dynamicLinkers are the program interpreters of the linux targets
+var dynamicLinkers = map[string]string{
+	"x86_64":  "/lib64/ld-linux-x86-64.so.2",
+	"aarch64": "/lib/ld-linux-aarch64.so.1",
+	"riscv64": "/lib/ld-linux-riscv64-lp64d.so.1",
+	"i386":    "/lib/ld-linux.so.2",
+	"i686":    "/lib/ld-linux.so.2",
+}
+
+// targetArch returns the architecture of a target triple
+func targetArch(triple string) string {
+	return strings.SplitN(triple, "-", 2)[0]
+}
+
+// multiarchName returns the name debian style systems keep the libraries
+// of a target under, which leaves the vendor out of the triple
+func multiarchName(triple string) string {
+	parts := strings.Split(triple, "-")
+	if len(parts) == 4 {
+		parts = append(parts[:1], parts[2:]...)
+	}
+	return strings.Join(parts, "-")
+}
+
+// systemLibDirs returns the directories the C runtime and libraries of a
+// target are usually installed in, including gcc's own, which has crtbegin.o.
+// The directories without the target in their name only have the libraries
+// of the machine geode runs on, so they are left out when cross compiling
+func systemLibDirs(triple string, cross bool) []string {
+	multiarch := multiarchName(triple)
+	dirs := []string{}
+	for _, gcc := range []string{"/usr/lib/gcc", "/usr/lib/gcc-cross"} {
+		gccDirs, _ := filepath.Glob(filepath.Join(gcc, multiarch, "*"))
+		// The newest version of gcc is first
+		sort.Slice(gccDirs, func(i, j int) bool {
+			return versionLess(filepath.Base(gccDirs[j]), filepath.Base(gccDirs[i]))
+		})
+		if len(gccDirs) > 0 {
+			dirs = append(dirs, gccDirs[0])
+		}
+	}
+	dirs = append(dirs,
+		// Where cross compilers keep the libraries of their target
+		filepath.Join("/usr", multiarch, "lib"),
+		filepath.Join("/usr/lib", multiarch),
+		filepath.Join("/lib", multiarch))
+	if cross {
+		return dirs
+	}
+	return append(dirs, "/usr/lib64", "/lib64", "/usr/lib", "/lib")
+}
+
+// versionLess returns if the dotted version a, like 9 or 4.9.2, is older
+// than b. The parts are compared as numbers, so 9 is older than 13
+func versionLess(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, xerr := strconv.Atoi(as[i])
+		y, yerr := strconv.Atoi(bs[i])
+		if xerr != nil || yerr != nil {
+			if as[i] != bs[i] {
+				return as[i] < bs[i]
+			}
+			continue
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	return len(as) < len(bs)
+}
+
+func copyFile(from, to string) error {
+	data, err := ioutil.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(to, data, 0644)
+}
@@ -0,0 +1,204 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// traceInit returns the function main calls to hand the runtime the table
+// of functions that stack traces are printed with. Like coverInit, it is
+// only declared until FinishTrace gives it a body
+func (p *Program) traceInit() *ir.Func {
+	if p.traceInitFunc == nil {
+		p.traceInitFunc = p.Module.NewFunc("__trace_init", types.Void)
+	}
+	return p.traceInitFunc
+}
+
+// traceCall remembers where a call was made, so the stack traces of the
+// program can show the line of the call rather than where the function
+// that made it was declared
+func (p *Program) traceCall(call *ir.InstCall, tok lexer.Token) {
+	if !tok.HasSource() || p.generated[tok.Path()] {
+		return
+	}
+	if p.traceCalls == nil {
+		p.traceCalls = make(map[*ir.InstCall]string)
+	}
+	p.traceCalls[call] = tok.FileInfo()
+}
+
+// traceFrame makes a function that calls others keep a frame on the stack
+// of them that the runtime prints traces with. The frame has the function
+// and the location of the call it is making, which is set before each call.
+// Return addresses can't be mapped back to the line of the call, so this is
+// how a trace shows where each function was in the middle of
+func (p *Program) traceFrame(fn *ir.Func, location constant.Constant, str func(string) constant.Constant) {
+	if len(fn.Blocks) == 0 {
+		return
+	}
+	calls := false
+	for _, blk := range fn.Blocks {
+		for _, inst := range blk.Insts {
+			if call, ok := inst.(*ir.InstCall); ok && p.traceCalls[call] != "" {
+				calls = true
+			}
+		}
+	}
+	if !calls {
+		return
+	}
+
+	top := p.traceTop()
+	frameType := types.NewStruct(types.I8Ptr, types.I8Ptr, types.I8Ptr)
+	field := func(frame value.Value, i int64) *ir.InstGetElementPtr {
+		return ir.NewGetElementPtr(frameType, frame, constant.NewInt(types.I32, 0), constant.NewInt(types.I32, i))
+	}
+
+	// The frame is pushed before anything else runs, starting out with
+	// where the function was declared
+	frame := ir.NewAlloca(frameType)
+	prev := ir.NewLoad(types.I8Ptr, top)
+	prevField, funcField, locField := field(frame, 0), field(frame, 1), field(frame, 2)
+	self := ir.NewBitCast(frame, types.I8Ptr)
+	push := []ir.Instruction{
+		frame, prev, prevField, funcField, locField,
+		ir.NewStore(prev, prevField),
+		ir.NewStore(constant.NewBitCast(fn, types.I8Ptr), funcField),
+		ir.NewStore(location, locField),
+		self,
+		ir.NewStore(self, top),
+	}
+
+	for i, blk := range fn.Blocks {
+		insts := make([]ir.Instruction, 0, len(blk.Insts))
+		if i == 0 {
+			insts = append(insts, push...)
+		}
+		for _, inst := range blk.Insts {
+			if call, ok := inst.(*ir.InstCall); ok && p.traceCalls[call] != "" {
+				insts = append(insts, ir.NewStore(str(p.traceCalls[call]), locField))
+			}
+			insts = append(insts, inst)
+		}
+		// and popped when it returns
+		if _, ok := blk.Term.(*ir.TermRet); ok {
+			insts = append(insts, ir.NewStore(prev, top))
+		}
+		blk.Insts = insts
+	}
+}
+
+// traceTop returns the runtime's thread local pointer to the innermost frame
+// of the functions that are calling others
+func (p *Program) traceTop() *ir.Global {
+	for _, g := range p.Module.Globals {
+		if g.Name() == "__trace_top" {
+			return g
+		}
+	}
+	top := p.Module.NewGlobal("__trace_top", types.I8Ptr)
+	top.Linkage = enum.LinkageExternal
+	top.TLSModel = enum.TLSModelGeneric
+	return top
+}
+
+// traceName returns the signature a stack trace shows for a function, like
+// io:print(byte*) void
+func traceName(fn *ir.Func) string {
+	name := fn.Name()
+	if !strings.HasPrefix(name, functionNamePrefix+separator) {
+		params := make([]types.Type, 0, len(fn.Sig.Params))
+		params = append(params, fn.Sig.Params...)
+		name = MangleFunctionName(name, params, fn.Sig.RetType)
+	}
+	if demangled, err := Demangle(name); err == nil {
+		return demangled
+	}
+	return fn.Name()
+}
+
+// FinishTrace fills in the function that registers the address, signature
+// and location of every compiled function with the runtime, which looks up
+// the return addresses of a stack trace in them. The table ends with an
+// empty function, so addresses past the last function aren't taken to be
+// in it. The functions that call others also get frames that have the line
+// of the call they are making. This should be called after all the
+// functions are compiled
+func (p *Program) FinishTrace() error {
+	if p.traceInitFunc == nil {
+		return nil
+	}
+
+	// Where each function was declared, by the variants that were compiled
+	locations := make(map[*ir.Func]string)
+	for _, node := range p.Functions {
+		if !node.Token.HasSource() || p.generated[node.Token.Path()] {
+			continue
+		}
+		for _, fn := range node.Variants {
+			locations[fn] = node.Token.FileInfo()
+		}
+	}
+
+	funcs := make([]constant.Constant, 0, len(p.Module.Funcs))
+	names := make([]constant.Constant, 0, len(p.Module.Funcs))
+	locs := make([]constant.Constant, 0, len(p.Module.Funcs))
+	zero := constant.NewInt(types.I32, 0)
+	str := func(name string, s string) constant.Constant {
+		def := p.Module.NewGlobalDef(name, newCharArray(s))
+		def.Immutable = true
+		return constant.NewGetElementPtr(def.ContentType, def, zero, zero)
+	}
+	lines := make(map[string]constant.Constant)
+	line := func(s string) constant.Constant {
+		if _, ok := lines[s]; !ok {
+			lines[s] = str(fmt.Sprintf("__trace.line.%d", len(lines)), s)
+		}
+		return lines[s]
+	}
+	for _, fn := range p.Module.Funcs {
+		if len(fn.Blocks) == 0 && fn != p.traceInitFunc {
+			continue
+		}
+		i := len(funcs)
+		loc := str(fmt.Sprintf("__trace.loc.%d", i), locations[fn])
+		funcs = append(funcs, constant.NewBitCast(fn, types.I8Ptr))
+		names = append(names, str(fmt.Sprintf("__trace.name.%d", i), traceName(fn)))
+		locs = append(locs, loc)
+		// WASI programs can't print traces, so they are left without frames
+		if locations[fn] != "" && !IsWASI(p.Target.Triple) {
+			p.traceFrame(fn, loc, line)
+		}
+	}
+
+	end := p.Module.NewFunc("__trace_end", types.Void)
+	end.NewBlock("").NewRet(nil)
+
+	tableType := types.NewArray(uint64(len(funcs)), types.I8Ptr)
+	funcTable := p.Module.NewGlobalDef("__trace.funcs", constant.NewArray(tableType, funcs...))
+	nameTable := p.Module.NewGlobalDef("__trace.names", constant.NewArray(tableType, names...))
+	locationTable := p.Module.NewGlobalDef("__trace.locations", constant.NewArray(tableType, locs...))
+
+	register, err := p.GetFunction("__trace_register", FunctionCompilationOptions{})
+	if err != nil {
+		return err
+	}
+
+	blk := p.traceInitFunc.NewBlock("")
+	blk.NewCall(register,
+		constant.NewGetElementPtr(funcTable.ContentType, funcTable, zero, zero),
+		constant.NewGetElementPtr(nameTable.ContentType, nameTable, zero, zero),
+		constant.NewGetElementPtr(locationTable.ContentType, locationTable, zero, zero),
+		constant.NewInt(types.I64, int64(len(funcs))),
+		constant.NewBitCast(end, types.I8Ptr))
+	blk.NewRet(nil)
+	return nil
+}
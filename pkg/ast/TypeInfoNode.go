@@ -39,34 +39,48 @@ func (n TypeInfoNode) Codegen(prog *Program) (value.Value, error) {
 		return nil, err
 	}
 
-	// allocation was not found, so we make a new global one.
 	typ, _ := n.Type(prog)
-
 	sct := typ.(*gtypes.StructType)
-	globl := prog.Module.NewGlobal(fmt.Sprintf("type_info_%s", n.T), sct)
 
-	globl.Init = constant.NewZeroInitializer(sct)
-
-	prog.TypeInfoDefs[n.T.String()] = &TypeInfoDeclaration{
-		Global:  globl,
-		Defined: false,
+	// The layout is known while compiling, so the info is a constant global
+	fields := map[string]constant.Constant{
+		"size":  typeInfoInt(sct, "size", gtypes.ByteCount(analyzeType)),
+		"align": typeInfoInt(sct, "align", gtypes.AlignOf(analyzeType)),
+		"name":  prog.constString(n.T.Name),
 	}
 
-	// https://stackoverflow.com/a/30830445
-	elemptr := constant.NewGetElementPtr(analyzeType, constant.NewNull(types.NewPointer(analyzeType)), constant.NewInt(types.I32, 1))
-
-	size := prog.Compiler.CurrentBlock().NewPtrToInt(elemptr, types.I64)
-
-	nameNode := StringNode{}
-	nameNode.Value = n.T.Name
-	name, _ := nameNode.Codegen(prog)
+	if class, ok := analyzeType.(*gtypes.StructType); ok {
+		names := make([]constant.Constant, len(class.Fields))
+		offsets := make([]constant.Constant, len(class.Fields))
+		offsetType := sct.Fields[sct.FieldIndex("offsets")].(*types.PointerType).ElemType.(*types.IntType)
+		for i, offset := range gtypes.FieldOffsets(class.StructType) {
+			name := ""
+			if i < len(class.Names) {
+				name = class.Names[i]
+			}
+			names[i] = prog.constString(name)
+			offsets[i] = constant.NewInt(offsetType, int64(offset))
+		}
+
+		fields["fieldcount"] = typeInfoInt(sct, "fieldcount", len(class.Fields))
+		fields["fieldnames"] = typeInfoTable(prog, fmt.Sprintf("type_info_%s.fieldnames", n.T), types.I8Ptr, names)
+		fields["offsets"] = typeInfoTable(prog, fmt.Sprintf("type_info_%s.offsets", n.T), offsetType, offsets)
+	}
 
-	inst := NewClassInstance(prog, sct, map[string]value.Value{
-		"size": size,
-		"name": name,
-	})
+	init := make([]constant.Constant, len(sct.Fields))
+	for i, t := range sct.Fields {
+		init[i] = constant.NewZeroInitializer(t)
+		if i < len(sct.Names) {
+			if c, ok := fields[sct.Names[i]]; ok {
+				init[i] = c
+			}
+		}
+	}
 
-	prog.Compiler.CurrentBlock().NewStore(inst, globl)
+	// The global is of the geode type, so its fields can be found by name
+	globl := prog.Module.NewGlobal(fmt.Sprintf("type_info_%s", n.T), sct)
+	globl.Init = constant.NewStruct(sct.StructType, init...)
+	globl.Immutable = true
 
 	prog.TypeInfoDefs[n.T.String()] = &TypeInfoDeclaration{
 		Global:  globl,
@@ -76,6 +90,23 @@ func (n TypeInfoNode) Codegen(prog *Program) (value.Value, error) {
 	return globl, nil
 }
 
+// typeInfoInt returns a number in a field of TypeInfo, as the field's type
+func typeInfoInt(sct *gtypes.StructType, field string, n int) constant.Constant {
+	return constant.NewInt(sct.Fields[sct.FieldIndex(field)].(*types.IntType), int64(n))
+}
+
+// typeInfoTable returns a pointer to a constant global array, which is
+// null if it is empty
+func typeInfoTable(prog *Program, name string, elemType types.Type, elems []constant.Constant) constant.Constant {
+	if len(elems) == 0 {
+		return constant.NewNull(types.NewPointer(elemType))
+	}
+	table := prog.Module.NewGlobalDef(name, constant.NewArray(types.NewArray(uint64(len(elems)), elemType), elems...))
+	table.Immutable = true
+	zero := constant.NewInt(types.I32, 0)
+	return constant.NewGetElementPtr(table.ContentType, table, zero, zero)
+}
+
 // GenAccess implements Accessable.Access for TypeInfoNode
 func (n TypeInfoNode) GenAccess(prog *Program) (value.Value, error) {
 	return n.Codegen(prog)
@@ -6,7 +6,6 @@ import (
 
 	"github.com/geode-lang/geode/pkg/util/log"
 	"github.com/llir/llvm/ir"
-	"github.com/llir/llvm/ir/constant"
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
 )
@@ -23,6 +22,7 @@ type VariableDefnNode struct {
 	NeedsInference bool
 
 	Package *Package
+	Doc     string // the comment written above a class field
 }
 
 // NameString implements Node.NameString
@@ -68,7 +68,15 @@ func (n VariableDefnNode) Codegen(prog *Program) (value.Value, error) {
 			}
 			val = v
 		}
+		// The variable has the type the checker inferred for it. Generic
+		// functions aren't checked, so theirs come from the value
 		valType = val.Type()
+		if t, ok := prog.Inferred[n.Token]; ok {
+			if val, err = createTypeCast(prog, val, t); err != nil {
+				return nil, err
+			}
+			valType = t
+		}
 	}
 
 	alloc = createBlockAlloca(f, valType, name.String())
@@ -97,10 +105,14 @@ func (n VariableDefnNode) Codegen(prog *Program) (value.Value, error) {
 
 	// If the value is nil, we need to pull the default value for a given type.
 	if val == nil {
-		val = constant.NewZeroInitializer(alloc.ElemType)
+		val, err = prog.defaultValue(alloc.ElemType)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	block.NewStore(val, alloc)
+	// The value can have branched, like a short circuited &&
+	prog.Compiler.CurrentBlock().NewStore(val, alloc)
 
 	return alloc, nil
 }
@@ -125,7 +137,7 @@ func (n VariableDefnNode) String() string {
 	buff := &bytes.Buffer{}
 
 	if n.NeedsInference {
-		fmt.Fprintf(buff, "let %s", n.Name)
+		fmt.Fprintf(buff, "%s", n.Name)
 	} else {
 		fmt.Fprintf(buff, "%s %s", n.Typ, n.Name)
 	}
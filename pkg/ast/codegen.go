@@ -64,7 +64,7 @@ func (n IfNode) Codegen(prog *Program) (value.Value, error) {
 
 	thenBlk := parentFunc.NewBlock(mangleName(namePrefix + "then"))
 
-	prog.Compiler.genInBlock(thenBlk, func() error {
+	err = prog.Compiler.genInBlock(thenBlk, func() error {
 		gen, gerr := n.Then.Codegen(prog)
 		if gerr != nil {
 			return gerr
@@ -72,11 +72,14 @@ func (n IfNode) Codegen(prog *Program) (value.Value, error) {
 		thenGenBlk = gen.(*ir.Block)
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	elseBlk := parentFunc.NewBlock(mangleName(namePrefix + "else"))
 	var elseGenBlk *ir.Block
 
-	prog.Compiler.genInBlock(elseBlk, func() error {
+	err = prog.Compiler.genInBlock(elseBlk, func() error {
 		// We only want to construct the else block if there is one.
 		if n.Else != nil {
 			gen, gerr := n.Else.Codegen(prog)
@@ -87,6 +90,9 @@ func (n IfNode) Codegen(prog *Program) (value.Value, error) {
 		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	endBlk = parentFunc.NewBlock(mangleName(namePrefix + "end"))
 	prog.Compiler.PushBlock(endBlk)
@@ -140,6 +146,10 @@ func (n UnaryNode) Codegen(prog *Program) (value.Value, error) {
 		return nil, fmt.Errorf("nil operand")
 	}
 
+	if n.Operator == "try" {
+		return n.genTry(prog, operandValue)
+	}
+
 	if n.Operator == "-" {
 
 		if types.IsFloat(operandValue.Type()) {
@@ -174,6 +184,9 @@ func (n UnaryNode) Codegen(prog *Program) (value.Value, error) {
 
 		// fmt.Println(prog.Compiler.CurrentFunc())
 		if types.IsPointer(operandValue.Type()) {
+			if err := prog.nilCheck(operandValue, n.Operand, n.Token); err != nil {
+				return nil, err
+			}
 			elemType := operandValue.Type().(*types.PointerType).ElemType
 			return prog.Compiler.CurrentBlock().NewLoad(elemType, operandValue), nil
 		}
@@ -203,14 +216,16 @@ func (n WhileNode) Codegen(prog *Program) (value.Value, error) {
 	if err != nil {
 		return nil, err
 	}
-	one := constant.NewInt(types.I1, 1)
-	prog.Compiler.PopBlock()
-	BranchIfNoTerminator(parentBlock, startblock)
-	c, err := createTypeCast(prog, predicate, types.I1)
+	// The condition is branched on as it is, so the end of a `while true`
+	// loop is known to be unreachable
+	predicate, err = createTypeCast(prog, predicate, types.I1)
 	if err != nil {
 		return nil, err
 	}
-	predicate = startblock.NewICmp(enum.IPredEQ, one, c)
+	// The condition can have branched, like a short circuited &&
+	condEndBlk := prog.Compiler.CurrentBlock()
+	prog.Compiler.PopBlock()
+	BranchIfNoTerminator(parentBlock, startblock)
 
 	var endBlk *ir.Block
 
@@ -232,7 +247,7 @@ func (n WhileNode) Codegen(prog *Program) (value.Value, error) {
 	BranchIfNoTerminator(bodyBlk, startblock)
 	BranchIfNoTerminator(bodyGenBlk, startblock)
 
-	startblock.NewCondBr(predicate, bodyBlk, endBlk)
+	condEndBlk.NewCondBr(predicate, bodyBlk, endBlk)
 
 	// branchIfNoTerminator(c.CurrentBlock(), endBlk)
 
@@ -251,7 +266,7 @@ func typeSize(t types.Type) int {
 }
 
 func typesAreLooselyEqual(a, b types.Type) bool {
-	return gtypes.IsNumber(a) && gtypes.IsNumber(b)
+	return gtypes.IsNumber(a) && gtypes.IsNumber(b) || isNilType(a) && types.IsPointer(b)
 }
 
 // createTypeCast is where most, if not all, type casting happens in the language.
@@ -286,6 +301,10 @@ func createTypeCast(prog *Program, in value.Value, to types.Type) (value.Value,
 		return nil, nil
 	}
 
+	if res, ok := to.(*gtypes.ResultType); ok {
+		return wrapResult(prog, in, res)
+	}
+
 	if types.IsPointer(inType) && types.IsPointer(to) {
 		return prog.Compiler.CurrentBlock().NewBitCast(in, to), nil
 	}
@@ -350,7 +369,7 @@ func (n ReturnNode) Codegen(prog *Program) (value.Value, error) {
 			given := retVal.Type()
 			expected := prog.Compiler.CurrentFunc().Sig.RetType
 			if !types.Equal(given, expected) {
-				if !(types.IsInt(given) && types.IsInt(expected)) {
+				if !(types.IsInt(given) && types.IsInt(expected)) && !(isNilType(given) && types.IsPointer(expected)) && !gtypes.IsResult(expected) {
 					n.SyntaxError()
 					fnName, err := UnmangleFunctionName(prog.Compiler.CurrentFunc().Name())
 					if err != nil {
@@ -376,6 +395,9 @@ func (n ReturnNode) Codegen(prog *Program) (value.Value, error) {
 					return nil, err
 				}
 			}
+		} else if res, ok := prog.Compiler.CurrentFunc().Sig.RetType.(*gtypes.ResultType); ok {
+			// A void result succeeds when it returns nothing
+			retVal, _ = wrapResult(prog, nil, res)
 		} else {
 
 			retVal = nil
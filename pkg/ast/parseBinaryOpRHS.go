@@ -17,6 +17,7 @@ func (p *Parser) parseBinaryOpRHS(exprPrec int, lhs Node) Node {
 			return lhs
 		}
 		binOp := p.token.Value
+		opToken := p.token
 		p.Next()
 
 		// right hand sides will never have a declaration, so pass false
@@ -33,7 +34,7 @@ func (p *Parser) parseBinaryOpRHS(exprPrec int, lhs Node) Node {
 			}
 		}
 		n := BinaryNode{}
-		n.TokenReference.Token = p.token
+		n.TokenReference.Token = opToken
 		n.NodeType = nodeBinary
 		n.OP = binOp
 		n.Left = lhs
@@ -45,6 +45,7 @@ func (p *Parser) parseBlockStmt() BlockNode {
 
 		// If the block is over.
 		if p.token.Is(lexer.TokRightCurly) {
+			blk.Close = p.token
 			break
 		}
 
@@ -12,6 +12,7 @@ func (p *Parser) parseClassDefn() Node {
 	n := ClassNode{}
 	n.TokenReference.Token = p.token
 	n.NodeType = nodeClass
+	n.Doc = p.docComment(p.token)
 
 	p.Next()
 
@@ -60,8 +61,12 @@ func (p *Parser) parseClassBody() []Node {
 		}
 
 		if p.atType() {
-			// No initializer is allowed in class variable defns
-			nodes = append(nodes, p.parseVariableDefn(false))
+			// A field can have an initializer, which is the value it
+			// starts out as in every instance of the class
+			doc := p.docComment(p.token)
+			field := p.parseVariableDefn(true)
+			field.Doc = doc
+			nodes = append(nodes, field)
 			p.globTerminator()
 			continue
 		}
@@ -70,6 +75,9 @@ func (p *Parser) parseClassBody() []Node {
 		if p.token.Is(lexer.TokRightCurly) {
 			break
 		}
+
+		p.token.SyntaxError()
+		log.Fatal("Expected a field or method in class body, found %q\n", p.token.Value)
 	}
 	p.Next()
 
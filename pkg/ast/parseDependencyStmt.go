@@ -16,6 +16,12 @@ func (p *Parser) parseDependencyStmt() Node {
 	}
 	p.Next()
 
+	// c is only a keyword between include and a header
+	if !d.CLinkage && p.token.Is(lexer.TokIdent) && p.token.Value == "c" && p.Peek(1).Is(lexer.TokString) {
+		d.CHeader = true
+		p.Next()
+	}
+
 	d.Paths = make([]string, 0)
 
 	for {
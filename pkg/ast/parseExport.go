@@ -0,0 +1,30 @@
+package ast
+
+import (
+	"github.com/geode-lang/geode/pkg/lexer"
+)
+
+// parseExport parses a function or class marked with export. Libraries
+// built with --lib export the function to C under its own name, like
+// nomangle, and declare it and the class in the header they come with
+func (p *Parser) parseExport() Node {
+	doc := p.docComment(p.token)
+	p.Next()
+
+	if p.token.Is(lexer.TokClassDefn) {
+		cls := p.parseClassDefn().(ClassNode)
+		cls.Export = true
+		if cls.Doc == "" {
+			cls.Doc = doc
+		}
+		return cls
+	}
+
+	fn := p.parseFunctionNode()
+	fn.Export = true
+	fn.Nomangle = true
+	if fn.Doc == "" {
+		fn.Doc = doc
+	}
+	return fn
+}
@@ -1,10 +1,16 @@
 package ast
 
 import (
+	"strings"
+
 	"github.com/geode-lang/geode/pkg/lexer"
 )
 
 func (p *Parser) parseExpression(allowdecl bool) Node {
+	if allowdecl && p.token.Is(lexer.TokIdent) && p.Peek(1).Value == ":=" {
+		defer p.globTerminator()
+		return p.parseInferredDefn()
+	}
 	lhs := p.parseUnary(allowdecl)
 	if lhs == nil {
 		return nil
@@ -26,6 +32,10 @@ func (p *Parser) parseCompoundExpression(allowdecl bool) (ExpComponent, error) {
 	switch p.token.Type {
 
 	case lexer.TokIdent, lexer.TokType:
+		if p.atClassLiteral() {
+			err = p.parseClassLiteralComponent(chain)
+			break
+		}
 		err = p.parseIdentifierComponent(chain, allowdecl)
 	case lexer.TokNumber:
 		err = p.parseNumberComponent(chain)
@@ -39,6 +49,8 @@ func (p *Parser) parseCompoundExpression(allowdecl bool) (ExpComponent, error) {
 		err = p.parseBooleanComponent(chain)
 	case lexer.TokChar:
 		err = p.parseCharComponent(chain)
+	case lexer.TokNil:
+		err = p.parseNilComponent(chain)
 	case lexer.TokInfo:
 		err = p.parseTypeInfoComponent(chain)
 	default:
@@ -120,6 +132,7 @@ func (p *Parser) parseIdentDeclComponent(base *BaseComponent) error {
 
 	// we now know the token is an ident, so we pull the value from it.
 	n.Name.Value = p.token.Value
+	n.Name.Token = p.token
 	p.Next()
 
 	base.Add(n)
@@ -338,6 +351,23 @@ func (p *Parser) parseCharComponent(base *BaseComponent) error {
 	return nil
 }
 
+// =========================== parseNilComponent ===========================
+
+func (p *Parser) parseNilComponent(base *BaseComponent) error {
+	n := &NilComponent{}
+	n.token = p.token
+
+	if !p.token.Is(lexer.TokNil) {
+		return p.Errorf("parseNilComponent expects nil")
+	}
+
+	p.Next()
+
+	base.Add(n)
+
+	return nil
+}
+
 // =========================== parseTypeInfoComponent ===========================
 
 func (p *Parser) parseTypeInfoComponent(base *BaseComponent) error {
@@ -368,3 +398,54 @@ func (p *Parser) parseTypeInfoComponent(base *BaseComponent) error {
 
 	return nil
 }
+
+// =========================== parseClassLiteralComponent ===========================
+
+// atClassLiteral returns if the parser is at the start of a class literal,
+// which is a type followed by a curly brace that is either closed right away
+// or opens with the name of a field, like `Point{}` or `Point{x: 1}`
+func (p *Parser) atClassLiteral() bool {
+	if !p.token.Is(lexer.TokType) || p.Peek(1).Type != lexer.TokLeftCurly {
+		return false
+	}
+	next := p.Peek(2)
+	return next.Is(lexer.TokRightCurly) || isFieldLabel(next)
+}
+
+// isFieldLabel returns if a token names a field in a class literal. The
+// lexer keeps the colon after a name as part of it, so `x: 1` is the
+// identifier `x:` followed by a number
+func isFieldLabel(tok lexer.Token) bool {
+	return tok.Is(lexer.TokIdent) && len(tok.Value) > 1 && strings.HasSuffix(tok.Value, ":")
+}
+
+func (p *Parser) parseClassLiteralComponent(base *BaseComponent) error {
+	n := &ClassLiteralComponent{}
+	n.token = p.token
+	n.Type = p.parseType()
+
+	for p.Next(); !p.token.Is(lexer.TokRightCurly); {
+		switch {
+		case p.token.Is(lexer.TokComma):
+			p.Next()
+		case isFieldLabel(p.token):
+			field := ClassLiteralField{}
+			field.Name = NewIdentNode(strings.TrimSuffix(p.token.Value, ":"))
+			field.Name.Token = p.token
+			p.Next()
+
+			field.Value = p.parseExpression(false)
+			if field.Value == nil {
+				return p.Errorf("missing value for field %s in class literal", field.Name)
+			}
+			n.Fields = append(n.Fields, field)
+		default:
+			return p.Errorf("expected the name of a field followed by a colon in class literal, found %q", p.token.Value)
+		}
+	}
+
+	p.Next()
+	base.Add(n)
+
+	return nil
+}
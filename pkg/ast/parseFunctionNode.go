@@ -12,6 +12,7 @@ func (p *Parser) parseFunctionNode() FunctionNode {
 	fn := FunctionNode{}
 	fn.TokenReference.Token = p.token
 	fn.NodeType = nodeFunction
+	fn.Doc = p.docComment(p.token)
 	fn.DeclKeyword = DeclKeywordFunc
 
 	fn.line = p.token.Line
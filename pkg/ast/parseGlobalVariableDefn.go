@@ -10,6 +10,7 @@ func (p *Parser) parseGlobalVariableDecl() GlobalVariableDeclNode {
 	n.Token = p.token
 	n.NodeType = nodeGlobalDecl
 	n.TokenReference.Token = p.token
+	n.Doc = p.docComment(p.token)
 
 	if p.atType() {
 		n.Type = p.parseType()
@@ -0,0 +1,53 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/geode-lang/geode/pkg/lexer"
+	"github.com/geode-lang/geode/pkg/util/log"
+)
+
+var testIndex = 0
+var benchIndex = 0
+
+// parseTestBlock parses a `test "name" { ... }` or `bench "name" { ... }`
+// block into a function that takes no arguments. The blocks are only
+// compiled when testing or benchmarking
+func (p *Parser) parseTestBlock() FunctionNode {
+	fn := FunctionNode{}
+	fn.TokenReference.Token = p.token
+	fn.NodeType = nodeFunction
+	fn.DeclKeyword = DeclKeywordFunc
+
+	fn.line = p.token.Line
+	fn.column = p.token.Column
+
+	kind := p.token.Value
+	p.Next()
+
+	name, err := UnescapeString(p.token.Value[1 : len(p.token.Value)-1])
+	if err != nil {
+		p.token.SyntaxError()
+		log.Fatal("invalid %s name: %s\n", kind, err)
+	}
+	if kind == "bench" {
+		fn.Bench = name
+		fn.Name = NewIdentNode(fmt.Sprintf("__bench_%d", benchIndex))
+		benchIndex++
+	} else {
+		fn.Test = name
+		fn.Name = NewIdentNode(fmt.Sprintf("__test_%d", testIndex))
+		testIndex++
+	}
+
+	fn.ReturnType = TypeNode{}
+	fn.ReturnType.Name = "void"
+
+	p.Next()
+	if !p.token.Is(lexer.TokLeftCurly) {
+		p.token.SyntaxError()
+		log.Fatal("expected a block after the name of %s %q\n", kind, name)
+	}
+	fn.BodyParser = p.forkBlockParser()
+	return fn
+}
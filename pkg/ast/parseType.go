@@ -5,7 +5,7 @@ import (
 	"github.com/geode-lang/geode/pkg/util/log"
 )
 
-var typeOperators = []string{"*", "?"}
+var typeOperators = []string{"*", "?", "!"}
 
 func validTypeInfoTokens(t lexer.Token) bool {
 	for _, op := range typeOperators {
@@ -47,6 +47,12 @@ func (p *Parser) parseType() (t TypeNode) {
 	for {
 
 		if p.token.Is(lexer.TokQuestionMark) {
+			// A `?` right after a pointer makes it nullable, `T*?`
+			if l := len(t.Modifiers); l > 0 && t.Modifiers[l-1] == ModifierPointer {
+				t.Modifiers = append(t.Modifiers, ModifierNullable)
+				p.Next()
+				continue
+			}
 			if t.Unknown {
 				log.Fatal("Multiple Unknown Type operators for %q used.\n", t.Name)
 			}
@@ -57,6 +63,13 @@ func (p *Parser) parseType() (t TypeNode) {
 			continue
 		}
 
+		// A `!` makes the type a result, `T!`, and has to come last
+		if p.token.Is(lexer.TokOper) && p.token.Value == "!" {
+			t.Modifiers = append(t.Modifiers, ModifierResult)
+			p.Next()
+			break
+		}
+
 		if p.token.Is(lexer.TokOper) && validTypeInfoTokens(p.token) {
 			for _, c := range p.token.Value {
 				if c == '*' {
@@ -22,6 +22,12 @@ func (p *Parser) parseUnary(allowdecl bool) Node {
 	// _, isBinaryOp := p.binaryOpPrecedence[p.token.Value]
 	_, isPtrOp := validUnaryOps[p.token.Value]
 
+	// try is only a keyword when it comes before a value, so it can still
+	// be used as a name
+	if p.token.Is(lexer.TokIdent) && p.token.Value == "try" && p.Peek(1).Is(lexer.TokIdent, lexer.TokLeftParen) {
+		isPtrOp = true
+	}
+
 	if !isPtrOp {
 		chain, _ := p.parseCompoundExpression(allowdecl)
 		if chain != nil {
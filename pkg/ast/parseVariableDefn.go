@@ -20,13 +20,14 @@ func (p *Parser) parseVariableDefn(allowDefn bool) VariableDefnNode {
 
 	if p.token.Is(lexer.TokIdent) {
 		n.Name = NewIdentNode(p.token.Value)
+		n.Name.Token = p.token
 		p.Next()
 	} else {
 		n.SyntaxError()
 		log.Fatal("type: Invalid variable declaration\n")
 	}
 
-	if p.token.Is(lexer.TokAssignment) {
+	if p.token.Is(lexer.TokOper) && p.token.Value == "=" {
 		if allowDefn {
 			n.HasValue = true
 			p.Next()
@@ -41,3 +42,31 @@ func (p *Parser) parseVariableDefn(allowDefn bool) VariableDefnNode {
 
 	return n
 }
+
+// parseInferredDefn parses a `name := value` declaration, where
+// the type of the variable is inferred from the value
+func (p *Parser) parseInferredDefn() VariableDefnNode {
+	n := VariableDefnNode{}
+	n.NodeType = nodeVariableDecl
+	n.TokenReference.Token = p.token
+	n.NeedsInference = true
+	n.HasValue = true
+
+	p.requires(lexer.TokIdent)
+	n.Name = NewIdentNode(p.token.Value)
+	n.Name.Token = p.token
+	p.Next()
+
+	if p.token.Value != ":=" {
+		n.SyntaxError()
+		log.Fatal("Expected ':=' in variable declaration\n")
+	}
+	p.Next()
+
+	n.Body = p.parseExpression(false)
+	if n.Body == nil {
+		n.SyntaxError()
+		log.Fatal("Variable declaration of '%s' requires a value\n", n.Name)
+	}
+	return n
+}
@@ -0,0 +1,140 @@
+// Package bindgen generates geode packages that bind the declarations of C
+// headers, so C libraries can be called without writing their external
+// declarations by hand.
+package bindgen
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Options are how a header is read
+type Options struct {
+	// CC is the C compiler that preprocesses the header. It defaults to $CC,
+	// or the first of cc, gcc and clang that is installed
+	CC string
+	// Target is the triple of the target the package is for, which is
+	// passed to the compiler if it is clang
+	Target string
+	// Flags are passed to the compiler, like -I or -D flags
+	Flags []string
+	// Package is the name of the package. It defaults to the header's name
+	Package string
+}
+
+// Result is a package generated from a header
+type Result struct {
+	Source string
+	// Skipped are the declarations that couldn't be bound, and why
+	Skipped []string
+	// Warnings are the declarations that couldn't be read
+	Warnings []string
+}
+
+// Generate returns a geode package of the functions, variables, structs and
+// constants a header declares. The header is a path to a file or the name
+// of a system header like stdio.h, which is looked up in the compiler's
+// include path
+func Generate(header string, opts Options) (*Result, error) {
+	src, err := preprocess(header, opts)
+	if err != nil {
+		return nil, err
+	}
+	l, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", header, err)
+	}
+	if l.main == "" {
+		return nil, fmt.Errorf("%s: the preprocessor didn't include it", header)
+	}
+	p := newParser(l)
+	p.parse()
+
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = PackageName(header)
+	}
+	g := &generator{
+		p:       p,
+		dir:     filepath.Dir(filepath.Clean(l.main)),
+		names:   make(map[string]bool),
+		classes: make(map[*record]bool),
+		layouts: make(map[*record]*layout),
+	}
+	source := g.generate(pkg, header)
+	return &Result{Source: source, Skipped: g.skipped, Warnings: p.warnings}, nil
+}
+
+// PackageName returns the name of the package a header is bound as, which
+// is the name of the file in lower case, like stdio for stdio.h. Names geode
+// keeps for itself get a suffix, like string_h for string.h
+func PackageName(header string) string {
+	base := strings.TrimSuffix(filepath.Base(header), filepath.Ext(header))
+	name := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return '_'
+		}
+		return unicode.ToLower(r)
+	}, base)
+	if name == "" || !unicode.IsLetter(rune(name[0])) {
+		name = "c_" + name
+	}
+	if keywords[name] {
+		name += "_h"
+	}
+	return name
+}
+
+// compiler returns the C compiler that preprocesses headers
+func compiler(opts Options) (string, error) {
+	if opts.CC != "" {
+		return opts.CC, nil
+	}
+	if cc := os.Getenv("CC"); cc != "" {
+		return cc, nil
+	}
+	for _, name := range []string{"cc", "gcc", "clang"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no C compiler found to read headers with, install cc, gcc or clang or set $CC")
+}
+
+// preprocess runs the preprocessor over a header, keeping the macros it
+// defines. A header that is a file is included by its path, and any other
+// from the include path
+func preprocess(header string, opts Options) (string, error) {
+	cc, err := compiler(opts)
+	if err != nil {
+		return "", err
+	}
+	include := fmt.Sprintf("#include <%s>\n", header)
+	if info, err := os.Stat(header); err == nil && !info.IsDir() {
+		abs, err := filepath.Abs(header)
+		if err != nil {
+			return "", err
+		}
+		include = fmt.Sprintf("#include %q\n", abs)
+	}
+
+	args := []string{"-E", "-dD"}
+	if opts.Target != "" && strings.Contains(filepath.Base(cc), "clang") {
+		args = append(args, "--target="+opts.Target)
+	}
+	args = append(append(args, opts.Flags...), "-x", "c", "-")
+
+	cmd := exec.Command(cc, args...)
+	cmd.Stdin = strings.NewReader(include)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to preprocess %s: %s\n%s", header, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,442 @@
+package bindgen
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// valueKind is the kind of a constant
+type valueKind int
+
+const (
+	valueInt valueKind = iota
+	valueFloat
+	valueString
+)
+
+// value is the value of a constant expression
+type value struct {
+	kind valueKind
+	i    int64
+	f    float64
+	s    string
+}
+
+func (v value) float() float64 {
+	if v.kind == valueFloat {
+		return v.f
+	}
+	return float64(v.i)
+}
+
+func (v value) truth() bool {
+	if v.kind == valueFloat {
+		return v.f != 0
+	}
+	return v.i != 0
+}
+
+func boolValue(b bool) value {
+	if b {
+		return value{kind: valueInt, i: 1}
+	}
+	return value{kind: valueInt}
+}
+
+// binaryPrecedence is the precedence of the binary operators of C
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"|":  3,
+	"^":  4,
+	"&":  5,
+	"==": 6, "!=": 6,
+	"<": 7, ">": 7, "<=": 7, ">=": 7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+}
+
+// evalMacro returns the value of a macro that is a constant expression
+func (p *parser) evalMacro(m *macro) (value, error) {
+	if p.expanding[m.name] {
+		return value{}, p.errorf("macro %s expands to itself", m.name)
+	}
+	p.expanding[m.name] = true
+	defer delete(p.expanding, m.name)
+
+	sub := *p
+	sub.toks = append(append([]token(nil), m.tokens...), token{kind: tokEOF, file: m.file})
+	sub.pos = 0
+	if sub.peek().kind == tokEOF {
+		return value{}, sub.errorf("macro %s is empty", m.name)
+	}
+	v, err := sub.constExpr()
+	if err != nil {
+		return value{}, err
+	}
+	if sub.peek().kind != tokEOF {
+		return value{}, sub.errorf("macro %s isn't a constant expression", m.name)
+	}
+	return v, nil
+}
+
+// constExpr reads and evaluates a constant expression
+func (p *parser) constExpr() (value, error) {
+	cond, err := p.binary(1)
+	if err != nil || !p.is("?") {
+		return cond, err
+	}
+	p.next()
+	then, err := p.constExpr()
+	if err != nil {
+		return value{}, err
+	}
+	if err := p.expect(":"); err != nil {
+		return value{}, err
+	}
+	otherwise, err := p.constExpr()
+	if err != nil {
+		return value{}, err
+	}
+	if cond.truth() {
+		return then, nil
+	}
+	return otherwise, nil
+}
+
+// binary reads binary operators with at least a precedence
+func (p *parser) binary(min int) (value, error) {
+	lhs, err := p.unary()
+	if err != nil {
+		return value{}, err
+	}
+	for {
+		t := p.peek()
+		prec, ok := binaryPrecedence[t.value]
+		if t.kind != tokPunct || !ok || prec < min {
+			return lhs, nil
+		}
+		p.next()
+		rhs, err := p.binary(prec + 1)
+		if err != nil {
+			return value{}, err
+		}
+		if lhs, err = p.apply(t.value, lhs, rhs); err != nil {
+			return value{}, err
+		}
+	}
+}
+
+// apply applies a binary operator to two constants
+func (p *parser) apply(op string, lhs value, rhs value) (value, error) {
+	if lhs.kind == valueString || rhs.kind == valueString {
+		return value{}, p.errorf("invalid operation on a string")
+	}
+	if lhs.kind == valueFloat || rhs.kind == valueFloat {
+		a, b := lhs.float(), rhs.float()
+		switch op {
+		case "+":
+			return value{kind: valueFloat, f: a + b}, nil
+		case "-":
+			return value{kind: valueFloat, f: a - b}, nil
+		case "*":
+			return value{kind: valueFloat, f: a * b}, nil
+		case "/":
+			return value{kind: valueFloat, f: a / b}, nil
+		case "<":
+			return boolValue(a < b), nil
+		case ">":
+			return boolValue(a > b), nil
+		case "<=":
+			return boolValue(a <= b), nil
+		case ">=":
+			return boolValue(a >= b), nil
+		case "==":
+			return boolValue(a == b), nil
+		case "!=":
+			return boolValue(a != b), nil
+		case "&&":
+			return boolValue(a != 0 && b != 0), nil
+		case "||":
+			return boolValue(a != 0 || b != 0), nil
+		}
+		return value{}, p.errorf("invalid operation %s on a float", op)
+	}
+
+	a, b := lhs.i, rhs.i
+	var n int64
+	switch op {
+	case "+":
+		n = a + b
+	case "-":
+		n = a - b
+	case "*":
+		n = a * b
+	case "/", "%":
+		if b == 0 {
+			return value{}, p.errorf("division by zero")
+		}
+		if op == "/" {
+			n = a / b
+		} else {
+			n = a % b
+		}
+	case "<<":
+		n = a << uint64(b)
+	case ">>":
+		n = a >> uint64(b)
+	case "&":
+		n = a & b
+	case "|":
+		n = a | b
+	case "^":
+		n = a ^ b
+	case "<":
+		return boolValue(a < b), nil
+	case ">":
+		return boolValue(a > b), nil
+	case "<=":
+		return boolValue(a <= b), nil
+	case ">=":
+		return boolValue(a >= b), nil
+	case "==":
+		return boolValue(a == b), nil
+	case "!=":
+		return boolValue(a != b), nil
+	case "&&":
+		return boolValue(a != 0 && b != 0), nil
+	case "||":
+		return boolValue(a != 0 || b != 0), nil
+	}
+	return value{kind: valueInt, i: n}, nil
+}
+
+// unary reads unary operators, casts and sizeof
+func (p *parser) unary() (value, error) {
+	switch {
+	case p.is("-", "+", "~", "!"):
+		op := p.next().value
+		v, err := p.unary()
+		if err != nil {
+			return value{}, err
+		}
+		if v.kind == valueString {
+			return value{}, p.errorf("invalid operation on a string")
+		}
+		switch {
+		case op == "!":
+			return boolValue(!v.truth()), nil
+		case op == "+":
+			return v, nil
+		case op == "-" && v.kind == valueFloat:
+			return value{kind: valueFloat, f: -v.f}, nil
+		case op == "-":
+			return value{kind: valueInt, i: -v.i}, nil
+		case v.kind == valueFloat:
+			return value{}, p.errorf("invalid operation ~ on a float")
+		}
+		return value{kind: valueInt, i: ^v.i}, nil
+	case p.is("sizeof", "_Alignof", "__alignof__", "alignof"):
+		op := p.next().value
+		var t ctype
+		if p.is("(") && p.startsType(p.peekAt(1)) {
+			p.next()
+			var err error
+			if t, err = p.typeName(); err != nil {
+				return value{}, err
+			}
+			if err := p.expect(")"); err != nil {
+				return value{}, err
+			}
+		} else {
+			return value{}, p.errorf("sizeof an expression isn't supported")
+		}
+		size, align, err := sizeAlign(t)
+		if err != nil {
+			return value{}, p.errorf("%s", err)
+		}
+		if op == "sizeof" {
+			return value{kind: valueInt, i: int64(size)}, nil
+		}
+		return value{kind: valueInt, i: int64(align)}, nil
+	case p.is("(") && p.startsType(p.peekAt(1)):
+		p.next()
+		t, err := p.typeName()
+		if err != nil {
+			return value{}, err
+		}
+		if err := p.expect(")"); err != nil {
+			return value{}, err
+		}
+		v, err := p.unary()
+		if err != nil {
+			return value{}, err
+		}
+		return p.cast(t, v)
+	}
+	return p.primary()
+}
+
+// cast converts a constant to a type
+func (p *parser) cast(t ctype, v value) (value, error) {
+	switch t := resolve(t).(type) {
+	case *enum:
+		return p.cast(&prim{primInt}, v)
+	case *prim:
+		switch t.kind {
+		case primFloat, primDouble:
+			return value{kind: valueFloat, f: v.float()}, nil
+		case primBool:
+			return boolValue(v.truth()), nil
+		case primChar, primShort, primInt, primLong, primLongLong:
+			if v.kind == valueString {
+				break
+			}
+			n := v.i
+			if v.kind == valueFloat {
+				n = int64(v.f)
+			}
+			if bits := uint(intBits(t.kind)); bits < 64 {
+				n = n << (64 - bits) >> (64 - bits)
+			}
+			return value{kind: valueInt, i: n}, nil
+		}
+	}
+	return value{}, p.errorf("cast isn't supported in a constant")
+}
+
+// primary reads a literal, a constant or a parenthesized expression
+func (p *parser) primary() (value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		v, ok := parseNumber(t.value)
+		if !ok {
+			return value{}, p.errorf("invalid number %s", t.value)
+		}
+		return v, nil
+	case tokChar:
+		s, ok := unquoteC(strings.TrimLeft(t.value, "LuU8"))
+		if !ok || len(s) == 0 {
+			return value{}, p.errorf("invalid character %s", t.value)
+		}
+		return value{kind: valueInt, i: int64(int8(s[0]))}, nil
+	case tokString:
+		if strings.HasPrefix(t.value, "L") || strings.HasPrefix(t.value, "u") || strings.HasPrefix(t.value, "U") {
+			return value{}, p.errorf("wide strings aren't supported")
+		}
+		buf := &bytes.Buffer{}
+		for {
+			s, ok := unquoteC(t.value)
+			if !ok {
+				return value{}, p.errorf("invalid string %s", t.value)
+			}
+			buf.WriteString(s)
+			// Adjacent strings are joined
+			if p.peek().kind != tokString {
+				break
+			}
+			t = p.next()
+		}
+		return value{kind: valueString, s: buf.String()}, nil
+	case tokIdent:
+		if v, ok := p.constants[t.value]; ok {
+			return v, nil
+		}
+		if m, ok := p.macros[t.value]; ok {
+			return p.evalMacro(m)
+		}
+		return value{}, p.errorf("%s isn't a constant", t.value)
+	case tokPunct:
+		if t.value == "(" {
+			v, err := p.constExpr()
+			if err != nil {
+				return value{}, err
+			}
+			return v, p.expect(")")
+		}
+	}
+	return value{}, p.errorf("unexpected %s in a constant", t)
+}
+
+// parseNumber reads a C integer or floating point literal
+func parseNumber(text string) (value, bool) {
+	lower := strings.ToLower(text)
+	hex := strings.HasPrefix(lower, "0x")
+	isFloat := strings.Contains(lower, ".") || strings.Contains(lower, "p") || !hex && strings.Contains(lower, "e")
+	if isFloat {
+		lower = strings.TrimRight(lower, "fl")
+		f, err := strconv.ParseFloat(lower, 64)
+		return value{kind: valueFloat, f: f}, err == nil
+	}
+	lower = strings.TrimRight(lower, "ul")
+	if len(lower) > 1 && lower[0] == '0' && lower[1] >= '0' && lower[1] <= '9' {
+		lower = "0o" + lower[1:]
+	}
+	n, err := strconv.ParseUint(lower, 0, 64)
+	return value{kind: valueInt, i: int64(n)}, err == nil
+}
+
+// unquoteC returns the text of a C string or character literal
+func unquoteC(lit string) (string, bool) {
+	if len(lit) < 2 {
+		return "", false
+	}
+	body := lit[1 : len(lit)-1]
+	buf := &bytes.Buffer{}
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(body) {
+			return "", false
+		}
+		switch c = body[i]; c {
+		case 'n':
+			buf.WriteByte('\n')
+		case 't':
+			buf.WriteByte('\t')
+		case 'r':
+			buf.WriteByte('\r')
+		case 'a':
+			buf.WriteByte('\a')
+		case 'b':
+			buf.WriteByte('\b')
+		case 'f':
+			buf.WriteByte('\f')
+		case 'v':
+			buf.WriteByte('\v')
+		case 'x':
+			j := i + 1
+			for j < len(body) && strings.IndexByte("0123456789abcdefABCDEF", body[j]) >= 0 {
+				j++
+			}
+			n, err := strconv.ParseUint(body[i+1:j], 16, 8)
+			if err != nil {
+				return "", false
+			}
+			buf.WriteByte(byte(n))
+			i = j - 1
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(body) && j < i+3 && body[j] >= '0' && body[j] <= '7' {
+				j++
+			}
+			n, err := strconv.ParseUint(body[i:j], 8, 8)
+			if err != nil {
+				return "", false
+			}
+			buf.WriteByte(byte(n))
+			i = j - 1
+		default:
+			// \\, \', \" and \?
+			buf.WriteByte(c)
+		}
+	}
+	return buf.String(), true
+}
@@ -0,0 +1,562 @@
+package bindgen
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxFields is the most fields a class is given. Arrays in structs are
+// bound as a field for each element, as classes can't have array fields,
+// so a struct with large arrays is left opaque instead
+const maxFields = 256
+
+// keywords are the words geode doesn't allow as names
+var keywords = map[string]bool{
+	"return": true, "if": true, "else": true, "for": true, "while": true, "func": true,
+	"let": true, "class": true, "include": true, "link": true, "is": true, "info": true,
+	"as": true, "true": true, "false": true, "nil": true, "pure": true, "nomangle": true,
+	"bool": true, "byte": true, "short": true, "int": true, "long": true, "big": true,
+	"large": true, "huge": true, "float": true, "string": true, "void": true,
+}
+
+// generator writes the geode package of the declarations a header has
+type generator struct {
+	p       *parser
+	dir     string // declarations are bound if they come from a header in this directory
+	names   map[string]bool
+	classes map[*record]bool
+	order   []*record // the records that are bound, in the order they are declared
+	layouts map[*record]*layout
+	skipped []string
+}
+
+// layout is the fields of the class a record is bound to, or why it is opaque
+type layout struct {
+	fields [][2]string
+	err    error
+}
+
+// reserved returns if a name is one C reserves for the implementation, like
+// __stream or _IO_FILE
+func reserved(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+// valueName returns if a C name can be used as the name of a geode function,
+// variable or field. Names that start with an upper case letter are types
+func valueName(name string) bool {
+	if name == "" || keywords[name] {
+		return false
+	}
+	first := rune(name[0])
+	return first == '_' || unicode.IsLower(first)
+}
+
+// bound returns if a declaration from a file is bound. Only the header and
+// the ones next to it are, the system headers they include are left out
+func (g *generator) bound(file string) bool {
+	return file == g.dir || strings.HasPrefix(file, g.dir+string(filepath.Separator))
+}
+
+// className returns the name of the class a record is bound to
+func (g *generator) className(r *record) string {
+	if r.name != "" {
+		return r.name
+	}
+	name := r.tag
+	if name == "" || reserved(name) {
+		for _, td := range r.typedefs {
+			if !reserved(td) {
+				name = td
+				break
+			}
+		}
+	}
+	if name == "" && len(r.typedefs) > 0 {
+		name = r.typedefs[0]
+	}
+	r.name = g.uniqueName(name)
+	return r.name
+}
+
+// uniqueName returns a class name made from a C name, which no other class
+// has. Class names have to start with an upper case letter
+func (g *generator) uniqueName(name string) string {
+	name = strings.TrimLeft(name, "_")
+	if name == "" {
+		name = "Anon"
+	}
+	runes := []rune(name)
+	if !unicode.IsLetter(runes[0]) {
+		runes = append([]rune("C"), runes...)
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	name = string(runes)
+	for i := 2; g.names[name]; i++ {
+		name = fmt.Sprintf("%s%d", string(runes), i)
+	}
+	g.names[name] = true
+	return name
+}
+
+// class binds a record to a class, and returns its name
+func (g *generator) class(r *record) string {
+	name := g.className(r)
+	if !g.classes[r] {
+		g.classes[r] = true
+		g.order = append(g.order, r)
+	}
+	return name
+}
+
+// typeName returns the geode type a C type is declared as. It fails for
+// types geode can't pass or store the same way C does
+func (g *generator) typeName(t ctype) (string, error) {
+	switch t := resolve(t).(type) {
+	case *prim:
+		switch t.kind {
+		case primBool:
+			return "bool", nil
+		case primChar, primShort, primInt, primLong, primLongLong, primInt128:
+			return map[int]string{8: "byte", 16: "short", 32: "int", 64: "long", 128: "big"}[intBits(t.kind)], nil
+		case primDouble:
+			return "float", nil
+		case primFloat:
+			return "", fmt.Errorf("geode has no 32 bit float")
+		case primLongDouble:
+			return "", fmt.Errorf("geode has no long double")
+		case primVaList:
+			return "", fmt.Errorf("geode can't pass a va_list")
+		case primVoid:
+			return "void", nil
+		}
+		return "", fmt.Errorf("geode has no equivalent of the type")
+	case *enum:
+		return "int", nil
+	case *pointer:
+		return g.pointerName(t.elem), nil
+	case *record:
+		return g.class(t), nil
+	case *array:
+		return "", fmt.Errorf("arrays can't be passed by value")
+	}
+	return "", fmt.Errorf("functions can't be passed by value")
+}
+
+// pointerName returns the geode type of a pointer to a C type. Pointers to
+// types geode doesn't have, like void or functions, are byte pointers
+func (g *generator) pointerName(elem ctype) string {
+	switch elem := resolve(elem).(type) {
+	case *pointer:
+		return g.pointerName(elem.elem) + "*"
+	case *record:
+		return g.class(elem) + "*"
+	case *prim, *enum:
+		if name, err := g.typeName(elem); err == nil && name != "void" {
+			return name + "*"
+		}
+	}
+	return "byte*"
+}
+
+// layout returns the fields of the class a record is bound to
+func (g *generator) layout(r *record) *layout {
+	if l, ok := g.layouts[r]; ok {
+		return l
+	}
+	fields, err := g.fields(r)
+	l := &layout{fields, err}
+	g.layouts[r] = l
+	return l
+}
+
+// byValue returns the class of a record a field or a union holds, which
+// can't be opaque
+func (g *generator) byValue(t ctype) (string, error) {
+	name, err := g.typeName(t)
+	if err != nil {
+		return "", err
+	}
+	if inner, ok := resolve(t).(*record); ok {
+		if l := g.layout(inner); l.err != nil || !inner.complete {
+			return "", fmt.Errorf("%s is opaque", name)
+		}
+	}
+	return name, nil
+}
+
+// fields returns the fields of the class a record is bound to, or why the
+// record has to be opaque
+func (g *generator) fields(r *record) ([][2]string, error) {
+	if !r.complete {
+		return nil, nil
+	}
+	if r.union {
+		return g.unionFields(r)
+	}
+
+	var fields [][2]string
+	used := make(map[string]bool)
+	for i, f := range r.fields {
+		if f.bitfield {
+			return nil, fmt.Errorf("it has bit fields")
+		}
+		name := fieldName(f.name, i, used)
+		typ, count := f.typ, int64(1)
+		for {
+			arr, ok := resolve(typ).(*array)
+			if !ok {
+				break
+			}
+			if arr.len < 0 {
+				if i != len(r.fields)-1 {
+					return nil, fmt.Errorf("field %s has no length", f.name)
+				}
+				// A flexible array member, which takes no space
+				count = 0
+			}
+			count *= arr.len
+			typ = arr.elem
+		}
+		if count < 0 {
+			count = 0
+		}
+		g.nameAnonymous(r, typ, name)
+		typeName, err := g.byValue(typ)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %s", name, err)
+		}
+		if count == 1 {
+			fields = append(fields, [2]string{typeName, name})
+			continue
+		}
+		if int64(len(fields))+count > maxFields {
+			return nil, fmt.Errorf("it has more than %d fields", maxFields)
+		}
+		for j := int64(0); j < count; j++ {
+			fields = append(fields, [2]string{typeName, fmt.Sprintf("%s_%d", name, j)})
+		}
+	}
+	return fields, nil
+}
+
+// nameAnonymous names the class of an anonymous struct or union a field
+// holds after the field
+func (g *generator) nameAnonymous(r *record, t ctype, field string) {
+	if inner, ok := resolve(t).(*record); ok && inner.name == "" && inner.tag == "" && len(inner.typedefs) == 0 {
+		inner.name = g.uniqueName(r.name + "_" + strings.TrimLeft(field, "_"))
+	}
+}
+
+// valueRecords returns the records a record holds by value, which have to
+// be declared before it
+func valueRecords(r *record) []*record {
+	var records []*record
+	for _, f := range r.fields {
+		t := resolve(f.typ)
+		for {
+			arr, ok := t.(*array)
+			if !ok {
+				break
+			}
+			t = resolve(arr.elem)
+		}
+		if inner, ok := t.(*record); ok {
+			records = append(records, inner)
+		}
+	}
+	return records
+}
+
+// unionFields returns the fields of the class a union is bound to, which
+// are the member with the largest alignment and bytes that pad it to the
+// size of the union
+func (g *generator) unionFields(r *record) ([][2]string, error) {
+	size, align, err := sizeAlign(r)
+	if err != nil {
+		return nil, err
+	}
+	var best field
+	bestSize, bestAlign := 0, 0
+	used := make(map[string]bool)
+	for i, f := range r.fields {
+		g.nameAnonymous(r, f.typ, fieldName(f.name, i, used))
+		if f.bitfield {
+			return nil, fmt.Errorf("it has bit fields")
+		}
+		fsize, falign, err := sizeAlign(f.typ)
+		if err != nil {
+			return nil, err
+		}
+		if _, isArray := resolve(f.typ).(*array); isArray {
+			continue
+		}
+		if _, err := g.byValue(f.typ); err != nil {
+			continue
+		}
+		if falign > bestAlign || falign == bestAlign && fsize > bestSize {
+			best, bestSize, bestAlign = f, fsize, falign
+		}
+	}
+	if bestAlign < align {
+		return nil, fmt.Errorf("its most aligned member can't be bound")
+	}
+
+	typeName, _ := g.typeName(best.typ)
+	fields := [][2]string{{typeName, fieldName(best.name, 0, map[string]bool{})}}
+	if size-bestSize > maxFields {
+		return nil, fmt.Errorf("it has more than %d fields", maxFields)
+	}
+	for i := bestSize; i < size; i++ {
+		fields = append(fields, [2]string{"byte", fmt.Sprintf("pad_%d", i-bestSize)})
+	}
+	return fields, nil
+}
+
+// fieldName returns the name a field is bound as, which has to be a value
+// name that the class doesn't use yet
+func fieldName(name string, index int, used map[string]bool) string {
+	if name == "" {
+		name = fmt.Sprintf("anon%d", index)
+	}
+	if !valueName(name) {
+		runes := []rune(name)
+		runes[0] = unicode.ToLower(runes[0])
+		name = string(runes)
+	}
+	for !valueName(name) || used[name] {
+		name += "_"
+	}
+	used[name] = true
+	return name
+}
+
+// constName returns the name a constant is bound as. Constants are usually
+// in upper case, which geode uses for types, so they are lower cased
+func constName(name string) string {
+	if strings.ToUpper(name) == name {
+		return strings.ToLower(name)
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// literal returns a constant as a geode literal, and its type
+func literal(v value) (string, string, bool) {
+	switch v.kind {
+	case valueInt:
+		if v.i >= math.MinInt32 && v.i <= math.MaxInt32 {
+			return strconv.FormatInt(v.i, 10), "int", true
+		}
+		return strconv.FormatInt(v.i, 10), "long", true
+	case valueFloat:
+		if math.IsInf(v.f, 0) || math.IsNaN(v.f) {
+			return "", "", false
+		}
+		s := strconv.FormatFloat(v.f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s, "float", true
+	case valueString:
+		buf := &bytes.Buffer{}
+		buf.WriteByte('"')
+		for _, c := range []byte(v.s) {
+			switch {
+			case c == '"' || c == '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case c == '\n':
+				buf.WriteString("\\n")
+			case c == '\t':
+				buf.WriteString("\\t")
+			case c < ' ' || c > '~':
+				return "", "", false
+			default:
+				buf.WriteByte(c)
+			}
+		}
+		buf.WriteByte('"')
+		return buf.String(), "string", true
+	}
+	return "", "", false
+}
+
+// function returns the declaration of an external function
+func (g *generator) function(d decl) (string, error) {
+	fn := d.typ.(*function)
+	var params []string
+	used := make(map[string]bool)
+	for i, param := range fn.params {
+		if _, byValue := resolve(param.typ).(*record); byValue {
+			return "", fmt.Errorf("it takes a struct by value")
+		}
+		typ, err := g.typeName(param.typ)
+		if err != nil {
+			return "", err
+		}
+		name := strings.TrimLeft(param.name, "_")
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		params = append(params, fmt.Sprintf("%s %s", typ, fieldName(name, i, used)))
+	}
+	if fn.variadic {
+		params = append(params, "...")
+	}
+
+	decl := fmt.Sprintf("func %s(%s)", d.name, strings.Join(params, ", "))
+	if _, byValue := resolve(fn.ret).(*record); byValue {
+		return "", fmt.Errorf("it returns a struct by value")
+	}
+	ret, err := g.typeName(fn.ret)
+	if err != nil {
+		return "", err
+	}
+	if ret != "void" {
+		decl += " " + ret
+	}
+	return decl + " ...", nil
+}
+
+// generate writes the package
+func (g *generator) generate(pkg string, header string) string {
+	p := g.p
+	buf := &bytes.Buffer{}
+	values := make(map[string]bool)
+
+	var funcs []string
+	for _, d := range p.funcs {
+		if !g.bound(d.file) || reserved(d.name) || values[d.name] {
+			continue
+		}
+		// Functions can be declared more than once
+		values[d.name] = true
+		if !valueName(d.name) {
+			g.skip(d.name, "its name isn't a valid geode function name")
+			continue
+		}
+		decl, err := g.function(d)
+		if err != nil {
+			g.skip(d.name, err.Error())
+			continue
+		}
+		funcs = append(funcs, decl)
+	}
+
+	var vars []string
+	for _, d := range p.vars {
+		if !g.bound(d.file) || reserved(d.name) || values[d.name] {
+			continue
+		}
+		values[d.name] = true
+		if !valueName(d.name) {
+			g.skip(d.name, "its name isn't a valid geode variable name")
+			continue
+		}
+		typ, err := g.typeName(d.typ)
+		if err != nil {
+			g.skip(d.name, err.Error())
+			continue
+		}
+		vars = append(vars, fmt.Sprintf("%s %s ...", typ, d.name))
+	}
+
+	var consts []string
+	bindConst := func(c constant) {
+		if !g.bound(c.file) || reserved(c.name) {
+			return
+		}
+		name := constName(c.name)
+		if !valueName(name) || values[name] {
+			return
+		}
+		lit, typ, ok := literal(c.value)
+		if !ok {
+			return
+		}
+		values[name] = true
+		consts = append(consts, fmt.Sprintf("%s %s = %s", typ, name, lit))
+	}
+	for _, c := range p.consts {
+		bindConst(c)
+	}
+	for _, name := range p.macroOrder {
+		m, ok := p.macros[name]
+		if !ok || !g.bound(m.file) || reserved(name) {
+			continue
+		}
+		v, err := p.evalMacro(m)
+		if err != nil {
+			continue
+		}
+		bindConst(constant{name, v, m.file})
+	}
+
+	// The structs the header declares are bound even if nothing uses them
+	for _, r := range p.records {
+		if g.bound(r.file) && r.complete && (r.tag != "" && !reserved(r.tag) || len(r.typedefs) > 0 && !reserved(r.typedefs[0])) {
+			g.class(r)
+		}
+	}
+
+	fmt.Fprintf(buf, "# Code generated by geode bindgen from %s. DO NOT EDIT.\n\n", header)
+	fmt.Fprintf(buf, "is %s\n", pkg)
+
+	// Laying out a class can bind the ones its fields point to, which are
+	// added to the end of the order
+	for i := 0; i < len(g.order); i++ {
+		g.layout(g.order[i])
+	}
+	written := make(map[*record]bool)
+	var write func(r *record)
+	write = func(r *record) {
+		if written[r] || !g.classes[r] {
+			return
+		}
+		written[r] = true
+		for _, inner := range valueRecords(r) {
+			write(inner)
+		}
+		l := g.layout(r)
+		if l.err != nil {
+			fmt.Fprintf(buf, "\n# %s is opaque, as %s\n", r.name, l.err)
+		} else {
+			fmt.Fprintf(buf, "\n")
+		}
+		if len(l.fields) == 0 {
+			fmt.Fprintf(buf, "class %s {}\n", r.name)
+			return
+		}
+		fmt.Fprintf(buf, "class %s {\n", r.name)
+		for _, f := range l.fields {
+			fmt.Fprintf(buf, "\t%s %s\n", f[0], f[1])
+		}
+		fmt.Fprintf(buf, "}\n")
+	}
+	for _, r := range g.order {
+		write(r)
+	}
+
+	for _, section := range [][]string{consts, vars, funcs} {
+		if len(section) == 0 {
+			continue
+		}
+		buf.WriteString("\n")
+		for _, line := range section {
+			fmt.Fprintf(buf, "%s\n", line)
+		}
+	}
+	return buf.String()
+}
+
+// skip records that a declaration isn't bound
+func (g *generator) skip(name string, reason string) {
+	g.skipped = append(g.skipped, fmt.Sprintf("%s: %s", name, reason))
+}
@@ -0,0 +1,233 @@
+package bindgen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenKind is the kind of a C token
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokChar
+	tokPunct
+)
+
+// token is a C token of the preprocessed header, with the file it came from
+type token struct {
+	kind  tokenKind
+	value string
+	file  string
+	line  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of file"
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+// macro is an object like #define the header made
+type macro struct {
+	name   string
+	tokens []token
+	file   string
+}
+
+// punctuators are the C punctuators, longest first so they are matched greedily
+var punctuators = []string{
+	"...", "<<=", ">>=",
+	"->", "++", "--", "<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"*=", "/=", "%=", "+=", "-=", "&=", "^=", "|=", "##",
+}
+
+// lexer turns the output of the preprocessor into tokens. The output has
+// line markers that say which file the lines after them come from, and the
+// #define and #undef lines it was asked to keep
+type lexer struct {
+	tokens []token
+	macros map[string]*macro
+	order  []string // the names of the macros in the order they were defined
+	main   string   // the header the input includes
+	file   string
+	line   int
+}
+
+// lex reads the output of the preprocessor
+func lex(src string) (*lexer, error) {
+	l := &lexer{macros: make(map[string]*macro)}
+	for _, text := range strings.Split(src, "\n") {
+		l.line++
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "#") {
+			if err := l.directive(strings.TrimSpace(trimmed[1:])); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		toks, err := l.lexLine(text)
+		if err != nil {
+			return nil, err
+		}
+		l.tokens = append(l.tokens, toks...)
+	}
+	l.tokens = append(l.tokens, token{kind: tokEOF, file: l.file, line: l.line})
+	return l, nil
+}
+
+// directive handles a line marker or a directive the preprocessor kept
+func (l *lexer) directive(text string) error {
+	switch {
+	case text == "":
+		return nil
+	case text[0] >= '0' && text[0] <= '9':
+		// A line marker, like # 1 "/usr/include/stdio.h" 1 3 4, where
+		// the flag 1 means the file was just included
+		fields := strings.SplitN(text, " ", 2)
+		n, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid line marker %q", text)
+		}
+		if len(fields) > 1 && strings.HasPrefix(fields[1], "\"") {
+			end, err := quoted(fields[1], 0)
+			if err != nil {
+				return fmt.Errorf("invalid line marker %q", text)
+			}
+			name, err := strconv.Unquote(fields[1][:end])
+			if err != nil {
+				return fmt.Errorf("invalid line marker %q", text)
+			}
+			flags := strings.Fields(fields[1][end:])
+			// The first file the input includes is the header being bound
+			if l.main == "" && l.file == "<stdin>" && len(flags) > 0 && flags[0] == "1" {
+				l.main = name
+			}
+			l.file = name
+		}
+		// The line after the marker is the one it names
+		l.line = n - 1
+	case strings.HasPrefix(text, "define "):
+		rest := strings.TrimSpace(text[len("define "):])
+		end := 0
+		for end < len(rest) && isIdentRune(rest[end], end == 0) {
+			end++
+		}
+		name := rest[:end]
+		// Function like macros are left out, only constants are bound
+		if name == "" || end < len(rest) && rest[end] == '(' {
+			return nil
+		}
+		toks, err := l.lexLine(rest[end:])
+		if err != nil {
+			// A macro can hold text that isn't valid C on its own
+			return nil
+		}
+		if _, ok := l.macros[name]; !ok {
+			l.order = append(l.order, name)
+		}
+		l.macros[name] = &macro{name: name, tokens: toks, file: l.file}
+	case strings.HasPrefix(text, "undef "):
+		delete(l.macros, strings.TrimSpace(text[len("undef "):]))
+	}
+	return nil
+}
+
+func isIdentRune(c byte, first bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || !first && c >= '0' && c <= '9'
+}
+
+// lexLine splits a line into tokens
+func (l *lexer) lexLine(text string) ([]token, error) {
+	var toks []token
+	emit := func(kind tokenKind, value string) {
+		toks = append(toks, token{kind: kind, value: value, file: l.file, line: l.line})
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case isIdentRune(c, true):
+			start := i
+			for i < len(text) && isIdentRune(text[i], false) {
+				i++
+			}
+			// Prefixed literals, like L"wide" or u8"text"
+			if i < len(text) && (text[i] == '"' || text[i] == '\'') {
+				switch text[start:i] {
+				case "L", "u", "U", "u8":
+					end, err := quoted(text, i)
+					if err != nil {
+						return nil, err
+					}
+					kind := tokString
+					if text[i] == '\'' {
+						kind = tokChar
+					}
+					emit(kind, text[start:end])
+					i = end
+					continue
+				}
+			}
+			emit(tokIdent, text[start:i])
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(text) && text[i+1] >= '0' && text[i+1] <= '9':
+			// A preprocessing number, which takes in suffixes and exponents
+			start := i
+			for i < len(text) {
+				d := text[i]
+				if (d == '+' || d == '-') && (text[i-1] == 'e' || text[i-1] == 'E' || text[i-1] == 'p' || text[i-1] == 'P') {
+					i++
+					continue
+				}
+				if !isIdentRune(d, false) && d != '.' {
+					break
+				}
+				i++
+			}
+			emit(tokNumber, text[start:i])
+		case c == '"' || c == '\'':
+			end, err := quoted(text, i)
+			if err != nil {
+				return nil, err
+			}
+			kind := tokString
+			if c == '\'' {
+				kind = tokChar
+			}
+			emit(kind, text[i:end])
+			i = end
+		default:
+			punct := string(c)
+			for _, p := range punctuators {
+				if strings.HasPrefix(text[i:], p) {
+					punct = p
+					break
+				}
+			}
+			emit(tokPunct, punct)
+			i += len(punct)
+		}
+	}
+	return toks, nil
+}
+
+// quoted returns the end of the string or character literal at start
+func quoted(text string, start int) (int, error) {
+	quote := text[start]
+	for i := start + 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated literal %s", text[start:])
+}
@@ -0,0 +1,756 @@
+package bindgen
+
+import (
+	"fmt"
+)
+
+// decl is a function or variable the header declares
+type decl struct {
+	name string
+	typ  ctype
+	file string
+}
+
+// constant is an enum constant or a macro the header defines
+type constant struct {
+	name  string
+	value value
+	file  string
+}
+
+// parser reads the declarations of a preprocessed header. Declarations it
+// can't read are skipped, and the reason is kept as a warning
+type parser struct {
+	toks []token
+	pos  int
+
+	typedefs  map[string]*typedef
+	tags      map[string]*record
+	enums     map[string]*enum
+	constants map[string]value
+	macros    map[string]*macro
+	// macroOrder is the names of the macros in the order they were defined
+	macroOrder []string
+	expanding  map[string]bool // the macros being evaluated, to stop recursive ones
+
+	records  []*record
+	funcs    []decl
+	vars     []decl
+	consts   []constant
+	warnings []string
+}
+
+func newParser(l *lexer) *parser {
+	return &parser{
+		toks:       l.tokens,
+		typedefs:   make(map[string]*typedef),
+		tags:       make(map[string]*record),
+		enums:      make(map[string]*enum),
+		constants:  make(map[string]value),
+		macros:     l.macros,
+		macroOrder: l.order,
+		expanding:  make(map[string]bool),
+	}
+}
+
+func (p *parser) peek() token {
+	return p.toks[p.pos]
+}
+
+func (p *parser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.toks) {
+		return p.toks[len(p.toks)-1]
+	}
+	return p.toks[p.pos+offset]
+}
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is returns if the next token is one of the punctuators or keywords
+func (p *parser) is(values ...string) bool {
+	t := p.peek()
+	if t.kind != tokPunct && t.kind != tokIdent {
+		return false
+	}
+	for _, v := range values {
+		if t.value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(value string) error {
+	if !p.is(value) {
+		return p.errorf("expected %q, found %s", value, p.peek())
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	return fmt.Errorf("%s:%d: %s", t.file, t.line, fmt.Sprintf(format, args...))
+}
+
+// skipBalanced skips a parenthesized, bracketed or braced group of tokens
+func (p *parser) skipBalanced() error {
+	closing := map[string]string{"(": ")", "[": "]", "{": "}"}
+	var stack []string
+	for {
+		t := p.next()
+		if t.kind == tokEOF {
+			return p.errorf("unbalanced %q", stack[0])
+		}
+		if t.kind != tokPunct {
+			continue
+		}
+		if c, ok := closing[t.value]; ok {
+			stack = append(stack, c)
+		} else if len(stack) > 0 && t.value == stack[len(stack)-1] {
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// skipAttributes skips the gcc attributes and asm labels that can follow
+// most parts of a declaration
+func (p *parser) skipAttributes() error {
+	for {
+		switch {
+		case p.is("__attribute__", "__attribute", "__declspec", "__asm__", "__asm", "asm", "_Alignas", "alignas"):
+			p.next()
+			if p.is("__volatile__", "volatile") {
+				p.next()
+			}
+			if p.is("(") {
+				if err := p.skipBalanced(); err != nil {
+					return err
+				}
+			}
+		case p.is("[") && p.peekAt(1).value == "[":
+			// C23 attributes, like [[nodiscard]]
+			if err := p.skipBalanced(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// skipDecl skips the rest of a declaration that couldn't be read
+func (p *parser) skipDecl() {
+	for {
+		switch {
+		case p.peek().kind == tokEOF:
+			return
+		case p.is(";"):
+			p.next()
+			return
+		case p.is("(", "[", "{"):
+			brace := p.is("{")
+			if p.skipBalanced() != nil {
+				return
+			}
+			// A function body or a struct at the top level ends it, unless
+			// the declaration goes on after it
+			if brace && !p.is(";", ",", "__attribute__") && p.peek().kind != tokIdent {
+				return
+			}
+		default:
+			p.next()
+		}
+	}
+}
+
+// parse reads every declaration of the header
+func (p *parser) parse() {
+	for p.peek().kind != tokEOF {
+		start := p.peek()
+		if err := p.externalDecl(); err != nil {
+			p.warnings = append(p.warnings, err.Error())
+			if p.peek() == start {
+				p.next()
+			}
+			p.skipDecl()
+		}
+	}
+}
+
+// specs are the specifiers a declaration starts with
+type specs struct {
+	typedef bool
+	extern  bool
+	static  bool
+	typ     ctype
+}
+
+// externalDecl reads a declaration at the top level of the header
+func (p *parser) externalDecl() error {
+	switch {
+	case p.is(";"):
+		p.next()
+		return nil
+	case p.is("_Static_assert", "static_assert", "__asm__", "__asm", "asm"):
+		p.next()
+		if err := p.skipBalanced(); err != nil {
+			return err
+		}
+		return p.expect(";")
+	}
+
+	s, err := p.declSpecs()
+	if err != nil {
+		return err
+	}
+	if p.is(";") {
+		p.next()
+		return nil
+	}
+
+	for {
+		nameTok := p.peek()
+		name, build, err := p.declarator()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return p.errorf("expected a name in declaration")
+		}
+		typ := build(s.typ)
+		if err := p.skipAttributes(); err != nil {
+			return err
+		}
+
+		switch t := typ.(type) {
+		case *function:
+			if s.typedef {
+				break
+			}
+			if !s.static {
+				p.funcs = append(p.funcs, decl{name, t, nameTok.file})
+			}
+			// A function definition, like an inline function in the header
+			if p.is("{") {
+				return p.skipBalanced()
+			}
+		}
+		// Typedefs of typedefs of a record can name it too
+		if r, ok := resolve(typ).(*record); ok && s.typedef {
+			r.typedefs = append(r.typedefs, name)
+		}
+		if s.typedef {
+			p.typedefs[name] = &typedef{name, typ}
+		} else if _, ok := typ.(*function); !ok && !s.static {
+			p.vars = append(p.vars, decl{name, typ, nameTok.file})
+		}
+
+		if p.is("=") {
+			// The initializer of a variable defined in the header
+			p.next()
+			for !p.is(",", ";") && p.peek().kind != tokEOF {
+				if p.is("(", "[", "{") {
+					if err := p.skipBalanced(); err != nil {
+						return err
+					}
+					continue
+				}
+				p.next()
+			}
+		}
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	return p.expect(";")
+}
+
+// declSpecs reads the storage classes, qualifiers and type specifiers a
+// declaration starts with
+func (p *parser) declSpecs() (specs, error) {
+	var s specs
+	var (
+		seen                                bool
+		void, boolean, char, short, integer bool
+		longs, floats, doubles, imaginary   int
+		signed, int128, valist, other       bool
+		single, double                      bool
+	)
+	for {
+		if err := p.skipAttributes(); err != nil {
+			return s, err
+		}
+		t := p.peek()
+		if t.kind != tokIdent {
+			break
+		}
+		switch t.value {
+		case "typedef":
+			s.typedef = true
+		case "extern":
+			s.extern = true
+		case "static":
+			s.static = true
+		case "auto", "register", "_Thread_local", "thread_local", "__thread",
+			"inline", "__inline", "__inline__", "_Noreturn", "noreturn", "__extension__",
+			"const", "__const", "__const__", "volatile", "__volatile", "__volatile__",
+			"restrict", "__restrict", "__restrict__",
+			"_Nonnull", "_Nullable", "_Null_unspecified", "__nonnull":
+		case "void":
+			void = true
+		case "_Bool", "bool":
+			boolean = true
+		case "char":
+			char = true
+		case "short":
+			short = true
+		case "int":
+			integer = true
+		case "long":
+			longs++
+		case "float":
+			floats++
+		case "double":
+			doubles++
+		case "signed", "__signed", "__signed__", "unsigned":
+			signed = true
+		case "_Complex", "__complex__", "_Imaginary":
+			imaginary++
+		case "__int128", "__int128_t", "__uint128_t":
+			int128 = true
+		case "__builtin_va_list":
+			valist = true
+		case "_Float32":
+			single = true
+		case "_Float64", "_Float32x":
+			double = true
+		case "_Float16", "_Float128", "_Float64x", "_Float128x", "__float128", "__float80", "__fp16", "__bf16",
+			"_Decimal32", "_Decimal64", "_Decimal128":
+			other = true
+		case "__typeof__", "__typeof", "typeof", "_Atomic":
+			p.next()
+			if t.value == "_Atomic" && !p.is("(") {
+				// _Atomic as a qualifier
+				continue
+			}
+			if err := p.skipBalanced(); err != nil {
+				return s, err
+			}
+			other, seen = true, true
+			continue
+		case "struct", "union":
+			r, err := p.recordSpec()
+			if err != nil {
+				return s, err
+			}
+			s.typ, seen = r, true
+			continue
+		case "enum":
+			e, err := p.enumSpec()
+			if err != nil {
+				return s, err
+			}
+			s.typ, seen = e, true
+			continue
+		default:
+			td, ok := p.typedefs[t.value]
+			if !ok || seen {
+				goto done
+			}
+			s.typ, seen = td, true
+			p.next()
+			continue
+		}
+		switch t.value {
+		case "void", "_Bool", "bool", "char", "short", "int", "long", "float", "double",
+			"signed", "__signed", "__signed__", "unsigned", "_Complex", "__complex__", "_Imaginary",
+			"__int128", "__int128_t", "__uint128_t", "__builtin_va_list",
+			"_Float32", "_Float64", "_Float32x", "_Float16", "_Float128", "_Float64x", "_Float128x",
+			"__float128", "__float80", "__fp16", "__bf16", "_Decimal32", "_Decimal64", "_Decimal128":
+			seen = true
+		}
+		p.next()
+	}
+done:
+	if s.typ != nil {
+		return s, nil
+	}
+
+	kind := primInt
+	switch {
+	case imaginary > 0 || other:
+		kind = primOther
+	case void:
+		kind = primVoid
+	case boolean:
+		kind = primBool
+	case char:
+		kind = primChar
+	case short:
+		kind = primShort
+	case int128:
+		kind = primInt128
+	case valist:
+		kind = primVaList
+	case single || floats > 0:
+		kind = primFloat
+	case double:
+		kind = primDouble
+	case doubles > 0 && longs > 0:
+		kind = primLongDouble
+	case doubles > 0:
+		kind = primDouble
+	case longs > 1:
+		kind = primLongLong
+	case longs == 1:
+		kind = primLong
+	case !seen && !signed && !integer && !s.typedef && !s.extern && !s.static:
+		return s, p.errorf("expected a declaration, found %s", p.peek())
+	}
+	s.typ = &prim{kind}
+	return s, nil
+}
+
+// recordSpec reads a struct or union specifier, and its fields if it has them
+func (p *parser) recordSpec() (*record, error) {
+	union := p.next().value == "union"
+	if err := p.skipAttributes(); err != nil {
+		return nil, err
+	}
+	var r *record
+	if p.peek().kind == tokIdent {
+		tag := p.next().value
+		key := "struct " + tag
+		if union {
+			key = "union " + tag
+		}
+		r = p.tags[key]
+		if r == nil {
+			r = &record{tag: tag, union: union, file: p.peek().file}
+			p.tags[key] = r
+			p.records = append(p.records, r)
+		}
+	} else {
+		r = &record{union: union, file: p.peek().file}
+		p.records = append(p.records, r)
+	}
+	if err := p.skipAttributes(); err != nil {
+		return nil, err
+	}
+	if !p.is("{") {
+		return r, nil
+	}
+
+	p.next()
+	r.fields = nil
+	r.file = p.peek().file
+	for !p.is("}") {
+		if p.peek().kind == tokEOF {
+			return nil, p.errorf("unterminated struct")
+		}
+		if p.is(";") {
+			p.next()
+			continue
+		}
+		if p.is("_Static_assert", "static_assert") {
+			p.next()
+			if err := p.skipBalanced(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		s, err := p.declSpecs()
+		if err != nil {
+			return nil, err
+		}
+		if p.is(";") {
+			// An anonymous struct or union member
+			r.fields = append(r.fields, field{typ: s.typ})
+			p.next()
+			continue
+		}
+		for {
+			var name string
+			typ := s.typ
+			if !p.is(":") {
+				var build func(ctype) ctype
+				name, build, err = p.declarator()
+				if err != nil {
+					return nil, err
+				}
+				typ = build(s.typ)
+			}
+			f := field{name: name, typ: typ}
+			if p.is(":") {
+				p.next()
+				if _, err := p.constExpr(); err != nil {
+					return nil, err
+				}
+				f.bitfield = true
+			}
+			if err := p.skipAttributes(); err != nil {
+				return nil, err
+			}
+			r.fields = append(r.fields, f)
+			if !p.is(",") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(";"); err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	r.complete = true
+	return r, p.skipAttributes()
+}
+
+// enumSpec reads an enum specifier, and defines the constants it has
+func (p *parser) enumSpec() (*enum, error) {
+	p.next()
+	if err := p.skipAttributes(); err != nil {
+		return nil, err
+	}
+	e := &enum{}
+	if p.peek().kind == tokIdent {
+		e.tag = p.next().value
+		if found, ok := p.enums[e.tag]; ok {
+			e = found
+		} else {
+			p.enums[e.tag] = e
+		}
+	}
+	if p.is(":") {
+		// An enum with a fixed underlying type
+		p.next()
+		if _, err := p.declSpecs(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.is("{") {
+		return e, nil
+	}
+	p.next()
+
+	following := value{kind: valueInt}
+	for !p.is("}") {
+		t := p.next()
+		if t.kind != tokIdent {
+			return nil, p.errorf("expected an enum constant, found %s", t)
+		}
+		if err := p.skipAttributes(); err != nil {
+			return nil, err
+		}
+		v := following
+		if p.is("=") {
+			p.next()
+			var err error
+			if v, err = p.constExpr(); err != nil {
+				return nil, err
+			}
+			if v.kind != valueInt {
+				return nil, p.errorf("the value of enum constant %s isn't an integer", t.value)
+			}
+		}
+		p.constants[t.value] = v
+		p.consts = append(p.consts, constant{t.value, v, t.file})
+		following = value{kind: valueInt, i: v.i + 1}
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return e, p.skipAttributes()
+}
+
+// startsType returns if a token starts a type name, which tells a cast from
+// a parenthesized expression and parameters from a nested declarator
+func (p *parser) startsType(t token) bool {
+	if t.kind != tokIdent {
+		return false
+	}
+	switch t.value {
+	case "void", "_Bool", "bool", "char", "short", "int", "long", "float", "double",
+		"signed", "__signed", "__signed__", "unsigned", "_Complex", "__complex__",
+		"__int128", "__int128_t", "__uint128_t", "__builtin_va_list",
+		"_Float32", "_Float64", "_Float32x", "_Float16", "_Float128", "_Float64x", "_Float128x",
+		"__float128", "__float80", "__fp16", "__bf16",
+		"struct", "union", "enum", "const", "__const", "volatile", "__volatile__",
+		"restrict", "__restrict", "__restrict__", "__typeof__", "typeof", "_Atomic",
+		"__extension__", "register", "__attribute__":
+		return true
+	}
+	_, ok := p.typedefs[t.value]
+	return ok
+}
+
+// nested returns if the parenthesis at the next token starts a nested
+// declarator, like the one of a function pointer, and not parameters
+func (p *parser) nested() bool {
+	next := p.peekAt(1)
+	if next.kind == tokPunct {
+		return next.value == "*" || next.value == "^" || next.value == "("
+	}
+	return next.kind == tokIdent && !p.startsType(next)
+}
+
+// declarator reads a declarator, and returns the name it declares and a
+// function that builds the type it declares from the one its specifiers name.
+// The name is empty for abstract declarators
+func (p *parser) declarator() (string, func(ctype) ctype, error) {
+	pointers := 0
+	for p.is("*", "^") {
+		p.next()
+		for p.is("const", "__const", "volatile", "__volatile__", "restrict", "__restrict", "__restrict__",
+			"_Nonnull", "_Nullable", "_Null_unspecified", "_Atomic") {
+			p.next()
+		}
+		if err := p.skipAttributes(); err != nil {
+			return "", nil, err
+		}
+		pointers++
+	}
+
+	name := ""
+	inner := func(t ctype) ctype { return t }
+	if p.is("(") && p.nested() {
+		p.next()
+		var err error
+		if name, inner, err = p.declarator(); err != nil {
+			return "", nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return "", nil, err
+		}
+	} else if p.peek().kind == tokIdent && !p.startsType(p.peek()) {
+		name = p.next().value
+	}
+
+	var suffixes []func(ctype) ctype
+	for {
+		if err := p.skipAttributes(); err != nil {
+			return "", nil, err
+		}
+		if p.is("[") {
+			p.next()
+			for p.is("static", "const", "__const", "volatile", "restrict", "__restrict", "__restrict__") {
+				p.next()
+			}
+			n := int64(-1)
+			if !p.is("]") {
+				if p.is("*") && p.peekAt(1).value == "]" {
+					p.next()
+				} else {
+					v, err := p.constExpr()
+					if err != nil {
+						return "", nil, err
+					}
+					if v.kind != valueInt {
+						return "", nil, p.errorf("array length isn't an integer")
+					}
+					n = v.i
+				}
+			}
+			if err := p.expect("]"); err != nil {
+				return "", nil, err
+			}
+			suffixes = append(suffixes, func(t ctype) ctype { return &array{t, n} })
+			continue
+		}
+		if p.is("(") {
+			fn, err := p.params()
+			if err != nil {
+				return "", nil, err
+			}
+			suffixes = append(suffixes, func(t ctype) ctype {
+				f := *fn
+				f.ret = t
+				return &f
+			})
+			continue
+		}
+		break
+	}
+
+	build := func(t ctype) ctype {
+		for i := 0; i < pointers; i++ {
+			t = &pointer{t}
+		}
+		for i := len(suffixes) - 1; i >= 0; i-- {
+			t = suffixes[i](t)
+		}
+		return inner(t)
+	}
+	return name, build, nil
+}
+
+// params reads the parameters of a function declarator
+func (p *parser) params() (*function, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	fn := &function{}
+	if p.is("void") && p.peekAt(1).value == ")" {
+		p.next()
+	}
+	for !p.is(")") {
+		if p.is("...") {
+			p.next()
+			fn.variadic = true
+			break
+		}
+		s, err := p.declSpecs()
+		if err != nil {
+			return nil, err
+		}
+		name, build, err := p.declarator()
+		if err != nil {
+			return nil, err
+		}
+		typ := build(s.typ)
+		// Arrays and functions are passed as pointers to them
+		switch t := resolve(typ).(type) {
+		case *array:
+			typ = &pointer{t.elem}
+		case *function:
+			typ = &pointer{t}
+		}
+		fn.params = append(fn.params, param{name, typ})
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return fn, nil
+}
+
+// typeName reads the name of a type, like in a cast or sizeof
+func (p *parser) typeName() (ctype, error) {
+	s, err := p.declSpecs()
+	if err != nil {
+		return nil, err
+	}
+	_, build, err := p.declarator()
+	if err != nil {
+		return nil, err
+	}
+	return build(s.typ), nil
+}
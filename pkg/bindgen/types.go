@@ -0,0 +1,175 @@
+package bindgen
+
+import (
+	"fmt"
+
+	"github.com/geode-lang/geode/pkg/gtypes"
+	"github.com/llir/llvm/ir/types"
+)
+
+// ctype is a C type
+type ctype interface{}
+
+// primKind is the kind of a builtin C type
+type primKind int
+
+const (
+	primVoid primKind = iota
+	primBool
+	primChar
+	primShort
+	primInt
+	primLong
+	primLongLong
+	primInt128
+	primFloat
+	primDouble
+	primLongDouble
+	primVaList
+	// primOther is a type geode can't represent, like _Complex or __float128
+	primOther
+)
+
+// prim is a builtin C type. Signedness doesn't change how geode declares
+// a type, so it isn't kept
+type prim struct {
+	kind primKind
+}
+
+// pointer is a pointer to a C type
+type pointer struct {
+	elem ctype
+}
+
+// array is a C array, whose length is -1 if it isn't given
+type array struct {
+	elem ctype
+	len  int64
+}
+
+// param is a parameter of a C function
+type param struct {
+	name string
+	typ  ctype
+}
+
+// function is the type of a C function
+type function struct {
+	ret      ctype
+	params   []param
+	variadic bool
+}
+
+// field is a member of a C struct or union
+type field struct {
+	name     string
+	typ      ctype
+	bitfield bool
+}
+
+// record is a C struct or union
+type record struct {
+	tag      string
+	union    bool
+	fields   []field
+	complete bool
+	file     string
+	// typedefs are the names of the typedefs for the record, in the order
+	// they were declared
+	typedefs []string
+	name     string // the name of the class the record is bound to
+}
+
+// enum is a C enum, which has the type of an int
+type enum struct {
+	tag string
+}
+
+// typedef is a type named with typedef
+type typedef struct {
+	name string
+	typ  ctype
+}
+
+// resolve returns the type a typedef names
+func resolve(t ctype) ctype {
+	for {
+		td, ok := t.(*typedef)
+		if !ok {
+			return t
+		}
+		t = td.typ
+	}
+}
+
+// intBits returns the size in bits of an integer kind. A long is as large
+// as a pointer, as it is on the unix targets geode builds for
+func intBits(kind primKind) int {
+	switch kind {
+	case primBool, primChar:
+		return 8
+	case primShort:
+		return 16
+	case primInt:
+		return 32
+	case primLong:
+		return gtypes.CurrentLayout.PointerSize * 8
+	case primLongLong:
+		return 64
+	case primInt128:
+		return 128
+	}
+	return 0
+}
+
+// sizeAlign returns the size and alignment in bytes of a C type, as it is
+// laid out on the target being compiled for
+func sizeAlign(t ctype) (int, int, error) {
+	l := gtypes.CurrentLayout
+	switch t := resolve(t).(type) {
+	case *prim:
+		switch t.kind {
+		case primBool, primChar, primShort, primInt, primLong, primLongLong, primInt128:
+			typ := types.NewInt(uint64(intBits(t.kind)))
+			return l.Size(typ), l.Align(typ), nil
+		case primFloat:
+			return l.Size(types.Float), l.Align(types.Float), nil
+		case primDouble:
+			return l.Size(types.Double), l.Align(types.Double), nil
+		}
+		return 0, 0, fmt.Errorf("the size of the type isn't known")
+	case *enum:
+		return 4, 4, nil
+	case *pointer:
+		return l.PointerSize, l.PointerAlign, nil
+	case *array:
+		if t.len < 0 {
+			return 0, 0, fmt.Errorf("the size of an array without a length isn't known")
+		}
+		size, align, err := sizeAlign(t.elem)
+		return size * int(t.len), align, err
+	case *record:
+		if !t.complete {
+			return 0, 0, fmt.Errorf("the size of incomplete struct %s isn't known", t.tag)
+		}
+		size, align := 0, 1
+		for _, f := range t.fields {
+			fsize, falign, err := sizeAlign(f.typ)
+			if err != nil {
+				return 0, 0, err
+			}
+			if falign > align {
+				align = falign
+			}
+			if t.union {
+				if fsize > size {
+					size = fsize
+				}
+				continue
+			}
+			size = (size+falign-1)/falign*falign + fsize
+		}
+		return (size + align - 1) / align * align, align, nil
+	}
+	return 0, 0, fmt.Errorf("the size of the type isn't known")
+}
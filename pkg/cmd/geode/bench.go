@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/ast"
+	"github.com/geode-lang/geode/pkg/util/color"
+)
+
+// RunBenchmarks builds the bench blocks of a package with optimizations
+// into a single binary then runs each of them in its own process. The
+// results are printed in the format benchstat reads, so the output of two
+// runs can be compared. It returns the status the command exits with
+func RunBenchmarks(input string, buildDir string) int {
+	if isDir, _ := ast.PathIsDir(input); !isDir {
+		if _, err := os.Stat(input); err != nil {
+			cwd, _ := os.Getwd()
+			input = ast.ResolveDepPath(cwd, input)
+		}
+	}
+
+	// Benchmarks of unoptimized code don't say much
+	if *arg.Optimize == 0 {
+		*arg.Optimize = 2
+	}
+
+	out := path.Join(buildDir, "bench.test")
+	context := NewContext(input, out)
+	context.Bench = true
+	context.TargetTripple = findTargetTripple()
+	context.Build(buildDir)
+	defer os.Remove(out)
+
+	if len(context.Benches) == 0 {
+		fmt.Println("no benchmarks")
+		return 0
+	}
+
+	dir, _ := filepath.Abs(ast.ReduceToDir(input))
+	fmt.Printf("target: %s\n", context.TargetTripple)
+	fmt.Printf("pkg: %s\n", filepath.Base(dir))
+
+	benchtime := strconv.FormatInt(arg.BenchTime.Nanoseconds(), 10)
+	status := 0
+	for i, bench := range context.Benches {
+		if !benchMatches(bench, *arg.BenchRun) {
+			continue
+		}
+		for c := 0; c < *arg.BenchCount; c++ {
+			code, err := runCommand(os.Stdout, os.Stderr, "", 0, "", out, []string{strconv.Itoa(i), benchtime})
+			if err != nil {
+				fmt.Printf("Error while running benchmark:\n%s\n", err.Error())
+				return 1
+			}
+			if code != 0 {
+				fmt.Printf("%s %s (%s)\n", color.Red("FAIL"), bench.Name, relativePath(bench.Location()))
+				fmt.Printf("Exit status: %d\n", code)
+				status = 1
+				break
+			}
+		}
+	}
+	return status
+}
+
+// benchMatches returns if a benchmark is picked by the --run filter, which
+// can be text in its name or a glob that matches it. The name can be the
+// one in the source or the one it is reported under, with or without the
+// Benchmark prefix
+func benchMatches(bench ast.TestCase, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	short := strings.TrimPrefix(bench.Name, "Benchmark")
+	for _, s := range []string{bench.Source, bench.Name, short} {
+		if strings.Contains(s, filter) {
+			return true
+		}
+		if ok, _ := filepath.Match(filter, s); ok {
+			return true
+		}
+	}
+	return false
+}
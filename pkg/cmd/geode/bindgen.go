@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/bindgen"
+	"github.com/geode-lang/geode/pkg/util/log"
+)
+
+// Bindgen writes the geode package that binds a C header to stdout or the
+// --out file. The declarations that can't be bound are listed with -v. It
+// returns the status the command exits with
+func Bindgen(header string) int {
+	opts := bindgen.Options{
+		Target:  *arg.Target,
+		Flags:   strings.Fields(*arg.BindgenCFlags),
+		Package: *arg.BindgenPackage,
+	}
+	res, err := bindgen.Generate(header, opts)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	for _, w := range res.Warnings {
+		log.Verbose("unable to read %s\n", w)
+	}
+	for _, s := range res.Skipped {
+		log.Verbose("skipped %s\n", s)
+	}
+
+	if *arg.BindgenOut == "" {
+		fmt.Print(res.Source)
+		return 0
+	}
+	if err := ioutil.WriteFile(*arg.BindgenOut, []byte(res.Source), 0644); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/cover"
+)
+
+// CoverReport merges coverage profiles and writes them as a report in the
+// format passed to the command, then prints how much of each file ran.
+// It returns the status the command exits with
+func CoverReport(profiles []string) int {
+	if len(profiles) == 0 {
+		profiles = []string{*arg.CoverProfile}
+	}
+	profile, err := cover.ReadFiles(profiles...)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	out := *arg.CoverReportOutput
+	write := cover.WriteHTML
+	if *arg.CoverReportFormat == "lcov" {
+		write = cover.WriteLCOV
+		if out == "" {
+			out = "lcov.info"
+		}
+	} else if out == "" {
+		out = "coverage.html"
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	defer f.Close()
+	if err := write(f, profile); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	for _, file := range profile.SortedFiles() {
+		covered, total := file.Covered()
+		fmt.Printf("%s\t%s\n", relativePath(file.Name), cover.Percent(covered, total))
+	}
+	covered, total := profile.Covered()
+	fmt.Printf("total:\t%s of statements\n", cover.Percent(covered, total))
+	return 0
+}
+
+// mergeCoverProfiles writes the profiles of several runs of programs
+// built with --cover into one, and removes them
+func mergeCoverProfiles(profiles []string, out string) error {
+	existing := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		if _, err := os.Stat(p); err == nil {
+			existing = append(existing, p)
+		}
+	}
+	profile, err := cover.ReadFiles(existing...)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := profile.Write(f); err != nil {
+		return err
+	}
+	for _, p := range existing {
+		os.Remove(p)
+	}
+	return nil
+}
+
+// coverEnv returns the environment a program built with --cover needs
+// to write its profile to a file
+func coverEnv(profile string) []string {
+	if !*arg.Cover {
+		return nil
+	}
+	return []string{"GEODECOVERPROFILE=" + profile}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/geode-lang/geode/pkg/ast"
+)
+
+// Demangle prints the names given with their mangled symbols demangled, or
+// copies stdin to stdout demangling it a line at a time, so the output of
+// nm, perf or a program's stack trace can be piped through it. It returns
+// the status the command exits with
+func Demangle(names []string) int {
+	if len(names) > 0 {
+		for _, name := range names {
+			fmt.Println(ast.DemangleText(name))
+		}
+		return 0
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	out := bufio.NewWriter(os.Stdout)
+	for {
+		line, err := in.ReadString('\n')
+		out.WriteString(ast.DemangleText(line))
+		// Lines are flushed as they come, so a program's output can be
+		// read while it runs
+		out.Flush()
+		if err == io.EOF {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/ast"
+	"github.com/geode-lang/geode/pkg/doc"
+	"github.com/geode-lang/geode/pkg/util"
+)
+
+// GenerateDocs writes the documentation for the packages named, or for
+// the whole standard library if there are none. Packages are found the
+// same way includes are. It returns the status the command exits with
+func GenerateDocs(packages []string) int {
+	var set *doc.Set
+	var err error
+
+	if len(packages) == 0 {
+		set, err = doc.LoadAll(util.StdLibDir())
+	} else {
+		dirs := make([]string, 0, len(packages))
+		for _, name := range packages {
+			dir := name
+			if isDir, _ := ast.PathIsDir(dir); !isDir {
+				cwd, _ := os.Getwd()
+				dir = ast.ResolveDepPath(cwd, name)
+			}
+			if isDir, _ := ast.PathIsDir(dir); !isDir {
+				fmt.Printf("Unable to find package %q\n", name)
+				return 1
+			}
+			dirs = append(dirs, dir)
+		}
+		set, err = doc.LoadDirs(dirs)
+	}
+
+	if err == nil {
+		err = set.Write(*arg.DocOutput, *arg.DocFormat)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if *arg.DocOutput == "-" {
+		return 0
+	}
+
+	index := "index.html"
+	if *arg.DocFormat == "markdown" {
+		index = "index.md"
+	}
+	index, _ = filepath.Abs(filepath.Join(*arg.DocOutput, index))
+	fmt.Printf("Documented %d packages in %s\n", len(set.Packages), index)
+	return 0
+}
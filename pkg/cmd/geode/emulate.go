@@ -0,0 +1,57 @@
+package main
+
+import (
+	"debug/elf"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"github.com/geode-lang/geode/pkg/util"
+)
+
+// elfArchs are the architectures of the machines geode builds programs
+// for, named the way target triples and qemu's emulators name them
+var elfArchs = map[elf.Machine]string{
+	elf.EM_X86_64:  "x86_64",
+	elf.EM_AARCH64: "aarch64",
+	elf.EM_RISCV:   "riscv64",
+}
+
+// hostArchs are the names of the architectures go runs on in target triples
+var hostArchs = map[string]string{
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"riscv64": "riscv64",
+}
+
+// foreignArch returns the architecture of a program built for another
+// machine than the one geode runs on, or an empty string if it runs here
+func foreignArch(program string) string {
+	f, err := elf.Open(program)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	arch, ok := elfArchs[f.Machine]
+	if !ok || arch == hostArchs[runtime.GOARCH] {
+		return ""
+	}
+	return arch
+}
+
+// emulatorCommand returns the command that runs a program built for another
+// architecture with qemu's user mode emulator of it. The emulator looks for
+// the program's dynamic linker and libraries where debian style systems
+// install the ones cross compilers link with
+func emulatorCommand(arch string, program string, args []string) (string, []string, error) {
+	qemu, err := exec.LookPath("qemu-" + arch)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s is built for %s, install qemu-%s to run it", program, arch, arch)
+	}
+	var res []string
+	if prefix := filepath.Join("/usr", arch+"-linux-gnu"); util.FileExists(prefix) {
+		res = append(res, "-L", prefix)
+	}
+	return qemu, append(append(res, program), args...), nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// errorComment matches the `# ERROR: pattern` comments that negative compile
+// tests write on the lines that must fail to compile. The pattern is a regular
+// expression that an error reported on the same line has to contain
+var errorComment = regexp.MustCompile(`#\s*ERROR:\s*(.*?)\s*$`)
+
+// errorLine matches an error with a position in the compiler's output
+var errorLine = regexp.MustCompile(`(?m)(\S+\.g):(\d+):\d+: error: (.*)$`)
+
+// expectedError is an error that a line of a test must fail to compile with
+type expectedError struct {
+	line    int
+	pattern *regexp.Regexp
+}
+
+// readExpectedErrors finds the `# ERROR:` comments in the source of a test
+func readExpectedErrors(file string) ([]expectedError, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	errors := make([]expectedError, 0)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		m := errorComment.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		pattern, err := regexp.Compile(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid ERROR pattern: %s", file, line, err)
+		}
+		errors = append(errors, expectedError{line, pattern})
+	}
+	return errors, scanner.Err()
+}
+
+// checkErrors describes how the errors the compiler reported for a test differ
+// from the ones its `# ERROR:` comments expect. Every comment needs a matching
+// error on its line, and every error needs a comment that expects it
+func checkErrors(errBuf *bytes.Buffer, job TestJob, output string) {
+	name := filepath.Base(job.sourcefile)
+	matched := make([]bool, len(job.errors))
+
+	for _, m := range errorLine.FindAllStringSubmatch(output, -1) {
+		line, _ := strconv.Atoi(m[2])
+		expected := false
+		for i, e := range job.errors {
+			if m[1] == name && e.line == line && e.pattern.MatchString(m[3]) {
+				matched[i] = true
+				expected = true
+			}
+		}
+		if !expected {
+			fmt.Fprintf(errBuf, "unexpected error: %s\n", m[0])
+		}
+	}
+
+	for i, e := range job.errors {
+		if !matched[i] {
+			fmt.Fprintf(errBuf, "%s:%d: missing error matching %q\n", name, e.line, e.pattern)
+		}
+	}
+}
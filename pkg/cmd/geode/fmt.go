@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/format"
+)
+
+// FormatFiles formats every geode file in the paths given, or in the current
+// directory if there are none. Depending on the flags, the files are either
+// rewritten, listed or diffed. It returns the status the command exits with
+func FormatFiles(paths []string) int {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	files, err := findSourceFiles(paths)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	opts := format.Options{ASCII: *arg.FmtASCII}
+	status := 0
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Println(err)
+			status = 1
+			continue
+		}
+
+		out, err := format.Source(file, src, opts)
+		if err != nil {
+			fmt.Println(err)
+			status = 1
+			continue
+		}
+
+		if bytes.Equal(src, out) {
+			continue
+		}
+
+		if *arg.FmtDiff {
+			fmt.Print(format.Diff(file, src, out))
+		}
+
+		if *arg.FmtCheck {
+			if !*arg.FmtDiff {
+				fmt.Println(file)
+			}
+			status = 1
+			continue
+		}
+
+		if !*arg.FmtDiff {
+			info, err := os.Stat(file)
+			if err == nil {
+				err = ioutil.WriteFile(file, out, info.Mode())
+			}
+			if err != nil {
+				fmt.Println(err)
+				status = 1
+			}
+		}
+	}
+	return status
+}
+
+// findSourceFiles expands directories into the .g files inside of them
+func findSourceFiles(paths []string) ([]string, error) {
+	files := make([]string, 0)
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// Skip hidden directories like .git
+			if info.IsDir() && file != path && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if !info.IsDir() && strings.HasSuffix(file, ".g") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// placeholderPattern matches the placeholders that expected output can use
+// for text that changes between runs, like pointer addresses. `{{*}}` matches
+// anything on a single line and `{{re:...}}` matches a regular expression
+var placeholderPattern = regexp.MustCompile(`\{\{(\*|re:.*?)\}\}`)
+
+// expectedPattern compiles expected output with placeholders into a regular
+// expression that matches the whole of the output
+func expectedPattern(expected string) (*regexp.Regexp, error) {
+	buf := &strings.Builder{}
+	buf.WriteString(`\A`)
+	last := 0
+	for _, m := range placeholderPattern.FindAllStringSubmatchIndex(expected, -1) {
+		buf.WriteString(regexp.QuoteMeta(expected[last:m[0]]))
+		placeholder := expected[m[2]:m[3]]
+		if placeholder == "*" {
+			buf.WriteString(`[^\n]*`)
+		} else {
+			buf.WriteString("(?:" + strings.TrimPrefix(placeholder, "re:") + ")")
+		}
+		last = m[1]
+	}
+	buf.WriteString(regexp.QuoteMeta(expected[last:]))
+	buf.WriteString(`\z`)
+	return regexp.Compile(buf.String())
+}
+
+// outputMatches returns if the output of a test is the one that was expected
+func outputMatches(expected, got string) bool {
+	if expected == got {
+		return true
+	}
+	if !placeholderPattern.MatchString(expected) {
+		return false
+	}
+	re, err := expectedPattern(expected)
+	return err == nil && re.MatchString(got)
+}
+
+// checkOutput describes how some output differs from the expected output, if it does
+func checkOutput(errBuf *bytes.Buffer, name string, expected, got string) {
+	if outputMatches(expected, got) {
+		return
+	}
+
+	fmt.Fprintf(errBuf, "%s:\n", name)
+	fmt.Fprintf(errBuf, "Expected: %q\n", expected)
+	fmt.Fprintf(errBuf, "Got:      %q\n", got)
+	if placeholderPattern.MatchString(expected) {
+		if _, err := expectedPattern(expected); err != nil {
+			fmt.Fprintf(errBuf, "invalid pattern: %s\n", err)
+		}
+	}
+
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffMain(expected, got, false)
+	fmt.Fprintf(errBuf, "diff:\n%s\n", dmp.DiffPrettyText(diffs))
+}
+
+// readExpected reads an expected output file, returning nil if there is none
+func readExpected(file string) (*string, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := string(data)
+	return &s, nil
+}
+
+// update rewrites the expected outputs of a test that don't match what it
+// actually output, and returns if any were changed. Outputs that still match
+// are left alone so the placeholders in them are kept
+func (res *testResult) update() (bool, error) {
+	job := &res.TestJob
+	dir := filepath.Dir(job.sourcefile)
+	changed, configChanged := false, false
+
+	if job.CompilerOutput != nil && !outputMatches(*job.CompilerOutput, res.compilerOutput) {
+		out := res.compilerOutput
+		job.CompilerOutput = &out
+		configChanged = true
+	}
+
+	// The output of a program that didn't build, run or finish isn't worth keeping
+	if res.CompilerStatus == 0 && !res.timedOut && len(job.errors) == 0 {
+		if job.expectedStdout != nil || job.expectedStderr != nil {
+			files := []struct {
+				name     string
+				expected **string
+				got      string
+			}{
+				{"expected.stdout", &job.expectedStdout, res.stdout},
+				{"expected.stderr", &job.expectedStderr, res.stderr},
+			}
+			for _, f := range files {
+				if *f.expected == nil || outputMatches(**f.expected, f.got) {
+					continue
+				}
+				if err := ioutil.WriteFile(filepath.Join(dir, f.name), []byte(f.got), 0644); err != nil {
+					return changed, err
+				}
+				got := f.got
+				*f.expected = &got
+				changed = true
+			}
+		} else if !outputMatches(job.RunOutput, res.RunOutput) {
+			job.RunOutput = res.RunOutput
+			configChanged = true
+		}
+	}
+
+	if configChanged {
+		buf := &bytes.Buffer{}
+		if err := toml.NewEncoder(buf).Encode(job); err != nil {
+			return changed, err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "test.toml"), buf.Bytes(), 0644); err != nil {
+			return changed, err
+		}
+	}
+	return changed || configChanged, nil
+}
+
+// syncBuffer is a buffer that a program's stdout and stderr can both write to
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
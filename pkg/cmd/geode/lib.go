@@ -0,0 +1,55 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/geode-lang/geode/pkg/ast"
+)
+
+// libraryOutput returns the file a library is built into when no output is
+// given, which is named after the package like libmath.a or libmath.so
+func libraryOutput(input string, kind string) string {
+	name := filepath.Base(input)
+	if strings.HasSuffix(name, ".g") {
+		name = strings.TrimSuffix(name, ".g")
+	} else if abs, err := filepath.Abs(input); err == nil {
+		name = filepath.Base(abs)
+	}
+	if kind == "shared" {
+		return "lib" + name + ".so"
+	}
+	return "lib" + name + ".a"
+}
+
+// headerPath returns the path of the C header of a library, which is next to
+// it with the extension replaced
+func headerPath(library string) string {
+	return strings.TrimSuffix(library, filepath.Ext(library)) + ".h"
+}
+
+// headerGuard returns the include guard of a header, made from its name
+func headerGuard(header string) string {
+	name := strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return '_'
+		}
+		return unicode.ToUpper(r)
+	}, filepath.Base(header))
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// writeHeader writes the C header that declares what a library exports
+func writeHeader(program *ast.Program, library string) (string, error) {
+	path := headerPath(library)
+	header, err := program.CHeader(headerGuard(path))
+	if err != nil {
+		return "", err
+	}
+	return path, ioutil.WriteFile(path, []byte(header), 0644)
+}
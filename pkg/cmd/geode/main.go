@@ -5,8 +5,9 @@ import (
 	"os"
 	"os/exec"
 	"path"
+	"path/filepath"
 	"runtime"
-	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -41,40 +42,57 @@ func main() {
 
 	log.PrintVerbose = *arg.PrintVerbose
 
-	clangVersion, clangError := util.RunCommand("clang", "-v")
-	if clangError != nil {
-		log.Fatal("Unable to find a clang install in your path. Please install clang and add it to your path\n")
-	}
-
-	clangVersionLines := strings.Split(string(clangVersion), "\n")
-	targetTripple := ""
-
-	for _, line := range clangVersionLines {
-		if strings.HasPrefix(line, "Target: ") {
-			targetTripple = strings.Replace(line, "Target: ", "", 1)
-		}
-	}
-
-	log.Verbose("Clang Version: %s\n", clangVersion)
 	log.Verbose("Building to %s...\n", buildDir)
 
 	switch command {
 	case arg.BuildCMD.FullCommand():
 		log.Timed("Compilation", func() {
-			context := NewContext(*arg.BuildInput, *arg.BuildOutput)
-			context.TargetTripple = targetTripple
+			out := *arg.BuildOutput
+			if *arg.BuildLib != "" && out == "a.out" {
+				out = libraryOutput(*arg.BuildInput, *arg.BuildLib)
+			}
+			context := NewContext(*arg.BuildInput, out)
+			context.Library = *arg.BuildLib
+			context.TargetTripple = findTargetTripple()
 			context.Build(buildDir)
 		})
 
 	case arg.RunCMD.FullCommand():
 		out := path.Join(buildDir, "a.out")
 		context := NewContext(*arg.RunInput, out)
-		context.TargetTripple = targetTripple
+		context.TargetTripple = findTargetTripple()
 		context.Build(buildDir)
 		context.Run(*arg.RunArgs, buildDir)
 
+	case arg.CheckCMD.FullCommand():
+		context := NewContext(*arg.CheckInput, "")
+		if !context.Check() {
+			os.Exit(1)
+		}
+
 	case arg.TestCMD.FullCommand():
-		RunTests("./tests")
+		if *arg.TestUnit {
+			os.Exit(RunUnitTests(*arg.TestInput, buildDir))
+		}
+		os.Exit(RunTests("./tests", buildDir))
+
+	case arg.BenchCMD.FullCommand():
+		os.Exit(RunBenchmarks(*arg.BenchInput, buildDir))
+
+	case arg.CoverReportCMD.FullCommand():
+		os.Exit(CoverReport(*arg.CoverReportInputs))
+
+	case arg.FmtCMD.FullCommand():
+		os.Exit(FormatFiles(*arg.FmtFiles))
+
+	case arg.DocCMD.FullCommand():
+		os.Exit(GenerateDocs(*arg.DocPackages))
+
+	case arg.BindgenCMD.FullCommand():
+		os.Exit(Bindgen(*arg.BindgenHeader))
+
+	case arg.DemangleCMD.FullCommand():
+		os.Exit(Demangle(*arg.DemangleNames))
 
 	case arg.NewTestCMD.FullCommand():
 		CreateTestCMD()
@@ -94,7 +112,7 @@ func main() {
 		log.Timed("information gathering", func() {
 			context := NewContext(*arg.InfoInput, "/tmp/geodeinfooutput")
 			*arg.DisableEmission = true
-			context.TargetTripple = targetTripple
+			context.TargetTripple = findTargetTripple()
 			context.Build(buildDir)
 			info.DumpJSON()
 		})
@@ -106,11 +124,64 @@ func main() {
 	}
 }
 
+var (
+	toolchainOnce sync.Once
+	toolchain     ast.Toolchain
+)
+
+// findToolchain finds the tools to build programs with, from the toolchain
+// file and the --toolchain flag or whatever is installed. Only commands that
+// emit code need a toolchain, so this is run lazily.
+func findToolchain() ast.Toolchain {
+	toolchainOnce.Do(func() {
+		cfg := ast.ToolchainConfig{}
+		file := *arg.ToolchainFile
+		if file == "" {
+			if p := path.Join(util.HomeDir(), ".geode/toolchain.toml"); util.FileExists(p) {
+				file = p
+			}
+		}
+		if file != "" {
+			var err error
+			if cfg, err = ast.LoadToolchainConfig(file); err != nil {
+				log.Fatal("%s\n", err)
+			}
+		}
+		if *arg.Toolchain != "" {
+			cfg.Kind = *arg.Toolchain
+		}
+		if *arg.Target != "" {
+			if _, err := ast.ParseTarget(*arg.Target); err != nil {
+				log.Fatal("%s\n", err)
+			}
+			cfg.Triple = *arg.Target
+		}
+
+		tc, err := ast.DetectToolchain(cfg)
+		if err != nil {
+			log.Fatal("%s\n", err)
+		}
+		log.Verbose("Building with the %s toolchain for %s\n", tc.Name(), tc.Triple())
+		toolchain = tc
+	})
+	return toolchain
+}
+
+// findTargetTripple returns the target the toolchain builds for
+func findTargetTripple() string {
+	return findToolchain().Triple()
+}
+
 // Context contains information for this compilation
 type Context struct {
 	Input         string
 	Output        string
 	TargetTripple string
+	Test          bool           // build the input's test blocks instead of its main function
+	Tests         []ast.TestCase // the test blocks that were built, in the order the test main indexes them
+	Bench         bool           // build the input's bench blocks instead of its main function
+	Benches       []ast.TestCase // the bench blocks that were built, in the order the bench main indexes them
+	Library       string         // build a static or shared library of the exported functions instead of a program
 }
 
 // NewContext constructs a new context and returns a pointer to it
@@ -124,11 +195,28 @@ func NewContext(in string, out string) *Context {
 	return res
 }
 
-// Build some context into a binary file
-func (c *Context) Build(buildDir string) {
-
+// parse reads the context's input and everything it depends on into a
+// congealed program, ready to be checked or compiled
+func (c *Context) parse() *ast.Program {
 	program := ast.NewProgram()
 
+	// Checking doesn't need a toolchain, so it doesn't have a target
+	if c.TargetTripple != "" {
+		target, err := ast.ParseTarget(c.TargetTripple)
+		if err != nil && *arg.Target == "" {
+			// The toolchain's own target may be one geode doesn't know, so
+			// its types are laid out the way the toolchain does by default
+			log.Verbose("%s, using the toolchain's default datalayout\n", err)
+			target, err = ast.Target{Triple: c.TargetTripple}, nil
+		}
+		if err == nil {
+			err = program.SetTarget(target)
+		}
+		if err != nil {
+			log.Fatal("%s\n", err)
+		}
+	}
+
 	if !*arg.DisableRuntime {
 		program.ParseDep("", "runtime")
 	}
@@ -140,23 +228,103 @@ func (c *Context) Build(buildDir string) {
 		os.Exit(-1)
 	}
 
+	if c.Test || c.Bench {
+		dir, _ := filepath.Abs(ast.ReduceToDir(c.Input))
+		program.TestDir = dir
+	}
+
 	program.ParsePath(c.Input)
-	program.TargetTripple = c.TargetTripple
+
+	if c.Test {
+		c.Tests = program.AddTestMain()
+	}
+	if c.Bench {
+		c.Benches = program.AddBenchMain()
+	}
 
 	_, err := program.Congeal()
 	if err != nil {
 		log.Fatal("%s\n", err)
 	}
+	return program
+}
+
+// check runs semantic analysis on a program and prints the diagnostics.
+// It returns false if any of the diagnostics are errors
+func (c *Context) check(program *ast.Program) bool {
+	diagnostics := program.Check()
+	for _, d := range diagnostics {
+		fmt.Println(d.Pretty())
+	}
+	return !diagnostics.HasErrors()
+}
 
-	options := ast.FunctionCompilationOptions{}
-	main, err := program.GetFunction("main", options)
+// Check parses and type checks the context without compiling it.
+// Unlike Build, the input doesn't need a main function and nothing is emitted
+func (c *Context) Check() bool {
+	return c.check(c.parse())
+}
+
+// Build some context into a binary file
+func (c *Context) Build(buildDir string) {
+	sanitizers, err := ast.ParseSanitizers(*arg.Sanitize)
 	if err != nil {
+		log.Fatal("%s\n", err)
+	}
+
+	program := c.parse()
+
+	// There is nothing to build if the package has no tests or benchmarks
+	if c.Test && len(c.Tests) == 0 || c.Bench && len(c.Benches) == 0 {
+		return
+	}
+
+	if !c.check(program) {
+		fmt.Println(color.Red("Failed to Compile"))
+		os.Exit(1)
+	}
+
+	if c.Library != "" {
+		// A library has no main function, the programs it is linked into call its exports
+		exports, err := program.CompileExports()
+		if err == nil && exports == 0 {
+			err = fmt.Errorf("nothing is exported from %s, mark the functions the library has with export", c.Input)
+		}
+		if err == nil {
+			err = program.AddLibraryInit()
+		}
+		if err != nil {
+			fmt.Println(color.Red("Failed to Compile"))
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else {
+		options := ast.FunctionCompilationOptions{}
+		main, err := program.GetFunction("main", options)
+		if err != nil {
+			fmt.Println(color.Red("Failed to Compile"))
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if main == nil {
+			log.Fatal("No function `main` found in compilation.\n")
+		}
+	}
+
+	if err := program.FinishCoverage(); err != nil {
 		fmt.Println(color.Red("Failed to Compile"))
 		fmt.Println(err)
 		os.Exit(1)
 	}
-	if main == nil {
-		log.Fatal("No function `main` found in compilation.\n")
+
+	if err := program.FinishTrace(); err != nil {
+		fmt.Println(color.Red("Failed to Compile"))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	if !*arg.DisableOptimization {
+		program.Optimize()
 	}
 
 	// virt := vm.New(program.Module)
@@ -178,6 +346,9 @@ func (c *Context) Build(buildDir string) {
 	linker.SetBuildDir(buildDir)
 	linker.SetOutput(c.Output)
 	linker.SetOptimize(*arg.Optimize)
+	linker.SetSanitizers(sanitizers)
+	linker.SetToolchain(findToolchain())
+	linker.SetLibrary(c.Library)
 
 	for _, clink := range program.CLinkages {
 		linker.AddObject(clink)
@@ -187,6 +358,13 @@ func (c *Context) Build(buildDir string) {
 		fmt.Println(program.Scope)
 	}
 
+	// The header is written first, as a library can't export what C can't declare
+	if c.Library != "" && target == ast.BinaryTarget {
+		if _, err := writeHeader(program, c.Output); err != nil {
+			log.Fatal("%s\n", err)
+		}
+	}
+
 	linker.AddObject(program.Emit(buildDir))
 	log.Timed("Linking", func() {
 		linker.Run()
@@ -195,11 +373,20 @@ func (c *Context) Build(buildDir string) {
 
 // Run a context with a given set of arguments
 func (c *Context) Run(args []string, buildDir string) {
-	cmd := exec.Command(c.Output, args...)
+	var env []string
+	if *arg.Cover {
+		env = coverEnv(*arg.CoverProfile)
+	}
+	program, args, env, err := programCommand(c.Output, args, env)
+	if err != nil {
+		log.Fatal("%s\n", err)
+	}
+	cmd := exec.Command(program, args...)
+	cmd.Env = append(os.Environ(), env...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	err := cmd.Run()
+	err = cmd.Run()
 
 	// The program exited with a failed code. So we need to exit with that same code.
 	// This is because the run command should feel like just running the binary
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/arg"
+)
+
+// junitSuites is the root of a JUnit XML report
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// jsonResult is a single test in a JSON report
+type jsonResult struct {
+	Name           string  `json:"name"`
+	Source         string  `json:"source"`
+	Passed         bool    `json:"passed"`
+	CompilerStatus int     `json:"compiler_status"`
+	CompilerOutput string  `json:"compiler_output"`
+	RunStatus      int     `json:"run_status"`
+	RunOutput      string  `json:"run_output"`
+	TimedOut       bool    `json:"timed_out"`
+	Seconds        float64 `json:"seconds"`
+	Failure        string  `json:"failure,omitempty"`
+	Skipped        string  `json:"skipped,omitempty"`
+}
+
+// writeTestReports writes the results of the tests to the report files passed to the test command
+func writeTestReports(results []testResult) error {
+	if *arg.TestJUnit != "" {
+		if err := writeJUnitReport(*arg.TestJUnit, results); err != nil {
+			return err
+		}
+	}
+	if *arg.TestJSON != "" {
+		if err := writeJSONReport(*arg.TestJSON, results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failureMessage returns the first line of a failure, which names what didn't match
+func failureMessage(failure string) string {
+	return strings.TrimSuffix(strings.SplitN(failure, "\n", 2)[0], ":")
+}
+
+func writeJUnitReport(file string, results []testResult) error {
+	suite := junitSuite{Name: "geode", Tests: len(results)}
+	for _, res := range results {
+		c := junitCase{
+			Name:      res.TestJob.Name,
+			ClassName: strings.TrimSuffix(res.TestJob.dir, "/"),
+			Time:      res.timetaken.Seconds(),
+			SystemOut: res.compilerOutput + res.RunOutput,
+		}
+		if res.skipped != "" {
+			c.Skipped = &junitSkipped{res.skipped}
+			suite.Skipped++
+		} else if res.failure != "" {
+			c.Failure = &junitFailure{failureMessage(res.failure), res.failure}
+			suite.Failures++
+		}
+		suite.Time += c.Time
+		suite.Cases = append(suite.Cases, c)
+	}
+
+	out, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append([]byte(xml.Header), append(out, '\n')...), 0644)
+}
+
+func writeJSONReport(file string, results []testResult) error {
+	report := make([]jsonResult, 0, len(results))
+	for _, res := range results {
+		report = append(report, jsonResult{
+			Name:           res.TestJob.Name,
+			Source:         res.TestJob.sourcefile,
+			Passed:         res.failure == "" && res.skipped == "",
+			CompilerStatus: res.CompilerStatus,
+			CompilerOutput: res.compilerOutput,
+			RunStatus:      res.RunStatus,
+			RunOutput:      res.RunOutput,
+			TimedOut:       res.timedOut,
+			Seconds:        res.timetaken.Seconds(),
+			Failure:        res.failure,
+			Skipped:        res.skipped,
+		})
+	}
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(out, '\n'), 0644)
+}
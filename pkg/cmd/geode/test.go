@@ -6,6 +6,8 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,6 +15,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 	"syscall"
@@ -21,29 +24,58 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/geode-lang/geode/pkg/arg"
-	"github.com/geode-lang/geode/pkg/util"
+	"github.com/geode-lang/geode/pkg/ast"
 	"github.com/geode-lang/geode/pkg/util/color"
 	"github.com/geode-lang/geode/pkg/util/log"
-	"github.com/sergi/go-diff/diffmatchpatch"
 )
 
-// TestJob -
+// TestJob is a test, as described by the test.toml file next to its source
 type TestJob struct {
-	Name, sourcefile                         string
-	CompilerArgs, RunArgs                    []string
-	compilerError, RunStatus, CompilerStatus int
-	Input                                    string
-	compilerOutput, RunOutput                string
+	Name                  string
+	CompilerArgs, RunArgs []string `toml:",omitempty"`
+	CompilerStatus        int
+	RunStatus             int
+	Timeout               string `toml:",omitempty"` // how long the test may run for, like "30s"
+	Input                 string
+	CompilerOutput        *string `toml:",omitempty"` // only checked when the test.toml sets it
+	RunOutput             string
+	// A C program that calls the functions the test exports. The test is
+	// built as a static library, and the driver linked with it is what runs
+	Driver string `toml:",omitempty"`
+	// A geode command, like ["demangle"], that is run on the Input with the
+	// RunArgs instead of the test's program. It runs in the test's directory,
+	// so the RunArgs can name the files next to it
+	Command []string `toml:",omitempty"`
+	// Programs the test needs that may not be installed, like wasm-ld or a
+	// cross compiler. "a|b" needs either of them. The test is skipped when
+	// one of them isn't in the PATH
+	Requires []string `toml:",omitempty"`
+
+	sourcefile string
+	dir        string // the directory of the test, relative to the tests directory
+
+	// The contents of the expected.stdout and expected.stderr files next to
+	// the test. When either exists, it is checked instead of RunOutput
+	expectedStdout, expectedStderr *string
+
+	// The `# ERROR:` comments in the source. A test that has them must fail
+	// to compile with those errors, and isn't run
+	errors []expectedError
 }
 
 type testResult struct {
 	TestJob        TestJob
-	compilerError  int
-	RunStatus      int
 	CompilerStatus int
 	compilerOutput string
-	RunOutput      string
+	RunStatus      int
+	RunOutput      string // stdout and stderr, interleaved
+	stdout, stderr string
+	timedOut       bool
 	timetaken      time.Duration
+	updated        bool   // if --update rewrote the expected outputs
+	failure        string // what didn't match the expected results, empty if the test passed
+	skipped        string // why the test wasn't run, empty if it was
+	coverProfile   string // where the program wrote its coverage profile when testing with --cover
 }
 
 func parseTestJob(filename string) (TestJob, error) {
@@ -54,11 +86,75 @@ func parseTestJob(filename string) (TestJob, error) {
 	if _, err := toml.DecodeFile(configPath, &job); err != nil {
 		return TestJob{}, err
 	}
+	if job.Timeout != "" {
+		if _, err := time.ParseDuration(job.Timeout); err != nil {
+			return TestJob{}, fmt.Errorf("%s: invalid Timeout: %s", configPath, err)
+		}
+	}
+
+	var err error
+	if job.expectedStdout, err = readExpected(path.Join(path.Dir(filename), "expected.stdout")); err != nil {
+		return TestJob{}, err
+	}
+	if job.expectedStderr, err = readExpected(path.Join(path.Dir(filename), "expected.stderr")); err != nil {
+		return TestJob{}, err
+	}
+	if job.errors, err = readExpectedErrors(filename); err != nil {
+		return TestJob{}, err
+	}
 	return job, nil
 }
 
-// RunTests runs all the tests in some directory
-func RunTests(testDirectory string) int {
+// timeout returns how long the test's program may run for
+func (job TestJob) timeout() time.Duration {
+	if d, err := time.ParseDuration(job.Timeout); err == nil && job.Timeout != "" {
+		return d
+	}
+	return *arg.TestTimeout
+}
+
+// missing returns why the test can't be run if a program it requires isn't
+// installed, or an empty string if they all are
+func (job TestJob) missing() string {
+	for _, req := range job.Requires {
+		names := strings.Split(req, "|")
+		found := false
+		for _, name := range names {
+			if _, err := exec.LookPath(name); err == nil {
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+		if len(names) == 1 {
+			return fmt.Sprintf("needs %s, which isn't installed", req)
+		}
+		return fmt.Sprintf("needs one of %s, which aren't installed", strings.Join(names, ", "))
+	}
+	return ""
+}
+
+// matches returns if the test's name or directory contains the
+// filter passed to --run, or matches it as a glob
+func (job TestJob) matches(filter string) bool {
+	if filter == "" {
+		return true
+	}
+	for _, s := range []string{job.Name, strings.TrimSuffix(job.dir, "/")} {
+		if strings.Contains(s, filter) {
+			return true
+		}
+		if ok, _ := filepath.Match(filter, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RunTests runs all the tests in some directory. Several of them are built
+// and run at once. It returns the status the command exits with
+func RunTests(testDirectory string, buildDir string) int {
 	var dirs []string
 	files := make(map[string][]string)
 
@@ -106,163 +202,318 @@ func RunTests(testDirectory string) int {
 				return 1
 			}
 			job.sourcefile = path
+			job.dir = dir
 
-			jobs = append(jobs, job)
+			if job.matches(*arg.TestRun) {
+				jobs = append(jobs, job)
+			}
 		}
 	}
 
-	// Do jobs
-
-	results := make(chan testResult, len(jobs))
-
-	testRunCount := 0
+	if len(jobs) == 0 {
+		fmt.Println("no tests to run")
+		return 0
+	}
 
-	util.RunCommand("geode", "clean")
+	os.RemoveAll(buildDir)
 
-	go func() {
-		for _, job := range jobs {
+	// Do jobs, keeping a channel for each so the results are printed in order
+	results := make([]chan testResult, len(jobs))
+	for i := range results {
+		results[i] = make(chan testResult, 1)
+	}
 
-			start := time.Now()
-			outBuf := new(bytes.Buffer)
-			outpath := fmt.Sprintf("%s_test", job.sourcefile)
+	workers := *arg.TestJobs
+	if workers < 1 {
+		workers = 1
+	}
+	queue := make(chan int, len(jobs))
+	for i := range jobs {
+		queue <- i
+	}
+	close(queue)
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range queue {
+				results[i] <- runTestJob(jobs[i])
+			}
+		}()
+	}
 
-			// Compile the test program
-			buildArgs := []string{"build"}
-			buildArgs = append(buildArgs, job.CompilerArgs...)
-			buildArgs = append(buildArgs, "-o", outpath, job.sourcefile)
+	// Check results
+	numSucceses, numSkipped := 0, 0
+	all := make([]testResult, 0, len(jobs))
 
-			outBuf.Reset()
+	ok := fmt.Sprintf("%sOKAY%s", color.TEXT_GREEN, color.TEXT_RESET)
+	failed := fmt.Sprintf("%sFAIL%s", color.TEXT_RED, color.TEXT_RESET)
+	skipped := fmt.Sprintf("%sSKIP%s", color.TEXT_YELLOW, color.TEXT_RESET)
 
-			var err error
-			res := testResult{TestJob: job}
+	for index, ch := range results {
+		res := <-ch
+		all = append(all, res)
 
-			res.compilerError, err = runCommand(outBuf, "", "geode", buildArgs)
-			if err != nil {
-				fmt.Printf("Error while building test:\n%s\n", err.Error())
-				os.Exit(1)
+		// Output result
+		if res.skipped != "" {
+			fmt.Printf("(%d)\t%s %s (%s)\n", index+1, skipped, res.TestJob.Name, res.skipped)
+			numSkipped++
+		} else if res.failure == "" {
+			if res.updated {
+				fmt.Printf("(%d)\t%s %s (updated)\n", index+1, ok, res.TestJob.Name)
+			} else {
+				fmt.Printf("(%d)\t%s %s\n", index+1, ok, res.TestJob.Name)
 			}
-			res.compilerOutput = outBuf.String()
+			numSucceses++
+		} else {
+			fmt.Printf("(%d)\t%s %s\n", index+1, failed, res.TestJob.Name)
+			fmt.Printf("%s\n", res.failure)
+		}
+	}
 
-			if res.compilerError != 0 {
-				results <- res
-				res.RunStatus = -1
-				return
-			}
+	numTests := len(jobs) - numSkipped
+	if numTests > 0 {
+		fmt.Printf("->\t%d/%d (%.0f%%) tests ran successfully", numSucceses, numTests, float64(numSucceses)/float64(numTests)*100)
+	} else {
+		fmt.Printf("->\tno tests ran")
+	}
+	if numSkipped > 0 {
+		fmt.Printf(", %d skipped", numSkipped)
+	}
+	fmt.Printf("\n\n")
 
-			// Run the test program
-			outBuf.Reset()
+	if err := writeTestReports(all); err != nil {
+		fmt.Println(err)
+		return 1
+	}
 
-			res.RunStatus, err = runCommand(outBuf, job.Input, fmt.Sprintf("./%s", outpath), job.RunArgs)
-			if err != nil {
-				fmt.Printf("Error while running test:\n%s\n", err.Error())
-				os.Exit(1)
-			}
+	if *arg.Cover {
+		profiles := make([]string, 0, len(all))
+		for _, res := range all {
+			profiles = append(profiles, res.coverProfile)
+		}
+		if err := mergeCoverProfiles(profiles, *arg.CoverProfile); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	}
 
-			res.RunOutput = outBuf.String()
+	if numSucceses < numTests {
+		return 1
+	}
+	return 0
+}
 
-			// Remove test executable
-			if err := os.Remove(outpath); err != nil {
-				fmt.Printf("Error while removing test executable:\n%s\n", err.Error())
-				os.Exit(1)
-			}
+// library returns where a test with a driver is built to. Its header is
+// next to it, so the driver can include it by name
+func (job TestJob) library() string {
+	return strings.TrimSuffix(job.sourcefile, ".g") + ".a"
+}
 
-			t := time.Now()
+// runTestJob builds and runs a single test and compares the results to the expected ones
+func runTestJob(job TestJob) testResult {
+	start := time.Now()
+	res := testResult{TestJob: job, RunStatus: -1}
+	if res.skipped = job.missing(); res.skipped != "" {
+		return res
+	}
+	outpath := fmt.Sprintf("%s_test", job.sourcefile)
 
-			elapsed := t.Sub(start)
+	res.CompilerStatus, res.compilerOutput = compileTest(job, outpath)
 
-			res.timetaken = elapsed
-			results <- res
-			testRunCount++
+	// Paths in the compiler's output are made relative to the test so they can be matched
+	if dir, err := filepath.Abs(filepath.Dir(job.sourcefile)); err == nil {
+		res.compilerOutput = strings.Replace(res.compilerOutput, dir+string(filepath.Separator), "", -1)
+	}
 
-			if testRunCount == len(jobs) {
-				close(results)
-			}
+	if res.CompilerStatus == 0 && len(job.errors) == 0 {
+		// Run the test program
+		combined := &syncBuffer{}
+		stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+		res.coverProfile = outpath + ".cover"
+		program, args, dir, env := fmt.Sprintf("./%s", outpath), job.RunArgs, "", coverEnv(res.coverProfile)
+		if len(job.Command) > 0 {
+			program, _ = os.Executable()
+			// The command gets the flags the tests are run with, other than
+			// the coverage ones, which only count the tests' own programs
+			skip := append([]string{"output", "cover", "coverprofile"}, arg.FlagNames(append(job.Command, job.RunArgs...))...)
+			args = append(append([]string{}, job.Command...), arg.SetGlobals(skip...)...)
+			args, dir, env = append(args, job.RunArgs...), filepath.Dir(job.sourcefile), nil
+		}
+		status, err := runCommand(io.MultiWriter(combined, stdout), io.MultiWriter(combined, stderr), job.Input, job.timeout(), dir, program, args, env...)
+		if err == errTimedOut {
+			res.timedOut = true
+		} else if err != nil {
+			fmt.Fprintf(combined, "Error while running test:\n%s\n", err.Error())
 		}
-	}()
+		res.RunStatus = status
+		res.RunOutput = combined.String()
+		res.stdout, res.stderr = stdout.String(), stderr.String()
 
-	// Check results
-	numSucceses := 0
-	numTests := 0
+		// Remove test executable
+		os.Remove(outpath)
+	}
+	if job.Driver != "" {
+		os.Remove(job.library())
+		os.Remove(headerPath(job.library()))
+	}
 
-	index := 0
+	res.timetaken = time.Since(start)
 
-	for res := range results {
-		index++
-		failure := false
+	if *arg.TestUpdate {
+		updated, err := res.update()
+		if err != nil {
+			res.failure = fmt.Sprintf("Error while updating the expected output:\n%s\n", err)
+			return res
+		}
+		res.updated = updated
+	}
 
-		errBuf := &bytes.Buffer{}
+	res.failure = res.check()
+	return res
+}
 
-		// Check build errors
+// check returns a description of how a test's results differ from the
+// expected ones, or an empty string if they are the same
+func (res testResult) check() string {
+	errBuf := &bytes.Buffer{}
+	job := res.TestJob
 
-		if (res.TestJob.compilerError == -1 && res.compilerError != res.TestJob.CompilerStatus) || (res.compilerError == res.TestJob.compilerError) {
-		} else {
+	if job.CompilerOutput != nil {
+		checkOutput(errBuf, "CompilerOutput", *job.CompilerOutput, res.compilerOutput)
+	}
+
+	// Negative compile tests only check the errors they fail with
+	if len(job.errors) > 0 {
+		if res.CompilerStatus == 0 {
 			fmt.Fprintf(errBuf, "CompilerStatus:\n")
-			fmt.Fprintf(errBuf, "Expected: %d\n", res.TestJob.compilerError)
-			fmt.Fprintf(errBuf, "Got:      %d\n", res.TestJob.CompilerStatus)
-			failure = true
+			fmt.Fprintf(errBuf, "Expected the build to fail\n")
 		}
+		checkErrors(errBuf, job, res.compilerOutput)
+		return errBuf.String()
+	}
 
-		// Check run errors
-		if res.RunStatus == res.TestJob.RunStatus {
-		} else {
-			fmt.Fprintf(errBuf, "RunStatus:\n")
-			fmt.Fprintf(errBuf, "Expected: %d\n", res.TestJob.RunStatus)
-			fmt.Fprintf(errBuf, "Got:      %d\n", res.RunStatus)
-			failure = true
+	// Check build errors
+	if res.CompilerStatus != job.CompilerStatus {
+		fmt.Fprintf(errBuf, "CompilerStatus:\n")
+		fmt.Fprintf(errBuf, "Expected: %d\n", job.CompilerStatus)
+		fmt.Fprintf(errBuf, "Got:      %d\n", res.CompilerStatus)
+		if res.compilerOutput != "" && job.CompilerOutput == nil {
+			fmt.Fprintf(errBuf, "CompilerOutput:\n%s\n", strings.TrimRight(res.compilerOutput, "\n"))
 		}
+	}
 
-		// Check run errors
-		if res.RunOutput == res.TestJob.RunOutput {
-		} else {
+	// A program that didn't build can't be checked any further
+	if res.CompilerStatus != 0 {
+		return errBuf.String()
+	}
 
-			dmp := diffmatchpatch.New()
-			expected := res.TestJob.RunOutput
-			got := res.RunOutput
-			diffs := dmp.DiffMain(expected, got, false)
+	if res.timedOut {
+		fmt.Fprintf(errBuf, "Timeout:\n")
+		fmt.Fprintf(errBuf, "Ran for longer than %s\n", job.timeout())
+		return errBuf.String()
+	}
 
-			fmt.Fprintf(errBuf, "RunOutput:\n")
-			fmt.Fprintf(errBuf, "Expected: %q\n", expected)
-			fmt.Fprintf(errBuf, "Got:      %q\n", got)
+	// Check run errors
+	if res.RunStatus != job.RunStatus {
+		fmt.Fprintf(errBuf, "RunStatus:\n")
+		fmt.Fprintf(errBuf, "Expected: %d\n", job.RunStatus)
+		fmt.Fprintf(errBuf, "Got:      %d\n", res.RunStatus)
+	}
 
-			fmt.Fprintf(errBuf, "diff:\n%s\n", dmp.DiffPrettyText(diffs))
-			failure = true
+	// Check run output
+	if job.expectedStdout != nil || job.expectedStderr != nil {
+		if job.expectedStdout != nil {
+			checkOutput(errBuf, "expected.stdout", *job.expectedStdout, res.stdout)
+		}
+		if job.expectedStderr != nil {
+			checkOutput(errBuf, "expected.stderr", *job.expectedStderr, res.stderr)
 		}
+	} else {
+		checkOutput(errBuf, "RunOutput", job.RunOutput, res.RunOutput)
+	}
+	return errBuf.String()
+}
 
-		ok := fmt.Sprintf("%sOKAY%s", color.TEXT_GREEN, color.TEXT_RESET)
+// colorPattern matches the escape codes the compiler colors its output with
+var colorPattern = regexp.MustCompile("\x1B\\[[0-9;]*m")
 
-		// Output result
-		if !failure {
-			fmt.Printf("(%d)\t%s %s\n", index, ok, res.TestJob.Name)
-			numSucceses++
-			// fmt.Printf("%sTest Passed%s\n", color.TEXT_GREEN, color.TEXT_RESET)
-		} else {
-			failed := fmt.Sprintf("%sFAIL%s", color.TEXT_RED, color.TEXT_RESET)
-			fmt.Printf("(%d)\t%s %s\n", index, failed, res.TestJob.Name)
-			fmt.Printf("%s\n", errBuf.String())
-		}
-		numTests++
+// compileTest builds a test with `geode build` in a child process, so tests
+// are built in parallel. It returns the status the build exited with and
+// everything it printed
+func compileTest(job TestJob, outpath string) (int, string) {
+	geode, err := os.Executable()
+	if err != nil {
+		return -1, err.Error()
+	}
 
+	// The flags the tests are run with apply to each build, and the test's own
+	// arguments come after them so they can override them
+	skip := append([]string{"output"}, arg.FlagNames(job.CompilerArgs)...)
+	args := append([]string{"build"}, arg.SetGlobals(skip...)...)
+	args = append(args, job.CompilerArgs...)
+	if job.Driver != "" {
+		args = append(args, "--lib", "static", "-o", job.library())
+	} else {
+		args = append(args, "-o", outpath)
+	}
+	args = append(args, job.sourcefile)
+
+	out := &bytes.Buffer{}
+	status, err := runCommand(out, out, "", 0, "", geode, args)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		if status == 0 {
+			status = -1
+		}
 	}
+	if status == 0 && job.Driver != "" {
+		if err := buildDriver(job, outpath); err != nil {
+			fmt.Fprintln(out, err)
+			status = 1
+		}
+	}
+	return status, colorPattern.ReplaceAllString(out.String(), "")
+}
 
-	fmt.Printf("->\t%d/%d (%.0f%%) tests ran successfully\n\n", numSucceses, numTests, float64(numSucceses)/float64(numTests)*100)
-	if numSucceses < numTests {
-		os.Exit(1)
-		return 1
+// buildDriver compiles the C driver of a test and links it with the library
+// the test was built as
+func buildDriver(job TestJob, outpath string) error {
+	tc := findToolchain()
+	opts := ast.BuildOptions{Optimize: *arg.Optimize}
+	obj := outpath + ".o"
+	defer os.Remove(obj)
+	if err := tc.CompileC(path.Join(path.Dir(job.sourcefile), job.Driver), obj, opts); err != nil {
+		return err
 	}
-	return 0
+	return tc.Link([]string{obj, job.library()}, outpath, opts)
 }
 
-func runCommand(out io.Writer, input string, cmd string, args []string) (int, error) {
+// errTimedOut is returned by runCommand when a command is killed for running too long
+var errTimedOut = errors.New("timed out")
+
+// runCommand runs a program in a directory, or the current one if dir is
+// empty, and returns the status it exits with
+func runCommand(stdout, stderr io.Writer, input string, timeout time.Duration, dir string, cmd string, args []string, env ...string) (int, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Disable coloring so the output can be matched
+	cmd, args, env, err := programCommand(cmd, args, append([]string{"COLOR=0"}, env...))
+	if err != nil {
+		return -1, err
+	}
+
 	// Run the test program
-	command := exec.Command(cmd, args...)
+	command := exec.CommandContext(ctx, cmd, args...)
 	command.Stdin = strings.NewReader(input)
+	command.Dir = dir
 
 	// Output handling
-	ow := out
-	command.Stdout, command.Stderr = ow, ow
-
-	// Disable coloring for matching compiler output
-	command.Env = append(os.Environ(), "COLOR=0")
+	command.Stdout, command.Stderr = stdout, stderr
+	command.Env = append(os.Environ(), env...)
 
 	// Start the test
 	if err := command.Start(); err != nil {
@@ -271,6 +522,9 @@ func runCommand(out io.Writer, input string, cmd string, args []string) (int, er
 
 	// Check the exit status
 	if err := command.Wait(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return -1, errTimedOut
+		}
 		if exiterr, ok := err.(*exec.ExitError); ok {
 			if status, ok := exiterr.Sys().(syscall.WaitStatus); ok {
 				return status.ExitStatus(), nil
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/arg"
+	"github.com/geode-lang/geode/pkg/ast"
+	"github.com/geode-lang/geode/pkg/util/color"
+)
+
+// RunUnitTests builds the test blocks of a package into a single binary
+// then runs each of them in its own process, so one failing test doesn't
+// stop the others. The package can be a directory or the name of a library
+// like `str`. It returns the status the command exits with
+func RunUnitTests(input string, buildDir string) int {
+	if isDir, _ := ast.PathIsDir(input); !isDir {
+		if _, err := os.Stat(input); err != nil {
+			cwd, _ := os.Getwd()
+			input = ast.ResolveDepPath(cwd, input)
+		}
+	}
+
+	out := path.Join(buildDir, "unit.test")
+	context := NewContext(input, out)
+	context.Test = true
+	context.TargetTripple = findTargetTripple()
+	context.Build(buildDir)
+	defer os.Remove(out)
+
+	if len(context.Tests) == 0 {
+		fmt.Println("no tests")
+		return 0
+	}
+
+	ok := fmt.Sprintf("%sOKAY%s", color.TEXT_GREEN, color.TEXT_RESET)
+	failed := fmt.Sprintf("%sFAIL%s", color.TEXT_RED, color.TEXT_RESET)
+
+	numSucceses := 0
+	outBuf := new(bytes.Buffer)
+	profiles := make([]string, 0, len(context.Tests))
+	for i, test := range context.Tests {
+		outBuf.Reset()
+		profile := fmt.Sprintf("%s.%d.cover", out, i)
+		profiles = append(profiles, profile)
+		status, err := runCommand(outBuf, outBuf, "", *arg.TestTimeout, "", out, []string{strconv.Itoa(i)}, coverEnv(profile)...)
+		if err == errTimedOut {
+			fmt.Fprintf(outBuf, "Ran for longer than %s\n", *arg.TestTimeout)
+		} else if err != nil {
+			fmt.Printf("Error while running test:\n%s\n", err.Error())
+			return 1
+		}
+
+		location := relativePath(test.Location())
+		if status == 0 {
+			fmt.Printf("(%d)\t%s %s (%s)\n", i+1, ok, test.Name, location)
+			numSucceses++
+			continue
+		}
+
+		fmt.Printf("(%d)\t%s %s (%s)\n", i+1, failed, test.Name, location)
+		fmt.Printf("Exit status: %d\n", status)
+		if output := strings.TrimRight(outBuf.String(), "\n"); output != "" {
+			fmt.Printf("%s\n", output)
+		}
+		fmt.Println()
+	}
+
+	numTests := len(context.Tests)
+	fmt.Printf("->\t%d/%d (%.0f%%) tests ran successfully\n\n", numSucceses, numTests, float64(numSucceses)/float64(numTests)*100)
+
+	if *arg.Cover {
+		if err := mergeCoverProfiles(profiles, *arg.CoverProfile); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+	}
+	if numSucceses < numTests {
+		return 1
+	}
+	return 0
+}
+
+// relativePath shortens a path to be relative to the working directory if it is inside of it
+func relativePath(p string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return p
+	}
+	rel, err := filepath.Rel(cwd, p)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return p
+	}
+	return rel
+}
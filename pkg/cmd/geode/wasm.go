@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// wasmRuntime is a program that runs the wasm modules built for wasm32-wasi
+type wasmRuntime struct {
+	name string
+	// args returns the arguments that run a module with some arguments. The
+	// module can read the current directory and the environment it is given
+	args func(module string, args []string, env []string) []string
+}
+
+// wasmRuntimes are the runtimes geode can run wasm modules with, in the
+// order they are looked for
+var wasmRuntimes = []wasmRuntime{
+	{"wasmtime", func(module string, args []string, env []string) []string {
+		res := []string{"run", "--dir=."}
+		for _, e := range env {
+			res = append(res, "--env", e)
+		}
+		return append(append(res, module), args...)
+	}},
+	{"wasmer", func(module string, args []string, env []string) []string {
+		res := []string{"run", "--dir=."}
+		for _, e := range env {
+			res = append(res, "--env", e)
+		}
+		return append(append(res, module, "--"), args...)
+	}},
+	{"iwasm", func(module string, args []string, env []string) []string {
+		res := []string{"--dir=."}
+		for _, e := range env {
+			res = append(res, "--env="+e)
+		}
+		return append(append(res, module), args...)
+	}},
+	// wasm3 can't pass an environment or directories to modules
+	{"wasm3", func(module string, args []string, env []string) []string {
+		return append([]string{module}, args...)
+	}},
+}
+
+// isWasmModule returns if a file is a wasm module instead of a native program
+func isWasmModule(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false
+	}
+	return bytes.Equal(magic, []byte("\x00asm"))
+}
+
+// programCommand returns the command that runs a program geode built, and
+// the variables to add to the environment it runs in. Native programs are
+// run directly, programs for another architecture with qemu and wasm
+// modules through the first wasm runtime installed. A module doesn't see
+// the environment of its runtime, so it is only given the variables geode sets
+func programCommand(program string, args []string, env []string) (string, []string, []string, error) {
+	if !isWasmModule(program) {
+		if arch := foreignArch(program); arch != "" {
+			qemu, args, err := emulatorCommand(arch, program, args)
+			return qemu, args, env, err
+		}
+		return program, args, env, nil
+	}
+	names := make([]string, 0, len(wasmRuntimes))
+	for _, rt := range wasmRuntimes {
+		if p, err := exec.LookPath(rt.name); err == nil {
+			return p, rt.args(program, args, env), nil, nil
+		}
+		names = append(names, rt.name)
+	}
+	return "", nil, nil, fmt.Errorf("%s is a wasm module, install one of %s to run it", program, strings.Join(names, ", "))
+}
@@ -0,0 +1,201 @@
+// Package cover reads the coverage profiles that programs built with
+// --cover write when they exit, and renders them as reports. A profile
+// starts with a `mode: count` line, followed by a line for every
+// statement with its location and how many times it ran:
+//
+//	/path/to/file.g:12.2 3
+//
+// The same statement can be in a profile more than once, like when a
+// generic function is compiled for several types, or when the profiles of
+// several programs are merged. The counts of a statement are added up.
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Profile is the coverage of every file that a set of profiles counted
+type Profile struct {
+	Files map[string]*File
+}
+
+// File is the coverage of the statements in a single source file
+type File struct {
+	Name       string
+	Statements []Statement // sorted by position
+}
+
+// Statement is how many times the statement at a position ran
+type Statement struct {
+	Line   int
+	Column int
+	Count  int64
+}
+
+// NewProfile returns an empty profile
+func NewProfile() *Profile {
+	return &Profile{Files: make(map[string]*File)}
+}
+
+// ReadFiles reads and merges the profiles at some paths
+func ReadFiles(paths ...string) (*Profile, error) {
+	p := NewProfile()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		err = p.Read(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+	}
+	return p, nil
+}
+
+// Read adds the counts of a profile to p
+func (p *Profile) Read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "mode:") {
+			continue
+		}
+		name, stmt, err := parseLine(text)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+		p.add(name, stmt)
+	}
+	return scanner.Err()
+}
+
+// parseLine parses the `file:line.column count` line of a statement
+func parseLine(text string) (string, Statement, error) {
+	stmt := Statement{}
+	space := strings.LastIndexByte(text, ' ')
+	colon := -1
+	if space >= 0 {
+		colon = strings.LastIndexByte(text[:space], ':')
+	}
+	if colon < 0 {
+		return "", stmt, fmt.Errorf("expected `file:line.column count`, got %q", text)
+	}
+
+	pos := strings.SplitN(text[colon+1:space], ".", 2)
+	var err error
+	if stmt.Line, err = strconv.Atoi(pos[0]); err != nil {
+		return "", stmt, fmt.Errorf("invalid line in %q", text)
+	}
+	if len(pos) == 2 {
+		if stmt.Column, err = strconv.Atoi(pos[1]); err != nil {
+			return "", stmt, fmt.Errorf("invalid column in %q", text)
+		}
+	}
+	if stmt.Count, err = strconv.ParseInt(text[space+1:], 10, 64); err != nil {
+		return "", stmt, fmt.Errorf("invalid count in %q", text)
+	}
+	return text[:colon], stmt, nil
+}
+
+func (p *Profile) add(name string, stmt Statement) {
+	f, ok := p.Files[name]
+	if !ok {
+		f = &File{Name: name}
+		p.Files[name] = f
+	}
+	i := sort.Search(len(f.Statements), func(i int) bool {
+		s := f.Statements[i]
+		return s.Line > stmt.Line || s.Line == stmt.Line && s.Column >= stmt.Column
+	})
+	if i < len(f.Statements) && f.Statements[i].Line == stmt.Line && f.Statements[i].Column == stmt.Column {
+		f.Statements[i].Count += stmt.Count
+		return
+	}
+	f.Statements = append(f.Statements, Statement{})
+	copy(f.Statements[i+1:], f.Statements[i:])
+	f.Statements[i] = stmt
+}
+
+// Write writes p as a single profile
+func (p *Profile) Write(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "mode: count\n")
+	for _, f := range p.SortedFiles() {
+		for _, s := range f.Statements {
+			fmt.Fprintf(buf, "%s:%d.%d %d\n", f.Name, s.Line, s.Column, s.Count)
+		}
+	}
+	return buf.Flush()
+}
+
+// SortedFiles returns the files of the profile sorted by name
+func (p *Profile) SortedFiles() []*File {
+	files := make([]*File, 0, len(p.Files))
+	for _, f := range p.Files {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files
+}
+
+// Covered returns how many of the statements in a file ran and how many there are
+func (f *File) Covered() (int, int) {
+	covered := 0
+	for _, s := range f.Statements {
+		if s.Count > 0 {
+			covered++
+		}
+	}
+	return covered, len(f.Statements)
+}
+
+// Covered returns how many of the statements in a profile ran and how many there are
+func (p *Profile) Covered() (int, int) {
+	covered, total := 0, 0
+	for _, f := range p.Files {
+		c, t := f.Covered()
+		covered += c
+		total += t
+	}
+	return covered, total
+}
+
+// Lines returns the coverage of each line of a file that has statements on it
+func (f *File) Lines() map[int]LineCoverage {
+	lines := make(map[int]LineCoverage)
+	for _, s := range f.Statements {
+		l := lines[s.Line]
+		l.Statements++
+		if s.Count > 0 {
+			l.Covered++
+		}
+		if s.Count > l.Count {
+			l.Count = s.Count
+		}
+		lines[s.Line] = l
+	}
+	return lines
+}
+
+// LineCoverage is the coverage of the statements on a line
+type LineCoverage struct {
+	Statements int
+	Covered    int
+	Count      int64 // how many times the statement on the line that ran the most ran
+}
+
+// Percent formats the share of statements that ran
+func Percent(covered, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(covered)/float64(total)*100)
+}
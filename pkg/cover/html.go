@@ -0,0 +1,90 @@
+package cover
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const htmlStyle = `body { font-family: sans-serif; margin: 0; color: #222; }
+#topbar { background: #f4f4f4; padding: 0.5em 1em; border-bottom: 1px solid #ddd; }
+#topbar select { font-size: 1em; }
+.legend span { margin-left: 1em; padding: 0 0.4em; }
+pre { margin: 0; padding: 1em; font-size: 0.9em; line-height: 1.3em; }
+.num { display: inline-block; width: 4em; color: #999; text-align: right; padding-right: 1em; user-select: none; }
+.count { display: inline-block; width: 5em; color: #999; text-align: right; padding-right: 1em; user-select: none; }
+.cov0 { background: #fdd; }
+.covpart { background: #ffd; }
+.cov { background: #dfd; }
+.file { display: none; }`
+
+const htmlScript = `function show(i) {
+  var files = document.getElementsByClassName("file");
+  for (var f = 0; f < files.length; f++) {
+    files[f].style.display = f == i ? "block" : "none";
+  }
+}
+show(0);`
+
+// WriteHTML writes a profile as a single html page that shows the source
+// of each file with the lines that ran in green and the lines that didn't
+// in red. Lines where only some of the statements ran are yellow
+func WriteHTML(w io.Writer, p *Profile) error {
+	buf := &strings.Builder{}
+	covered, total := p.Covered()
+
+	fmt.Fprintf(buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Geode Coverage</title>\n")
+	fmt.Fprintf(buf, "<style>\n%s\n</style>\n</head>\n<body>\n", htmlStyle)
+
+	fmt.Fprintf(buf, "<div id=\"topbar\">\n<select onchange=\"show(this.selectedIndex)\">\n")
+	files := p.SortedFiles()
+	for _, f := range files {
+		c, t := f.Covered()
+		fmt.Fprintf(buf, "<option>%s (%s)</option>\n", html.EscapeString(f.Name), Percent(c, t))
+	}
+	fmt.Fprintf(buf, "</select>\n<span class=\"legend\">total %s", Percent(covered, total))
+	fmt.Fprintf(buf, "<span class=\"cov0\">not run</span><span class=\"covpart\">partly run</span><span class=\"cov\">run</span></span>\n</div>\n")
+
+	for _, f := range files {
+		fmt.Fprintf(buf, "<pre class=\"file\">")
+		src, err := ioutil.ReadFile(f.Name)
+		if err != nil {
+			fmt.Fprintf(buf, "unable to read %s: %s", html.EscapeString(f.Name), html.EscapeString(err.Error()))
+		} else {
+			writeHTMLSource(buf, f, string(src))
+		}
+		fmt.Fprintf(buf, "</pre>\n")
+	}
+
+	fmt.Fprintf(buf, "<script>\n%s\n</script>\n</body>\n</html>\n", htmlScript)
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+func writeHTMLSource(buf *strings.Builder, f *File, src string) {
+	lines := f.Lines()
+	for i, text := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+		n := i + 1
+		count := ""
+		class := ""
+		if l, ok := lines[n]; ok {
+			count = fmt.Sprintf("%d", l.Count)
+			switch {
+			case l.Covered == 0:
+				class = "cov0"
+			case l.Covered < l.Statements:
+				class = "covpart"
+			default:
+				class = "cov"
+			}
+		}
+		fmt.Fprintf(buf, "<span class=\"num\">%d</span><span class=\"count\">%s</span>", n, count)
+		if class != "" {
+			fmt.Fprintf(buf, "<span class=\"%s\">%s</span>\n", class, html.EscapeString(text))
+		} else {
+			fmt.Fprintf(buf, "%s\n", html.EscapeString(text))
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// WriteLCOV writes a profile as an lcov tracefile, which most coverage
+// services and editors can read. lcov counts lines instead of statements,
+// so a line ran as many times as the statement on it that ran the most
+func WriteLCOV(w io.Writer, p *Profile) error {
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, "TN:\n")
+	for _, f := range p.SortedFiles() {
+		lines := f.Lines()
+		numbers := make([]int, 0, len(lines))
+		for n := range lines {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+
+		hit := 0
+		fmt.Fprintf(buf, "SF:%s\n", f.Name)
+		for _, n := range numbers {
+			fmt.Fprintf(buf, "DA:%d,%d\n", n, lines[n].Count)
+			if lines[n].Count > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(buf, "LF:%d\n", len(numbers))
+		fmt.Fprintf(buf, "LH:%d\n", hit)
+		fmt.Fprintf(buf, "end_of_record\n")
+	}
+	return buf.Flush()
+}
@@ -0,0 +1,312 @@
+// Package doc builds browsable documentation for geode packages. The
+// documentation of a function, class, class field or global variable is
+// the block of `#` comments written on the lines directly above it. Any
+// `pkg:name` reference in a comment or signature is turned into a link
+// when the package it names is part of the same set of documentation.
+package doc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/ast"
+	"github.com/geode-lang/geode/pkg/lexer"
+)
+
+// Package is the documentation for a single geode package
+type Package struct {
+	Name      string
+	Dir       string
+	Functions []Func
+	Classes   []Class
+	Globals   []Global
+
+	names map[string]bool // every name the package documents
+}
+
+// Func is the documentation for a function or method
+type Func struct {
+	Name      string
+	Signature string
+	Doc       string
+}
+
+// Class is the documentation for a class, its fields and methods
+type Class struct {
+	Name    string
+	Doc     string
+	Fields  []Field
+	Methods []Func
+}
+
+// Field is a single variable in a class
+type Field struct {
+	Name string
+	Type string
+	Doc  string
+}
+
+// Global is a global variable
+type Global struct {
+	Name      string
+	Signature string
+	Doc       string
+}
+
+// Load parses every geode file in a directory and collects the
+// documentation of the package they make up
+func Load(dir string) (*Package, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.g"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no geode source files in %s", dir)
+	}
+	sort.Strings(files)
+
+	pkg := &Package{Dir: dir, names: make(map[string]bool)}
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		source, _ := lexer.NewSourcefile(file)
+		source.LoadBytes(src)
+		nodes := ast.Parse(lexer.Lex(source))
+
+		name, err := ast.NamespaceFromNodes(nodes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+		if pkg.Name != "" && pkg.Name != name {
+			return nil, fmt.Errorf("%s: found package %s, expected %s", file, name, pkg.Name)
+		}
+		pkg.Name = name
+		pkg.add(nodes)
+	}
+
+	sort.Slice(pkg.Functions, func(i, j int) bool { return pkg.Functions[i].Name < pkg.Functions[j].Name })
+	sort.Slice(pkg.Classes, func(i, j int) bool { return pkg.Classes[i].Name < pkg.Classes[j].Name })
+	sort.Slice(pkg.Globals, func(i, j int) bool { return pkg.Globals[i].Name < pkg.Globals[j].Name })
+	return pkg, nil
+}
+
+// internal returns if a name is only meant to be used by the compiler
+func internal(name string) bool {
+	return strings.HasPrefix(name, "__")
+}
+
+func (pkg *Package) add(nodes []ast.Node) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case ast.FunctionNode:
+			if internal(n.Name.Value) {
+				continue
+			}
+			pkg.Functions = append(pkg.Functions, Func{n.Name.Value, n.Signature(), n.Doc})
+			pkg.names[n.Name.Value] = true
+
+		case ast.ClassNode:
+			class := Class{Name: n.Name, Doc: n.Doc}
+			for _, field := range n.Variables {
+				class.Fields = append(class.Fields, Field{field.Name.Value, field.Typ.String(), field.Doc})
+			}
+			for _, method := range n.Methods {
+				class.Methods = append(class.Methods, Func{method.Name.Value, method.Signature(), method.Doc})
+				pkg.names[n.Name+"."+method.Name.Value] = true
+			}
+			pkg.Classes = append(pkg.Classes, class)
+			pkg.names[n.Name] = true
+
+		case ast.GlobalVariableDeclNode:
+			signature := fmt.Sprintf("%s %s", n.Type, n.Name)
+			pkg.Globals = append(pkg.Globals, Global{n.Name.Value, signature, n.Doc})
+			pkg.names[n.Name.Value] = true
+		}
+	}
+}
+
+// Set is the group of packages that are documented together.
+// References between them are turned into links
+type Set struct {
+	Packages []*Package
+}
+
+// LoadAll loads the documentation of every package directory in a
+// library directory, like the installed standard library
+func LoadAll(lib string) (*Set, error) {
+	entries, err := ioutil.ReadDir(lib)
+	if err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(lib, entry.Name())
+		if files, _ := filepath.Glob(filepath.Join(dir, "*.g")); len(files) > 0 {
+			dirs = append(dirs, dir)
+		}
+	}
+	return LoadDirs(dirs)
+}
+
+// LoadDirs loads the documentation of the packages in each directory
+func LoadDirs(dirs []string) (*Set, error) {
+	set := &Set{}
+	for _, dir := range dirs {
+		pkg, err := Load(dir)
+		if err != nil {
+			return nil, err
+		}
+		set.Packages = append(set.Packages, pkg)
+	}
+	sort.Slice(set.Packages, func(i, j int) bool { return set.Packages[i].Name < set.Packages[j].Name })
+	return set, nil
+}
+
+// Package returns the package in the set with some name
+func (s *Set) Package(name string) *Package {
+	for _, pkg := range s.Packages {
+		if pkg.Name == name {
+			return pkg
+		}
+	}
+	return nil
+}
+
+// refPattern matches references like `str:len`, `c:FILE` or `runtime:TypeInfo`
+var refPattern = regexp.MustCompile(`\b([a-z_][a-z0-9_]*):([A-Za-z_][A-Za-z0-9_']*(?:\.[A-Za-z_][A-Za-z0-9_']*)?)`)
+
+// typePattern matches the bare names in a signature, which may refer to a class in the same package
+var typePattern = regexp.MustCompile(`\b[A-Z][A-Za-z0-9_]*\b`)
+
+// resolve returns the package and anchor a `pkg:name` reference points
+// to, or false if it isn't documented in the set
+func (s *Set) resolve(ref string) (*Package, string, bool) {
+	parts := refPattern.FindStringSubmatch(ref)
+	if parts == nil {
+		return nil, "", false
+	}
+	pkg := s.Package(parts[1])
+	if pkg == nil || !pkg.names[parts[2]] {
+		return nil, "", false
+	}
+	return pkg, parts[2], true
+}
+
+// linkify replaces the references in some text with links. The text is
+// escaped and the links are built by the functions passed in
+func (s *Set) linkify(from *Package, text string, escape func(string) string, link func(text, page, anchor string) string, signature bool) string {
+	out := &strings.Builder{}
+	last := 0
+	matches := refPattern.FindAllStringIndex(text, -1)
+	if signature {
+		matches = mergeMatches(matches, typePattern.FindAllStringIndex(text, -1))
+	}
+	for _, m := range matches {
+		ref := text[m[0]:m[1]]
+		var pkg *Package
+		anchor := ref
+		ok := false
+		if strings.Contains(ref, ":") {
+			pkg, anchor, ok = s.resolve(ref)
+		} else if from.names[ref] {
+			pkg, ok = from, true
+		}
+		if !ok {
+			continue
+		}
+		out.WriteString(escape(text[last:m[0]]))
+		out.WriteString(link(escape(ref), pkg.Name, anchor))
+		last = m[1]
+	}
+	out.WriteString(escape(text[last:]))
+	return out.String()
+}
+
+// mergeMatches combines two sets of match indexes, dropping any
+// match from the second set that overlaps one from the first
+func mergeMatches(a, b [][]int) [][]int {
+	res := append([][]int{}, a...)
+outer:
+	for _, m := range b {
+		for _, o := range a {
+			if m[0] < o[1] && o[0] < m[1] {
+				continue outer
+			}
+		}
+		res = append(res, m)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i][0] < res[j][0] })
+	return res
+}
+
+// Write renders the set into a directory as html or markdown, with
+// an index page listing every package and a page for each of them.
+// A directory of - prints the pages instead
+func (s *Set) Write(dir string, format string) error {
+	var r renderer
+	switch format {
+	case "html":
+		r = htmlRenderer{s}
+	case "markdown", "md":
+		r = markdownRenderer{s}
+	default:
+		return fmt.Errorf("unknown documentation format %q", format)
+	}
+
+	// The pages are printed one after another when the directory is -
+	if dir != "-" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	write := func(name string, render func(*strings.Builder)) error {
+		buf := &strings.Builder{}
+		render(buf)
+		if dir == "-" {
+			_, err := fmt.Fprintln(os.Stdout, buf.String())
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dir, name+r.ext()), []byte(buf.String()), 0644)
+	}
+
+	if err := write("index", r.index); err != nil {
+		return err
+	}
+	for _, pkg := range s.Packages {
+		pkg := pkg
+		if err := write(pkg.Name, func(buf *strings.Builder) { r.page(buf, pkg) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderer renders the pages of a documentation set in some format
+type renderer interface {
+	ext() string
+	index(*strings.Builder)
+	page(*strings.Builder, *Package)
+}
+
+// summary returns the first line of some documentation, skipping
+// lines like `str:len` that only repeat the name
+func summary(doc string) string {
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && refPattern.FindString(line) != line {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package doc
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+const htmlStyle = `body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; color: #222; }
+a { color: #2a5db0; text-decoration: none; }
+a:hover { text-decoration: underline; }
+pre.signature { background: #f4f4f4; padding: 0.5em 1em; border-radius: 3px; }
+p.doc { white-space: pre-line; }
+table.fields td { padding: 0.1em 1em 0.1em 0; vertical-align: top; }
+h3 { margin-top: 2em; }
+h4 { margin-top: 1.5em; }`
+
+// htmlRenderer renders documentation as static html pages
+type htmlRenderer struct {
+	set *Set
+}
+
+func (r htmlRenderer) ext() string { return ".html" }
+
+func (r htmlRenderer) header(buf *strings.Builder, title string) {
+	fmt.Fprintf(buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintf(buf, "<style>\n%s\n</style>\n</head>\n<body>\n", htmlStyle)
+}
+
+func (r htmlRenderer) footer(buf *strings.Builder) {
+	fmt.Fprintf(buf, "</body>\n</html>\n")
+}
+
+func (r htmlRenderer) link(text, page, anchor string) string {
+	return fmt.Sprintf("<a href=\"%s.html#%s\">%s</a>", page, html.EscapeString(anchor), text)
+}
+
+func (r htmlRenderer) text(pkg *Package, s string, signature bool) string {
+	return r.set.linkify(pkg, s, html.EscapeString, r.link, signature)
+}
+
+func (r htmlRenderer) doc(buf *strings.Builder, pkg *Package, doc string) {
+	if doc != "" {
+		fmt.Fprintf(buf, "<p class=\"doc\">%s</p>\n", r.text(pkg, doc, false))
+	}
+}
+
+func (r htmlRenderer) index(buf *strings.Builder) {
+	r.header(buf, "Geode Packages")
+	fmt.Fprintf(buf, "<h1>Packages</h1>\n<table class=\"fields\">\n")
+	for _, pkg := range r.set.Packages {
+		count := len(pkg.Functions) + len(pkg.Classes) + len(pkg.Globals)
+		fmt.Fprintf(buf, "<tr><td><a href=\"%s.html\">%s</a></td><td>%d declarations</td></tr>\n", pkg.Name, pkg.Name, count)
+	}
+	fmt.Fprintf(buf, "</table>\n")
+	r.footer(buf)
+}
+
+func (r htmlRenderer) page(buf *strings.Builder, pkg *Package) {
+	r.header(buf, "package "+pkg.Name)
+	fmt.Fprintf(buf, "<p><a href=\"index.html\">Packages</a></p>\n")
+	fmt.Fprintf(buf, "<h1>package %s</h1>\n", pkg.Name)
+	fmt.Fprintf(buf, "<pre>include \"%s\"</pre>\n", pkg.Name)
+
+	// A short index of the page before the full documentation
+	fmt.Fprintf(buf, "<h2>Index</h2>\n<ul>\n")
+	for _, g := range pkg.Globals {
+		fmt.Fprintf(buf, "<li><a href=\"#%s\">%s</a></li>\n", g.Name, html.EscapeString(g.Signature))
+	}
+	for _, c := range pkg.Classes {
+		fmt.Fprintf(buf, "<li><a href=\"#%s\">class %s</a></li>\n", c.Name, c.Name)
+	}
+	for _, f := range pkg.Functions {
+		fmt.Fprintf(buf, "<li><a href=\"#%s\">%s</a></li>\n", f.Name, html.EscapeString(f.Signature))
+	}
+	fmt.Fprintf(buf, "</ul>\n")
+
+	if len(pkg.Globals) > 0 {
+		fmt.Fprintf(buf, "<h2>Globals</h2>\n")
+		for _, g := range pkg.Globals {
+			fmt.Fprintf(buf, "<h3 id=\"%s\">%s</h3>\n", g.Name, g.Name)
+			fmt.Fprintf(buf, "<pre class=\"signature\">%s</pre>\n", r.text(pkg, g.Signature, true))
+			r.doc(buf, pkg, g.Doc)
+		}
+	}
+
+	if len(pkg.Classes) > 0 {
+		fmt.Fprintf(buf, "<h2>Classes</h2>\n")
+		for _, c := range pkg.Classes {
+			fmt.Fprintf(buf, "<h3 id=\"%s\">class %s</h3>\n", c.Name, c.Name)
+			r.doc(buf, pkg, c.Doc)
+			if len(c.Fields) > 0 {
+				fmt.Fprintf(buf, "<table class=\"fields\">\n")
+				for _, f := range c.Fields {
+					fmt.Fprintf(buf, "<tr><td><code>%s %s</code></td><td>%s</td></tr>\n", r.text(pkg, f.Type, true), f.Name, r.text(pkg, f.Doc, false))
+				}
+				fmt.Fprintf(buf, "</table>\n")
+			}
+			for _, m := range c.Methods {
+				fmt.Fprintf(buf, "<h4 id=\"%s.%s\">%s.%s</h4>\n", c.Name, m.Name, c.Name, m.Name)
+				fmt.Fprintf(buf, "<pre class=\"signature\">%s</pre>\n", r.text(pkg, m.Signature, true))
+				r.doc(buf, pkg, m.Doc)
+			}
+		}
+	}
+
+	if len(pkg.Functions) > 0 {
+		fmt.Fprintf(buf, "<h2>Functions</h2>\n")
+		for _, f := range pkg.Functions {
+			fmt.Fprintf(buf, "<h3 id=\"%s\">%s</h3>\n", f.Name, f.Name)
+			fmt.Fprintf(buf, "<pre class=\"signature\">%s</pre>\n", r.text(pkg, f.Signature, true))
+			r.doc(buf, pkg, f.Doc)
+		}
+	}
+	r.footer(buf)
+}
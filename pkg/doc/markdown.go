@@ -0,0 +1,95 @@
+package doc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// markdownRenderer renders documentation as markdown files
+type markdownRenderer struct {
+	set *Set
+}
+
+func (r markdownRenderer) ext() string { return ".md" }
+
+// anchorPattern matches the characters markdown renderers drop from heading anchors
+var anchorPattern = regexp.MustCompile(`[^a-z0-9_ -]`)
+
+func (r markdownRenderer) link(text, page, anchor string) string {
+	anchor = anchorPattern.ReplaceAllString(strings.ToLower(anchor), "")
+	return fmt.Sprintf("[%s](%s.md#%s)", text, page, anchor)
+}
+
+// escape keeps characters in documentation from being read as markdown
+func (r markdownRenderer) escape(s string) string {
+	return strings.NewReplacer("*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", "&lt;").Replace(s)
+}
+
+func (r markdownRenderer) doc(buf *strings.Builder, pkg *Package, doc string) {
+	if doc == "" {
+		return
+	}
+	// Hard line breaks keep the layout of the comment
+	lines := strings.Split(r.set.linkify(pkg, doc, r.escape, r.link, false), "\n")
+	fmt.Fprintf(buf, "%s\n\n", strings.Join(lines, "  \n"))
+}
+
+// signature writes a signature as a code block, which can't contain links
+func (r markdownRenderer) signature(buf *strings.Builder, s string) {
+	fmt.Fprintf(buf, "```\n%s\n```\n\n", s)
+}
+
+func (r markdownRenderer) index(buf *strings.Builder) {
+	fmt.Fprintf(buf, "# Packages\n\n")
+	fmt.Fprintf(buf, "| Package | Declarations |\n| --- | --- |\n")
+	for _, pkg := range r.set.Packages {
+		count := len(pkg.Functions) + len(pkg.Classes) + len(pkg.Globals)
+		fmt.Fprintf(buf, "| [%s](%s.md) | %d |\n", pkg.Name, pkg.Name, count)
+	}
+}
+
+func (r markdownRenderer) page(buf *strings.Builder, pkg *Package) {
+	fmt.Fprintf(buf, "# package %s\n\n", pkg.Name)
+	fmt.Fprintf(buf, "[Packages](index.md)\n\n")
+	r.signature(buf, fmt.Sprintf("include \"%s\"", pkg.Name))
+
+	if len(pkg.Globals) > 0 {
+		fmt.Fprintf(buf, "## Globals\n\n")
+		for _, g := range pkg.Globals {
+			fmt.Fprintf(buf, "### %s\n\n", r.escape(g.Name))
+			r.signature(buf, g.Signature)
+			r.doc(buf, pkg, g.Doc)
+		}
+	}
+
+	if len(pkg.Classes) > 0 {
+		fmt.Fprintf(buf, "## Classes\n\n")
+		for _, c := range pkg.Classes {
+			fmt.Fprintf(buf, "### %s\n\n", r.escape(c.Name))
+			r.doc(buf, pkg, c.Doc)
+			if len(c.Fields) > 0 {
+				fmt.Fprintf(buf, "| Field | Type | |\n| --- | --- | --- |\n")
+				for _, f := range c.Fields {
+					doc := strings.Replace(r.set.linkify(pkg, f.Doc, r.escape, r.link, false), "\n", " ", -1)
+					fmt.Fprintf(buf, "| %s | %s | %s |\n", r.escape(f.Name), r.set.linkify(pkg, f.Type, r.escape, r.link, true), doc)
+				}
+				fmt.Fprintf(buf, "\n")
+			}
+			for _, m := range c.Methods {
+				fmt.Fprintf(buf, "#### %s.%s\n\n", r.escape(c.Name), r.escape(m.Name))
+				r.signature(buf, m.Signature)
+				r.doc(buf, pkg, m.Doc)
+			}
+		}
+	}
+
+	if len(pkg.Functions) > 0 {
+		fmt.Fprintf(buf, "## Functions\n\n")
+		for _, f := range pkg.Functions {
+			fmt.Fprintf(buf, "### %s\n\n", r.escape(f.Name))
+			r.signature(buf, f.Signature)
+			r.doc(buf, pkg, f.Doc)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+type diffLine struct {
+	op   diffmatchpatch.Operation
+	text string
+}
+
+// Diff returns a unified diff from the source before formatting to
+// the source after it, or an empty string if they are the same
+func Diff(name string, before, after []byte) string {
+	if bytes.Equal(before, after) {
+		return ""
+	}
+
+	dmp := diffmatchpatch.New()
+	a, b, lines := dmp.DiffLinesToChars(string(before), string(after))
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(a, b, false), lines)
+
+	all := make([]diffLine, 0)
+	for _, d := range diffs {
+		text := strings.TrimSuffix(d.Text, "\n")
+		for _, line := range strings.Split(text, "\n") {
+			all = append(all, diffLine{d.Type, line})
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", name, name)
+
+	// oldLine and newLine are the 1 based line numbers of all[i] in each file
+	oldLine, newLine := 1, 1
+	for i := 0; i < len(all); {
+		if all[i].op == diffmatchpatch.DiffEqual {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+
+		// Grow the hunk until the changes are separated by enough unchanged lines
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(all) {
+			if all[end].op != diffmatchpatch.DiffEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(all) && all[run].op == diffmatchpatch.DiffEqual {
+				run++
+			}
+			if run == len(all) || run-end > 2*diffContext {
+				end += min(diffContext, run-end)
+				break
+			}
+			end = run
+		}
+
+		oldStart, newStart := oldLine-(i-start), newLine-(i-start)
+		oldCount, newCount := 0, 0
+		hunk := &bytes.Buffer{}
+		for _, line := range all[start:end] {
+			switch line.op {
+			case diffmatchpatch.DiffEqual:
+				fmt.Fprintf(hunk, " %s\n", line.text)
+				oldCount++
+				newCount++
+			case diffmatchpatch.DiffDelete:
+				fmt.Fprintf(hunk, "-%s\n", line.text)
+				oldCount++
+			case diffmatchpatch.DiffInsert:
+				fmt.Fprintf(hunk, "+%s\n", line.text)
+				newCount++
+			}
+		}
+		fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		buf.Write(hunk.Bytes())
+
+		// Move past the hunk, keeping track of the line numbers
+		for _, line := range all[i:end] {
+			if line.op != diffmatchpatch.DiffInsert {
+				oldLine++
+			}
+			if line.op != diffmatchpatch.DiffDelete {
+				newLine++
+			}
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,455 @@
+// Package format prints geode source code in its canonical form.
+// The formatter works on the token stream rather than the ast so that
+// comments, which the parser throws away, survive formatting. Line
+// breaks are kept where the author put them, but indentation, spacing
+// around operators, blank lines and semicolons are all normalised.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/geode-lang/geode/pkg/lexer"
+)
+
+// Options changes how source is formatted
+type Options struct {
+	// ASCII rewrites unicode operator aliases like ≠ to their
+	// ascii spelling using the lexer's alias table
+	ASCII bool
+}
+
+// Source formats the source of a single file. The name is only used for
+// error messages. An error is returned if the formatted source would
+// not lex to the same program, in which case nothing should be written
+func Source(name string, src []byte, opts Options) ([]byte, error) {
+	text, toks := lex(name, src)
+
+	p := &printer{opts: opts, text: text}
+	for i := range toks {
+		p.print(toks, i)
+	}
+	p.finish()
+
+	out := p.buf.Bytes()
+	if err := verify(name, toks, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func lex(name string, src []byte) (string, []lexer.Token) {
+	source, _ := lexer.NewSourcefile(name)
+	source.LoadBytes(src)
+	return source.String(), lexer.Lex(source)
+}
+
+// verify makes sure the formatted output lexes to the same tokens as the
+// input, ignoring semicolons and trailing space in comments
+func verify(name string, before []lexer.Token, after []byte) error {
+	_, toks := lex(name, after)
+	a, b := significant(before), significant(toks)
+	for i := range a {
+		if i >= len(b) || a[i].Type != b[i].Type || a[i].Value != b[i].Value {
+			return fmt.Errorf("%s:%d: formatting would change the meaning of the program near %q", name, a[i].Line, a[i].Value)
+		}
+	}
+	if len(b) > len(a) {
+		return fmt.Errorf("%s: formatting would add tokens to the program", name)
+	}
+	return nil
+}
+
+func significant(toks []lexer.Token) []lexer.Token {
+	res := make([]lexer.Token, 0, len(toks))
+	for _, tok := range toks {
+		if tok.Is(lexer.TokSemiColon) {
+			continue
+		}
+		if tok.Is(lexer.TokComment) {
+			tok.Value = strings.TrimRight(tok.Value, " \t\r")
+		}
+		res = append(res, tok)
+	}
+	return res
+}
+
+// printer writes tokens to a buffer one at a time, deciding the
+// whitespace that goes before each one
+type printer struct {
+	opts Options
+	text string
+	buf  bytes.Buffer
+
+	last  *lexer.Token // the last token printed, including comments
+	prev  *lexer.Token // the last token printed that isn't a comment
+	depth int          // the number of open curly braces
+	paren int          // the number of open parens and square braces
+
+	unary  bool // the prev token is a unary operator
+	suffix bool // the prev token is a pointer or unknown type modifier
+
+	// literal is the number of open class literals, like `Point{x: 1}`,
+	// whose braces are printed like parens instead of blocks
+	literal  int
+	litOpen  bool // the prev token opens a class literal
+	litClose bool // the prev token closes a class literal
+
+	// breakNext is set when the next token must start a new line
+	breakNext bool
+	// stmtType is the type of the first token in the current statement
+	stmtType lexer.TokenType
+	// forHeader is set between a `for` and its block, where
+	// semicolons separate the clauses instead of ending lines
+	forHeader bool
+}
+
+// spelling returns how a token should be written
+func (p *printer) spelling(tok lexer.Token) string {
+	if tok.Is(lexer.TokComment) {
+		return strings.TrimRight(tok.Value, " \t\r")
+	}
+	if p.opts.ASCII {
+		return tok.Value
+	}
+	return p.text[tok.Pos:tok.EndPos]
+}
+
+// newlines returns how many line breaks the author put before a token
+func (p *printer) newlines(tok lexer.Token) int {
+	if p.last == nil {
+		return 0
+	}
+	return strings.Count(p.text[p.last.EndPos:tok.Pos], "\n")
+}
+
+func (p *printer) print(toks []lexer.Token, i int) {
+	tok := toks[i]
+	newlines := p.newlines(tok)
+
+	if tok.Is(lexer.TokSemiColon) {
+		p.semicolon(&toks[i])
+		return
+	}
+
+	// Comments that follow code on the same line stay there
+	if tok.Is(lexer.TokComment) && p.last != nil && newlines == 0 {
+		if !p.continues() && p.needsTerminator(nextCode(toks, i)) {
+			p.write(";")
+		}
+		p.write(" ")
+		p.write(p.spelling(tok))
+		p.last = &toks[i]
+		p.breakNext = true
+		return
+	}
+
+	brk := p.breakNext || newlines > 0
+	if p.last == nil {
+		brk = false
+	}
+	openLit := tok.Is(lexer.TokLeftCurly) && opensLiteral(toks, i)
+	closeLit := p.closesLiteral(tok)
+
+	// Opening braces and else are always joined onto the line before
+	if p.prev != nil && p.last == p.prev {
+		if tok.Is(lexer.TokLeftCurly) || (tok.Is(lexer.TokElse) && p.prev.Is(lexer.TokRightCurly)) {
+			brk = false
+		}
+	}
+	// Empty blocks are written as {}
+	if tok.Is(lexer.TokRightCurly) && p.last != nil && p.last.Is(lexer.TokLeftCurly) {
+		brk = false
+	}
+	// Every other closing brace goes on its own line
+	if tok.Is(lexer.TokRightCurly) && !closeLit && p.last != nil && !p.last.Is(lexer.TokLeftCurly) {
+		brk = true
+	}
+
+	if brk {
+		continues := p.continues()
+		if !continues && p.needsTerminator(tok) {
+			p.write(";")
+		}
+		if tok.Is(lexer.TokRightCurly) && !closeLit {
+			p.depth--
+		}
+		p.write("\n")
+		// Keep at most one blank line, and none at the start or end of a block
+		if newlines > 1 && !p.last.Is(lexer.TokLeftCurly) && !tok.Is(lexer.TokRightCurly) {
+			p.write("\n")
+		}
+		indent := p.depth
+		if continues && !(p.paren == 1 && (closes(tok) || closeLit)) {
+			indent++
+		}
+		p.write(strings.Repeat("\t", indent))
+		if !continues {
+			p.stmtType = tok.Type
+		}
+	} else {
+		if tok.Is(lexer.TokRightCurly) && !closeLit {
+			p.depth--
+		}
+		if p.last == nil {
+			p.stmtType = tok.Type
+		} else if !openLit && p.spaceBefore(tok) {
+			p.write(" ")
+		}
+	}
+
+	p.write(p.spelling(tok))
+	p.breakNext = false
+	p.last = &toks[i]
+
+	if tok.Is(lexer.TokComment) {
+		p.breakNext = true
+		return
+	}
+
+	p.unary = tok.Is(lexer.TokOper) && !p.endsOperand()
+	p.suffix = p.isSuffix(tok)
+	p.litOpen, p.litClose = openLit, closeLit
+	p.prev = &toks[i]
+
+	switch tok.Type {
+	case lexer.TokLeftParen, lexer.TokLeftBrace:
+		if tok.Value == "]" {
+			// the lexer gives closing square braces the opening brace type
+			p.paren--
+		} else {
+			p.paren++
+		}
+	case lexer.TokRightParen, lexer.TokRightBrace:
+		p.paren--
+	case lexer.TokFor:
+		p.forHeader = true
+	case lexer.TokLeftCurly:
+		if openLit {
+			p.literal++
+			p.paren++
+			break
+		}
+		p.depth++
+		p.forHeader = false
+		p.breakNext = true
+	case lexer.TokRightCurly:
+		if closeLit {
+			p.literal--
+			p.paren--
+			break
+		}
+		p.breakNext = true
+	}
+	if p.paren < 0 {
+		p.paren = 0
+	}
+}
+
+// semicolon writes a semicolon unless it is redundant
+func (p *printer) semicolon(tok *lexer.Token) {
+	if p.forHeader && p.paren == 0 {
+		p.write(";")
+		p.prev, p.last = tok, tok
+		p.unary, p.suffix = false, false
+		p.litOpen, p.litClose = false, false
+		return
+	}
+	if p.prev == nil || p.prev.Is(lexer.TokLeftCurly, lexer.TokSemiColon) || (p.prev.Is(lexer.TokRightCurly) && !p.litClose) {
+		return
+	}
+	if p.last.Is(lexer.TokComment) {
+		// the statement was already terminated before the comment
+		return
+	}
+	p.write(";")
+	p.prev, p.last = tok, tok
+	p.unary, p.suffix = false, false
+	p.litOpen, p.litClose = false, false
+	p.breakNext = true
+}
+
+// nextCode returns the first token after i that isn't a comment
+func nextCode(toks []lexer.Token, i int) lexer.Token {
+	for _, tok := range toks[i+1:] {
+		if !tok.Is(lexer.TokComment) {
+			return tok
+		}
+	}
+	return lexer.Token{}
+}
+
+// prevCode returns the index of the last token before i that isn't a
+// comment, or -1 if there is none
+func prevCode(toks []lexer.Token, i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if !toks[j].Is(lexer.TokComment) {
+			return j
+		}
+	}
+	return -1
+}
+
+// isLabel returns if a token names a field in a class literal, which the
+// lexer reads as an identifier ending in a colon
+func isLabel(tok lexer.Token) bool {
+	return tok.Is(lexer.TokIdent) && len(tok.Value) > 1 && strings.HasSuffix(tok.Value, ":")
+}
+
+// opensLiteral returns if the curly brace at i opens a class literal rather
+// than a block. Like the parser, it looks for a type before the brace and a
+// field name or closing brace after it. A class or function header can end
+// in a type too, like `class Empty {}`, so the type has to follow something
+// a value can
+func opensLiteral(toks []lexer.Token, i int) bool {
+	typ := prevCode(toks, i)
+	if typ < 0 || !toks[typ].Is(lexer.TokType) {
+		return false
+	}
+	if next := nextCode(toks, i); !next.Is(lexer.TokRightCurly) && !isLabel(next) {
+		return false
+	}
+	before := prevCode(toks, typ)
+	if before < 0 {
+		return false
+	}
+	b := toks[before]
+	return b.Is(lexer.TokOper, lexer.TokLeftParen, lexer.TokComma, lexer.TokReturn) || (b.Is(lexer.TokLeftBrace) && b.Value == "[") || isLabel(b)
+}
+
+// closesLiteral returns if tok closes a class literal. Literals can't hold
+// blocks, so any closing brace inside one is its own
+func (p *printer) closesLiteral(tok lexer.Token) bool {
+	return tok.Is(lexer.TokRightCurly) && p.literal > 0
+}
+
+// closes returns if tok closes a paren or square brace
+func closes(tok lexer.Token) bool {
+	return tok.Is(lexer.TokRightParen, lexer.TokRightBrace) || (tok.Is(lexer.TokLeftBrace) && tok.Value == "]")
+}
+
+// continues returns if the line being ended is part of a longer statement
+func (p *printer) continues() bool {
+	if p.prev == nil || p.paren > 0 {
+		return p.paren > 0
+	}
+	if p.prev.Is(lexer.TokOper) {
+		return !p.suffix
+	}
+	return p.prev.Is(lexer.TokComma, lexer.TokDot, lexer.TokAs)
+}
+
+// needsTerminator returns if the statement that ends before tok
+// should have a semicolon written after it
+func (p *printer) needsTerminator(tok lexer.Token) bool {
+	if p.forHeader || p.prev == nil || p.last.Is(lexer.TokComment) {
+		return false
+	}
+	if p.depth == 0 && p.stmtType != lexer.TokType {
+		// only global variables need terminating at the top level
+		return false
+	}
+	if !p.endsOperand() && !p.prev.Is(lexer.TokReturn) {
+		return false
+	}
+	return !tok.Is(lexer.TokLeftCurly, lexer.TokElse, lexer.TokDot, lexer.TokAs, lexer.TokElipsis, lexer.TokQuestionMark, lexer.TokSemiColon)
+}
+
+// endsOperand returns if the prev token can be the end of a value, so
+// an operator after it must be binary and not unary
+func (p *printer) endsOperand() bool {
+	if p.prev == nil {
+		return false
+	}
+	if isLabel(*p.prev) {
+		return false
+	}
+	switch p.prev.Type {
+	case lexer.TokRightCurly:
+		return p.litClose
+	case lexer.TokIdent, lexer.TokType, lexer.TokNumber, lexer.TokString, lexer.TokChar,
+		lexer.TokBool, lexer.TokNil, lexer.TokRightParen, lexer.TokRightBrace, lexer.TokQuestionMark:
+		return true
+	case lexer.TokLeftBrace:
+		return p.prev.Value == "]"
+	case lexer.TokOper:
+		return p.suffix
+	}
+	return false
+}
+
+// isSuffix returns if tok modifies the type before it, like the star in `int*`
+// or the bang in `int!`
+func (p *printer) isSuffix(tok lexer.Token) bool {
+	if tok.Is(lexer.TokQuestionMark) {
+		return true
+	}
+	if !tok.Is(lexer.TokOper) || (tok.Value != "*" && tok.Value != "!") || p.prev == nil {
+		return false
+	}
+	return p.prev.Is(lexer.TokType) || p.suffix
+}
+
+// spaceBefore returns if a space separates tok from the token before it on the same line
+func (p *printer) spaceBefore(tok lexer.Token) bool {
+	prev := p.prev
+	if p.last.Is(lexer.TokComment) || prev == nil {
+		return true
+	}
+
+	if closes(tok) || p.closesLiteral(tok) || tok.Is(lexer.TokComma, lexer.TokSemiColon) {
+		return false
+	}
+	if tok.Is(lexer.TokRightCurly) && prev.Is(lexer.TokLeftCurly) {
+		return false
+	}
+	opening := prev.Is(lexer.TokLeftParen) || (prev.Is(lexer.TokLeftBrace) && prev.Value == "[") || p.litOpen
+	if opening {
+		return false
+	}
+	if tok.Is(lexer.TokDot, lexer.TokNamespaceAccess) || prev.Is(lexer.TokDot, lexer.TokNamespaceAccess) {
+		return false
+	}
+	if p.isSuffix(tok) {
+		return false
+	}
+
+	switch {
+	case tok.Is(lexer.TokLeftParen) && !p.unary:
+		// calls, format strings and info(T) hug their arguments, keywords and operators don't
+		return !prev.Is(lexer.TokIdent, lexer.TokType, lexer.TokString, lexer.TokRightParen, lexer.TokInfo) && !p.closesSquare()
+	case tok.Is(lexer.TokLeftBrace):
+		// indexing hugs the value, array literals don't
+		return !p.endsOperand()
+	}
+
+	// Unary operators hug their operand. A minus is only joined to a paren
+	// because the lexer reads `-x` as a single number token
+	if p.unary {
+		return (prev.Value == "-" || prev.Value == "+") && !tok.Is(lexer.TokLeftParen)
+	}
+	return true
+}
+
+func (p *printer) closesSquare() bool {
+	return p.prev.Is(lexer.TokLeftBrace, lexer.TokRightBrace) && p.prev.Value == "]"
+}
+
+func (p *printer) write(s string) {
+	p.buf.WriteString(s)
+}
+
+// finish ends the last statement and makes sure the file ends in a single newline
+func (p *printer) finish() {
+	if p.last == nil {
+		return
+	}
+	if !p.last.Is(lexer.TokComment) && !p.continues() && p.needsTerminator(lexer.Token{}) {
+		p.write(";")
+	}
+	out := bytes.TrimRight(p.buf.Bytes(), " \t\n")
+	p.buf.Reset()
+	p.buf.Write(out)
+	p.buf.WriteString("\n")
+}
@@ -0,0 +1,131 @@
+package gtypes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Layout is how a target lays types out in memory, as its llvm datalayout
+// describes. Alignments and sizes are in bytes
+type Layout struct {
+	BigEndian      bool
+	PointerSize    int
+	PointerAlign   int
+	AggregateAlign int // the least alignment of a struct
+
+	ints    map[int]int // the alignment of the integer types of a bit size
+	floats  map[int]int // the alignment of the floating point types of a bit size
+	vectors map[int]int // the alignment of the vector types of a bit size
+}
+
+// CurrentLayout is the layout of the target being compiled for
+var CurrentLayout, _ = ParseLayout("")
+
+// ParseLayout reads an llvm datalayout string. The alignments it leaves
+// out are the ones llvm uses when a datalayout doesn't have them
+func ParseLayout(datalayout string) (*Layout, error) {
+	l := &Layout{
+		PointerSize:    8,
+		PointerAlign:   8,
+		AggregateAlign: 1,
+		ints:           map[int]int{1: 1, 8: 1, 16: 2, 32: 4, 64: 4},
+		floats:         map[int]int{16: 2, 32: 4, 64: 8, 128: 16},
+		vectors:        map[int]int{64: 8, 128: 16},
+	}
+	if datalayout == "" {
+		return l, nil
+	}
+
+	for _, spec := range strings.Split(datalayout, "-") {
+		if spec == "" {
+			continue
+		}
+		fields := strings.Split(spec[1:], ":")
+		// Every spec after its letter is a list of bit counts, apart from
+		// the ones whose values we don't need, which are skipped
+		bits := func(i int) (int, error) {
+			if i >= len(fields) {
+				return 0, fmt.Errorf("invalid datalayout spec %q", spec)
+			}
+			n, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return 0, fmt.Errorf("invalid datalayout spec %q", spec)
+			}
+			return n, nil
+		}
+
+		var err error
+		switch spec[0] {
+		case 'e':
+			l.BigEndian = false
+		case 'E':
+			l.BigEndian = true
+		case 'p':
+			// Only the default address space is used
+			if fields[0] != "" && fields[0] != "0" {
+				continue
+			}
+			var size, align int
+			if size, err = bits(1); err == nil {
+				align, err = bits(2)
+			}
+			l.PointerSize, l.PointerAlign = size/8, align/8
+		case 'i', 'f', 'v':
+			var size, align int
+			if size, err = bits(0); err == nil {
+				align, err = bits(1)
+			}
+			table := map[byte]map[int]int{'i': l.ints, 'f': l.floats, 'v': l.vectors}[spec[0]]
+			table[size] = align / 8
+		case 'a':
+			var align int
+			if align, err = bits(1); err == nil && align > 0 {
+				l.AggregateAlign = align / 8
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// intAlign returns the alignment of an integer type. Sizes without their
+// own alignment take the one of the next larger size, or of the largest
+func (l *Layout) intAlign(bits int) int {
+	best, largest := 0, 0
+	for size := range l.ints {
+		if size >= bits && (best == 0 || size < best) {
+			best = size
+		}
+		if size > largest {
+			largest = size
+		}
+	}
+	if best == 0 {
+		best = largest
+	}
+	return l.ints[best]
+}
+
+// sizedAlign returns the alignment of a float or vector type from a table,
+// or the type's size rounded up to a power of two if it isn't in it
+func sizedAlign(table map[int]int, bits int, storeSize int) int {
+	if align, ok := table[bits]; ok {
+		return align
+	}
+	align := 1
+	for align < storeSize {
+		align *= 2
+	}
+	return align
+}
+
+// alignTo rounds n up to a multiple of align
+func alignTo(n int, align int) int {
+	if align <= 1 {
+		return n
+	}
+	return (n + align - 1) / align * align
+}
@@ -0,0 +1,62 @@
+package gtypes
+
+import (
+	"github.com/llir/llvm/ir/types"
+)
+
+// ResultType type is a Geode result type, the value of a `T!` that is either
+// a T or the error that kept one from being made.
+type ResultType struct {
+	// Element type.
+	ElemType types.Type
+
+	// A Geode result type is implemented as a struct type with the fields
+	// value and error. A void result has no value, only the error.
+	//    { elem, error* }
+	*StructType
+}
+
+// NewResult returns a new Geode result type based on the given element type
+// and the type of the errors it can hold.
+func NewResult(elem, err types.Type) *ResultType {
+	var typ *StructType
+	if elem.Equal(types.Void) {
+		typ = NewStruct(err)
+		typ.Names = []string{"error"}
+	} else {
+		typ = NewStruct(elem, err)
+		typ.Names = []string{"value", "error"}
+	}
+	return &ResultType{
+		ElemType:   elem,
+		StructType: typ,
+	}
+}
+
+// ErrorIndex returns the index of the error field.
+func (t *ResultType) ErrorIndex() int {
+	return len(t.Fields) - 1
+}
+
+// ErrorType returns the type of the errors the result can hold.
+func (t *ResultType) ErrorType() types.Type {
+	return t.Fields[t.ErrorIndex()]
+}
+
+// IsVoid reports whether the result has no value, only an error.
+func (t *ResultType) IsVoid() bool {
+	return t.ElemType.Equal(types.Void)
+}
+
+// Underlying returns the underlying LLVM IR type of the Geode result type.
+func (t *ResultType) Underlying() types.Type {
+	return t.StructType.StructType
+}
+
+// Equal reports whether t and u are of equal type.
+func (t *ResultType) Equal(u types.Type) bool {
+	if u, ok := u.(*ResultType); ok {
+		return t.StructType.Equal(u.StructType)
+	}
+	return u.Equal(t.StructType.StructType)
+}
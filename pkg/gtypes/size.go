@@ -6,63 +6,140 @@ import (
 	"github.com/llir/llvm/ir/types"
 )
 
-// ByteCount returns the byte size of the type.
+// SetDataLayout makes the sizes of types follow the datalayout of the
+// target being compiled for
+func SetDataLayout(datalayout string) error {
+	l, err := ParseLayout(datalayout)
+	if err != nil {
+		return err
+	}
+	CurrentLayout = l
+	return nil
+}
+
+// ByteCount returns the byte size of the type, including the padding that
+// keeps the next one in an array aligned, like sizeof in C.
 func ByteCount(t types.Type) int {
+	return CurrentLayout.Size(t)
+}
+
+// AlignOf returns the alignment in bytes of the type.
+func AlignOf(t types.Type) int {
+	return CurrentLayout.Align(t)
+}
+
+// FieldOffsets returns the byte offsets of the fields of the struct type.
+func FieldOffsets(t *types.StructType) []int {
+	offsets, _, _ := CurrentLayout.structLayout(t)
+	return offsets
+}
+
+// Size returns the byte size of the type in the layout
+func (l *Layout) Size(t types.Type) int {
 	switch t := t.(type) {
+	case *StructType:
+		return l.Size(t.StructType)
+	case *SliceType:
+		return l.Size(t.StructType)
+	case *ResultType:
+		return l.Size(t.StructType)
 	case *types.ArrayType:
-		return ArrayByteCount(t)
+		return int(t.Len) * l.Size(t.ElemType)
 	case *types.StructType:
-		return StructByteCount(t)
-	case *types.VoidType:
-		return VoidByteCount(t)
-	case *types.FuncType:
-		return FuncByteCount(t)
-	case *types.LabelType:
-		return LabelByteCount(t)
-	case *types.MetadataType:
-		return MetadataByteCount(t)
+		_, size, _ := l.structLayout(t)
+		return size
+	case *types.VoidType, *types.FuncType, *types.LabelType, *types.MetadataType:
+		// These have no size, only pointers to them do
+		return 0
 	case *types.IntType:
-		return IntByteCount(t)
+		return alignTo(IntByteCount(t), l.Align(t))
 	case *types.FloatType:
-		return FloatByteCount(t)
+		return alignTo(FloatByteCount(t), l.Align(t))
 	case *types.PointerType:
-		return PointerByteCount(t)
+		return l.PointerSize
 	case *types.VectorType:
-		return VectorByteCount(t)
+		return alignTo(VectorByteCount(t), l.Align(t))
+	default:
+		panic(fmt.Errorf("support for type %T not yet implemented", t))
+	}
+}
+
+// Align returns the alignment in bytes of the type in the layout
+func (l *Layout) Align(t types.Type) int {
+	switch t := t.(type) {
+	case *StructType:
+		return l.Align(t.StructType)
 	case *SliceType:
-		return SliceByteCount(t)
+		return l.Align(t.StructType)
+	case *ResultType:
+		return l.Align(t.StructType)
+	case *types.ArrayType:
+		return l.Align(t.ElemType)
+	case *types.StructType:
+		_, _, align := l.structLayout(t)
+		return align
+	case *types.VoidType, *types.FuncType, *types.LabelType, *types.MetadataType:
+		return 1
+	case *types.IntType:
+		return l.intAlign(int(t.BitSize))
+	case *types.FloatType:
+		return sizedAlign(l.floats, FloatBitSize(t), FloatByteCount(t))
+	case *types.PointerType:
+		return l.PointerAlign
+	case *types.VectorType:
+		size := VectorByteCount(t)
+		return sizedAlign(l.vectors, size*8, size)
 	default:
 		panic(fmt.Errorf("support for type %T not yet implemented", t))
 	}
 }
 
+// structLayout returns the offsets of the fields of a struct, its size and
+// its alignment. Fields are padded to their alignment, and the struct is
+// padded to the largest of them, unless it is packed
+func (l *Layout) structLayout(t *types.StructType) ([]int, int, int) {
+	offsets := make([]int, len(t.Fields))
+	size, align := 0, 1
+	if !t.Packed {
+		align = l.AggregateAlign
+	}
+	for i, field := range t.Fields {
+		if !t.Packed {
+			fieldAlign := l.Align(field)
+			size = alignTo(size, fieldAlign)
+			if fieldAlign > align {
+				align = fieldAlign
+			}
+		}
+		offsets[i] = size
+		size += l.Size(field)
+	}
+	return offsets, alignTo(size, align), align
+}
+
 // ArrayByteCount returns the byte size of the type.
 func ArrayByteCount(t *types.ArrayType) int {
-	return 8
+	return ByteCount(t)
 }
 
 // StructByteCount returns the byte size of the type.
 func StructByteCount(t *types.StructType) int {
-	var size int
-	for _, ty := range t.Fields {
-		size += ByteCount(ty)
-	}
-	return size
+	return ByteCount(t)
 }
 
 // VoidByteCount returns the byte size of the type.
 func VoidByteCount(t *types.VoidType) int {
-	return 8
+	return 0
 }
 
 // FuncByteCount returns the byte size of the type.
 func FuncByteCount(t *types.FuncType) int {
-	return 8
+	return 0
 }
 
 // LabelByteCount returns the byte size of the type.
 func LabelByteCount(t *types.LabelType) int {
-	return 8
+	return 0
 }
 
 // MetadataByteCount returns the byte size of the type.
@@ -70,12 +147,14 @@ func MetadataByteCount(t *types.MetadataType) int {
 	return 0
 }
 
-// IntByteCount returns the byte size of the type.
+// IntByteCount returns the number of bytes the type is stored in, without
+// the padding its alignment adds.
 func IntByteCount(t *types.IntType) int {
-	return int(t.BitSize) / 8
+	return (int(t.BitSize) + 7) / 8
 }
 
-// FloatByteCount returns the byte size of the type.
+// FloatByteCount returns the number of bytes the type is stored in, without
+// the padding its alignment adds.
 func FloatByteCount(t *types.FloatType) int {
 	switch t.Kind {
 	case types.FloatKindHalf:
@@ -97,19 +176,27 @@ func FloatByteCount(t *types.FloatType) int {
 
 // PointerByteCount returns the byte size of the type.
 func PointerByteCount(t *types.PointerType) int {
-	return 8
+	return ByteCount(t)
 }
 
-// VectorByteCount returns the byte size of the type.
+// VectorByteCount returns the number of bytes the type is stored in, without
+// the padding its alignment adds.
 func VectorByteCount(t *types.VectorType) int {
-	return ByteCount(t.ElemType) * int(t.Len)
+	var bits int
+	switch elem := t.ElemType.(type) {
+	case *types.IntType:
+		bits = int(elem.BitSize)
+	case *types.FloatType:
+		bits = FloatBitSize(elem)
+	default:
+		bits = ByteCount(elem) * 8
+	}
+	return (bits*int(t.Len) + 7) / 8
 }
 
 // SliceByteCount returns the byte size of the type.
 func SliceByteCount(t *SliceType) int {
-	size := ByteCount(t.ElemType)
-	size += ByteCount(types.I64)
-	return size
+	return ByteCount(t)
 }
 
 // FloatBitSize returns the bit size of the given floating-point type.
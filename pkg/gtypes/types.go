@@ -28,3 +28,21 @@ func IsStruct(t types.Type) bool {
 	}
 	return types.IsStruct(t)
 }
+
+// IsResult reports whether the given type is a Geode result type.
+func IsResult(t types.Type) bool {
+	_, ok := t.(*ResultType)
+	return ok
+}
+
+// FieldsOf returns the struct type that the fields of a class or a result
+// are looked up in.
+func FieldsOf(t types.Type) (*StructType, bool) {
+	switch t := t.(type) {
+	case *StructType:
+		return t, true
+	case *ResultType:
+		return t.StructType, true
+	}
+	return nil, false
+}
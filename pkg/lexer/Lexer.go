@@ -257,6 +257,12 @@ func lexTopLevel(l *Lexer) stateFn {
 		l.backup()
 		return lexNumber
 
+	case r == ':' && l.peek() == '=':
+		// the := declaration operator
+		l.next()
+		l.emit(TokOper)
+		return lexTopLevel
+
 	case r == ':':
 		// l.backup()
 		return lexSymbol
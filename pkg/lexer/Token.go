@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/geode-lang/geode/pkg/util/color"
 	"github.com/llir/llvm/ir/metadata"
@@ -50,6 +51,50 @@ func (t Token) FileInfo() string {
 	return fmt.Sprintf("%s:%d", p, t.Line)
 }
 
+// HasSource returns if the token was lexed from a source file.
+// Tokens that the compiler creates itself do not have one
+func (t Token) HasSource() bool {
+	return t.source != nil
+}
+
+// Path returns the path to the file the token was lexed from
+func (t Token) Path() string {
+	if t.source == nil {
+		return ""
+	}
+	return filepath.Clean(t.source.Path)
+}
+
+// StartColumn returns the column (starting at 1) of the first rune of the token
+func (t Token) StartColumn() int {
+	if t.source == nil {
+		return 0
+	}
+	src := t.source.Bytes()
+	if t.Pos > len(src) {
+		return 0
+	}
+	lineStart := bytes.LastIndexByte(src[:t.Pos], '\n') + 1
+	return utf8.RuneCount(src[lineStart:t.Pos]) + 1
+}
+
+// LineText returns the full text of the source line the token is on
+func (t Token) LineText() string {
+	if t.source == nil {
+		return ""
+	}
+	lines := strings.Split(t.source.String(), "\n")
+	if t.Line < 1 || t.Line > len(lines) {
+		return ""
+	}
+	return lines[t.Line-1]
+}
+
+// Position returns the path:line:column location of a token
+func (t Token) Position() string {
+	return fmt.Sprintf("%s:%d:%d", t.Path(), t.Line, t.StartColumn())
+}
+
 // SyntaxError prints a formatted syntax error
 func (t *Token) SyntaxError() {
 
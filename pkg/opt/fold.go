@@ -0,0 +1,344 @@
+package opt
+
+import (
+	"math/big"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// FoldPass replaces instructions whose operands are all constants
+// with the constant they would compute, ie: `add i64 2, 3` becomes `5`.
+// Conditional branches on a constant condition are turned into
+// unconditional branches so the unreachable pass can clean up after it.
+type FoldPass struct{}
+
+// Name implements Pass.Name
+func (p *FoldPass) Name() string { return "constant folding" }
+
+// Run implements Pass.Run
+func (p *FoldPass) Run(m *ir.Module) bool {
+	changed := false
+	for _, f := range m.Funcs {
+		if foldFunc(f) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func foldFunc(f *ir.Func) bool {
+	changed := false
+	dead := make(map[ir.Instruction]bool)
+
+	for _, blk := range f.Blocks {
+		for _, inst := range blk.Insts {
+			folded := foldInst(inst)
+			if folded == nil {
+				continue
+			}
+			replaceUses(f, inst.(value.Value), folded)
+			dead[inst] = true
+			changed = true
+		}
+
+		if br, ok := blk.Term.(*ir.TermCondBr); ok {
+			if cond, ok := br.Cond.(*constant.Int); ok {
+				target, dropped := br.TargetFalse, br.TargetTrue
+				if cond.X.Sign() != 0 {
+					target, dropped = br.TargetTrue, br.TargetFalse
+				}
+				if dropped != target {
+					removeIncoming(dropped.(*ir.Block), blk)
+				}
+				blk.NewBr(target.(*ir.Block))
+				changed = true
+			}
+		}
+	}
+
+	removeInsts(f, dead)
+	return changed
+}
+
+// removeIncoming removes the values the phis of a block take when it is
+// branched to from pred, which no longer branches to it
+func removeIncoming(blk, pred *ir.Block) {
+	for _, inst := range blk.Insts {
+		phi, ok := inst.(*ir.InstPhi)
+		if !ok {
+			continue
+		}
+		incs := phi.Incs[:0]
+		for _, inc := range phi.Incs {
+			if inc.Pred != pred {
+				incs = append(incs, inc)
+			}
+		}
+		phi.Incs = incs
+	}
+}
+
+// foldInst returns the constant value of an instruction,
+// or nil if it cannot be computed at compile time
+func foldInst(inst ir.Instruction) constant.Constant {
+	switch inst := inst.(type) {
+	case *ir.InstAdd:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, _ uint64) *big.Int { return new(big.Int).Add(x, y) })
+	case *ir.InstSub:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, _ uint64) *big.Int { return new(big.Int).Sub(x, y) })
+	case *ir.InstMul:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, _ uint64) *big.Int { return new(big.Int).Mul(x, y) })
+	case *ir.InstSDiv:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, _ uint64) *big.Int {
+			if y.Sign() == 0 {
+				return nil
+			}
+			return new(big.Int).Quo(x, y)
+		})
+	case *ir.InstSRem:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, _ uint64) *big.Int {
+			if y.Sign() == 0 {
+				return nil
+			}
+			return new(big.Int).Rem(x, y)
+		})
+	case *ir.InstUDiv:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, bits uint64) *big.Int {
+			x, y = unsigned(x, bits), unsigned(y, bits)
+			if y.Sign() == 0 {
+				return nil
+			}
+			return new(big.Int).Quo(x, y)
+		})
+	case *ir.InstURem:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, bits uint64) *big.Int {
+			x, y = unsigned(x, bits), unsigned(y, bits)
+			if y.Sign() == 0 {
+				return nil
+			}
+			return new(big.Int).Rem(x, y)
+		})
+	case *ir.InstShl:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, bits uint64) *big.Int {
+			if !validShift(y, bits) {
+				return nil
+			}
+			return new(big.Int).Lsh(x, uint(y.Uint64()))
+		})
+	case *ir.InstLShr:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, bits uint64) *big.Int {
+			if !validShift(y, bits) {
+				return nil
+			}
+			return new(big.Int).Rsh(unsigned(x, bits), uint(y.Uint64()))
+		})
+	case *ir.InstAShr:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, bits uint64) *big.Int {
+			if !validShift(y, bits) {
+				return nil
+			}
+			return new(big.Int).Rsh(x, uint(y.Uint64()))
+		})
+	case *ir.InstAnd:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, _ uint64) *big.Int { return new(big.Int).And(x, y) })
+	case *ir.InstOr:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, _ uint64) *big.Int { return new(big.Int).Or(x, y) })
+	case *ir.InstXor:
+		return foldInt(inst.X, inst.Y, func(x, y *big.Int, _ uint64) *big.Int { return new(big.Int).Xor(x, y) })
+
+	case *ir.InstFAdd:
+		return foldFloat(inst.X, inst.Y, func(x, y float64) (float64, bool) { return x + y, true })
+	case *ir.InstFSub:
+		return foldFloat(inst.X, inst.Y, func(x, y float64) (float64, bool) { return x - y, true })
+	case *ir.InstFMul:
+		return foldFloat(inst.X, inst.Y, func(x, y float64) (float64, bool) { return x * y, true })
+	case *ir.InstFDiv:
+		return foldFloat(inst.X, inst.Y, func(x, y float64) (float64, bool) { return x / y, y != 0 })
+
+	case *ir.InstICmp:
+		return foldICmp(inst)
+	case *ir.InstFCmp:
+		return foldFCmp(inst)
+
+	case *ir.InstTrunc:
+		return foldIntCast(inst.From, inst.To, false)
+	case *ir.InstSExt:
+		return foldIntCast(inst.From, inst.To, false)
+	case *ir.InstZExt:
+		return foldIntCast(inst.From, inst.To, true)
+	case *ir.InstSIToFP:
+		x, ok := inst.From.(*constant.Int)
+		to, isFloat := inst.To.(*types.FloatType)
+		if !ok || !isFloat || !fitsDouble(to) {
+			return nil
+		}
+		f, _ := new(big.Float).SetInt(signed(x.X, x.Typ.BitSize)).Float64()
+		return constant.NewFloat(to, f)
+	case *ir.InstFPToSI:
+		x, ok := inst.From.(*constant.Float)
+		to, isInt := inst.To.(*types.IntType)
+		if !ok || !isInt || x.NaN || x.X.IsInf() {
+			return nil
+		}
+		i, _ := x.X.Int(nil)
+		return newInt(to, i)
+	}
+	return nil
+}
+
+// signed reinterprets x as a two's complement integer of some bit width
+func signed(x *big.Int, bits uint64) *big.Int {
+	u := unsigned(x, bits)
+	if bits > 1 && u.Bit(int(bits)-1) == 1 {
+		u.Sub(u, new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+	}
+	return u
+}
+
+// unsigned reinterprets x as an unsigned integer of some bit width
+func unsigned(x *big.Int, bits uint64) *big.Int {
+	mod := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	return new(big.Int).Mod(x, mod)
+}
+
+// newInt constructs an integer constant, wrapping the value to the type's width
+func newInt(t *types.IntType, x *big.Int) *constant.Int {
+	if t.BitSize == 1 {
+		return constant.NewBool(unsigned(x, 1).Sign() != 0)
+	}
+	return &constant.Int{Typ: t, X: signed(x, t.BitSize)}
+}
+
+func validShift(y *big.Int, bits uint64) bool {
+	return y.Sign() >= 0 && y.IsUint64() && y.Uint64() < bits
+}
+
+func fitsDouble(t *types.FloatType) bool {
+	return t.Kind == types.FloatKindFloat || t.Kind == types.FloatKindDouble
+}
+
+func foldInt(a, b value.Value, op func(x, y *big.Int, bits uint64) *big.Int) constant.Constant {
+	x, ok := a.(*constant.Int)
+	if !ok {
+		return nil
+	}
+	y, ok := b.(*constant.Int)
+	if !ok || !x.Typ.Equal(y.Typ) {
+		return nil
+	}
+	bits := x.Typ.BitSize
+	res := op(signed(x.X, bits), signed(y.X, bits), bits)
+	if res == nil {
+		return nil
+	}
+	return newInt(x.Typ, res)
+}
+
+func foldFloat(a, b value.Value, op func(x, y float64) (float64, bool)) constant.Constant {
+	x, ok := a.(*constant.Float)
+	if !ok || x.NaN || !fitsDouble(x.Typ) {
+		return nil
+	}
+	y, ok := b.(*constant.Float)
+	if !ok || y.NaN || !x.Typ.Equal(y.Typ) {
+		return nil
+	}
+	xf, _ := x.X.Float64()
+	yf, _ := y.X.Float64()
+	res, ok := op(xf, yf)
+	if !ok {
+		return nil
+	}
+	if x.Typ.Kind == types.FloatKindFloat {
+		res = float64(float32(res))
+	}
+	return constant.NewFloat(x.Typ, res)
+}
+
+func foldIntCast(from value.Value, to types.Type, zext bool) constant.Constant {
+	x, ok := from.(*constant.Int)
+	t, isInt := to.(*types.IntType)
+	if !ok || !isInt {
+		return nil
+	}
+	if zext {
+		return newInt(t, unsigned(x.X, x.Typ.BitSize))
+	}
+	return newInt(t, signed(x.X, x.Typ.BitSize))
+}
+
+func foldICmp(inst *ir.InstICmp) constant.Constant {
+	x, ok := inst.X.(*constant.Int)
+	if !ok {
+		return nil
+	}
+	y, ok := inst.Y.(*constant.Int)
+	if !ok || !x.Typ.Equal(y.Typ) {
+		return nil
+	}
+	bits := x.Typ.BitSize
+	scmp := signed(x.X, bits).Cmp(signed(y.X, bits))
+	ucmp := unsigned(x.X, bits).Cmp(unsigned(y.X, bits))
+
+	var res bool
+	switch inst.Pred {
+	case enum.IPredEQ:
+		res = scmp == 0
+	case enum.IPredNE:
+		res = scmp != 0
+	case enum.IPredSGT:
+		res = scmp > 0
+	case enum.IPredSGE:
+		res = scmp >= 0
+	case enum.IPredSLT:
+		res = scmp < 0
+	case enum.IPredSLE:
+		res = scmp <= 0
+	case enum.IPredUGT:
+		res = ucmp > 0
+	case enum.IPredUGE:
+		res = ucmp >= 0
+	case enum.IPredULT:
+		res = ucmp < 0
+	case enum.IPredULE:
+		res = ucmp <= 0
+	default:
+		return nil
+	}
+	return constant.NewBool(res)
+}
+
+func foldFCmp(inst *ir.InstFCmp) constant.Constant {
+	x, ok := inst.X.(*constant.Float)
+	if !ok || x.NaN {
+		return nil
+	}
+	y, ok := inst.Y.(*constant.Float)
+	if !ok || y.NaN {
+		return nil
+	}
+	cmp := x.X.Cmp(y.X)
+
+	var res bool
+	switch inst.Pred {
+	case enum.FPredOEQ:
+		res = cmp == 0
+	case enum.FPredONE:
+		res = cmp != 0
+	case enum.FPredOGT:
+		res = cmp > 0
+	case enum.FPredOGE:
+		res = cmp >= 0
+	case enum.FPredOLT:
+		res = cmp < 0
+	case enum.FPredOLE:
+		res = cmp <= 0
+	default:
+		return nil
+	}
+	return constant.NewBool(res)
+}
@@ -0,0 +1,214 @@
+package opt
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// Mem2RegPass promotes stack allocations to plain ssa values. This is a
+// simple version of llvm's mem2reg that doesn't insert phi nodes. It only
+// promotes allocas whose address never escapes and that are either only
+// used within a single block, or stored exactly once in the entry block
+// before any load. This covers function arguments and most temporaries.
+type Mem2RegPass struct{}
+
+// Name implements Pass.Name
+func (p *Mem2RegPass) Name() string { return "mem2reg" }
+
+// Run implements Pass.Run
+func (p *Mem2RegPass) Run(m *ir.Module) bool {
+	changed := false
+	for _, f := range m.Funcs {
+		if promoteAllocas(f) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// allocaUse is a load or store of an alloca along with where it lives
+type allocaUse struct {
+	inst  ir.Instruction
+	block *ir.Block
+	index int
+}
+
+type allocaInfo struct {
+	alloca  *ir.InstAlloca
+	loads   []allocaUse
+	stores  []allocaUse
+	escapes bool
+}
+
+func promoteAllocas(f *ir.Func) bool {
+	if len(f.Blocks) == 0 {
+		return false
+	}
+
+	infos := make(map[*ir.InstAlloca]*allocaInfo)
+	order := make([]*allocaInfo, 0)
+
+	for _, blk := range f.Blocks {
+		for _, inst := range blk.Insts {
+			if alloca, ok := inst.(*ir.InstAlloca); ok && alloca.NElems == nil {
+				info := &allocaInfo{alloca: alloca}
+				infos[alloca] = info
+				order = append(order, info)
+			}
+		}
+	}
+	if len(infos) == 0 {
+		return false
+	}
+
+	// Classify every use of every alloca
+	for _, blk := range f.Blocks {
+		for i, inst := range blk.Insts {
+			use := allocaUse{inst, blk, i}
+			switch inst := inst.(type) {
+			case *ir.InstLoad:
+				if info, ok := lookupAlloca(infos, inst.Src); ok {
+					info.loads = append(info.loads, use)
+				}
+				continue
+			case *ir.InstStore:
+				if info, ok := lookupAlloca(infos, inst.Dst); ok {
+					info.stores = append(info.stores, use)
+					// storing a value of some other type through the alloca
+					// can't be expressed without the memory
+					if !types.Equal(inst.Src.Type(), info.alloca.ElemType) {
+						info.escapes = true
+					}
+				}
+				if info, ok := lookupAlloca(infos, inst.Src); ok {
+					info.escapes = true
+				}
+				continue
+			}
+			markEscapes(infos, inst)
+		}
+		if blk.Term != nil {
+			markEscapes(infos, blk.Term)
+		}
+	}
+
+	changed := false
+	dead := make(map[ir.Instruction]bool)
+
+	for _, info := range order {
+		if info.escapes {
+			continue
+		}
+		if promoteLocal(f, info, dead) || promoteSingleStore(f, info, dead) {
+			dead[info.alloca] = true
+			changed = true
+		}
+	}
+
+	removeInsts(f, dead)
+	return changed
+}
+
+func lookupAlloca(infos map[*ir.InstAlloca]*allocaInfo, v value.Value) (*allocaInfo, bool) {
+	alloca, ok := v.(*ir.InstAlloca)
+	if !ok {
+		return nil, false
+	}
+	info, ok := infos[alloca]
+	return info, ok
+}
+
+func markEscapes(infos map[*ir.InstAlloca]*allocaInfo, inst interface{}) {
+	visitOperands(inst, func(op *value.Value) {
+		if info, ok := lookupAlloca(infos, *op); ok {
+			info.escapes = true
+		}
+	})
+}
+
+// promoteLocal handles allocas that are only used within one block
+func promoteLocal(f *ir.Func, info *allocaInfo, dead map[ir.Instruction]bool) bool {
+	var blk *ir.Block
+	for _, uses := range [][]allocaUse{info.loads, info.stores} {
+		for _, use := range uses {
+			if blk == nil {
+				blk = use.block
+			}
+			if use.block != blk {
+				return false
+			}
+		}
+	}
+
+	current := zeroValue(info.alloca.ElemType)
+	if blk == nil {
+		// never used at all
+		return true
+	}
+
+	// A load before the first store would see the value from a previous
+	// trip around a loop, unless we are in the entry block which
+	// can never be branched back to.
+	if blk != f.Blocks[0] && len(info.loads) > 0 {
+		if len(info.stores) == 0 || info.loads[0].index < info.stores[0].index {
+			return false
+		}
+	}
+
+	for _, inst := range blk.Insts {
+		switch inst := inst.(type) {
+		case *ir.InstLoad:
+			if inst.Src == info.alloca {
+				replaceUses(f, inst, current)
+				dead[inst] = true
+			}
+		case *ir.InstStore:
+			if inst.Dst == info.alloca {
+				current = inst.Src
+				dead[inst] = true
+			}
+		}
+	}
+	return true
+}
+
+// promoteSingleStore handles allocas that are stored once
+// in the entry block and then only ever loaded
+func promoteSingleStore(f *ir.Func, info *allocaInfo, dead map[ir.Instruction]bool) bool {
+	if len(info.stores) != 1 {
+		return false
+	}
+	store := info.stores[0]
+	if store.block != f.Blocks[0] {
+		return false
+	}
+	for _, load := range info.loads {
+		if load.block == store.block && load.index < store.index {
+			return false
+		}
+	}
+
+	src := store.inst.(*ir.InstStore).Src
+	for _, load := range info.loads {
+		replaceUses(f, load.inst.(value.Value), src)
+		dead[load.inst] = true
+	}
+	dead[store.inst] = true
+	return true
+}
+
+// zeroValue returns the value a load would see from
+// an alloca that was never stored to
+func zeroValue(t types.Type) value.Value {
+	switch t := t.(type) {
+	case *types.IntType:
+		return constant.NewInt(t, 0)
+	case *types.FloatType:
+		return constant.NewFloat(t, 0)
+	case *types.PointerType:
+		return constant.NewNull(t)
+	}
+	return constant.NewZeroInitializer(t)
+}
@@ -0,0 +1,140 @@
+package opt
+
+import (
+	"reflect"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/value"
+)
+
+var (
+	valueType    = reflect.TypeOf((*value.Value)(nil)).Elem()
+	constType    = reflect.TypeOf((*constant.Constant)(nil)).Elem()
+	incomingType = reflect.TypeOf(&ir.Incoming{})
+	constantPkg  = reflect.TypeOf(ir.Incoming{}).PkgPath() + "/constant"
+)
+
+// visitOperands calls fn with a pointer to every value operand of an
+// instruction or terminator. llir doesn't give us a generic way to walk
+// the operands of an instruction, so we reach into the exported fields
+// that hold values. The pointer lets the caller replace the operand.
+func visitOperands(inst interface{}, fn func(*value.Value)) {
+	v := reflect.ValueOf(inst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	visitStruct(v.Elem(), fn)
+}
+
+func visitStruct(v reflect.Value, fn func(*value.Value)) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		info := t.Field(i)
+
+		if info.PkgPath != "" && !info.Anonymous {
+			// unexported field
+			continue
+		}
+
+		switch {
+		case info.Type == valueType:
+			if !field.IsNil() {
+				fn(field.Addr().Interface().(*value.Value))
+			}
+
+		case info.Type.Kind() == reflect.Slice && info.Type.Elem() == valueType:
+			for j := 0; j < field.Len(); j++ {
+				if !field.Index(j).IsNil() {
+					fn(field.Index(j).Addr().Interface().(*value.Value))
+				}
+			}
+
+		case info.Type.Kind() == reflect.Slice && info.Type.Elem() == incomingType:
+			for j := 0; j < field.Len(); j++ {
+				visitOperands(field.Index(j).Interface(), fn)
+			}
+
+		case info.Anonymous && info.Type.Kind() == reflect.Ptr && !field.IsNil():
+			// Embedded instructions (like the nop in an LLVMComment)
+			visitStruct(field.Elem(), fn)
+		}
+	}
+}
+
+// visitConstants calls fn with every constant operand of a constant
+// expression. Unlike instructions, these are never replaced in place.
+func visitConstants(expr interface{}, fn func(constant.Constant)) {
+	v := reflect.ValueOf(expr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		info := t.Field(i)
+		if info.PkgPath != "" {
+			continue
+		}
+		switch {
+		case info.Type == constType:
+			if !field.IsNil() {
+				fn(field.Interface().(constant.Constant))
+			}
+		case info.Type.Kind() == reflect.Slice && info.Type.Elem() == constType:
+			for j := 0; j < field.Len(); j++ {
+				if !field.Index(j).IsNil() {
+					fn(field.Index(j).Interface().(constant.Constant))
+				}
+			}
+		}
+	}
+}
+
+// isConstantExpr returns if the value is a constant expression that might
+// reference globals, like the getelementptr that string literals use.
+func isConstantExpr(v value.Value) bool {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.PkgPath() == constantPkg
+}
+
+// replaceUses replaces every use of old in a function with new
+func replaceUses(f *ir.Func, old, new value.Value) {
+	replace := func(op *value.Value) {
+		if *op == old {
+			*op = new
+		}
+	}
+	for _, blk := range f.Blocks {
+		for _, inst := range blk.Insts {
+			visitOperands(inst, replace)
+		}
+		if blk.Term != nil {
+			visitOperands(blk.Term, replace)
+		}
+	}
+}
+
+// removeInsts removes all instructions in the set from the function
+func removeInsts(f *ir.Func, dead map[ir.Instruction]bool) {
+	if len(dead) == 0 {
+		return
+	}
+	for _, blk := range f.Blocks {
+		insts := blk.Insts[:0]
+		for _, inst := range blk.Insts {
+			if !dead[inst] {
+				insts = append(insts, inst)
+			}
+		}
+		blk.Insts = insts
+	}
+}
@@ -0,0 +1,69 @@
+// Package opt contains the optimization pipeline that is run over the
+// llvm module after codegen and before the ir is emitted to clang.
+// The passes are intentionally simple. They exist to make the emitted
+// ir smaller and readable, not to replace the optimizations clang
+// does at -O1 and above.
+package opt
+
+import (
+	"fmt"
+
+	"github.com/geode-lang/geode/pkg/util/log"
+	"github.com/llir/llvm/ir"
+)
+
+// maxIterations is the maximum number of times the pipeline will rerun
+// the transforming passes while they keep reporting changes.
+const maxIterations = 10
+
+// Pass is a single transformation over a module
+type Pass interface {
+	// Name returns a short human readable name for the pass
+	Name() string
+	// Run transforms the module and returns whether or not anything changed
+	Run(*ir.Module) bool
+}
+
+// Pipeline is an ordered list of passes that will be run over a module.
+// The passes are run repeatedly until none of them report a change,
+// then the pruning pass removes what is left unused.
+type Pipeline struct {
+	Passes []Pass
+	Prune  *PrunePass
+}
+
+// NewPipeline constructs the default pipeline. The roots are the names of
+// functions that must be kept even if nothing in the module references them.
+func NewPipeline(roots ...string) *Pipeline {
+	p := &Pipeline{}
+	p.Passes = []Pass{
+		&Mem2RegPass{},
+		&FoldPass{},
+		&UnreachablePass{},
+	}
+	p.Prune = NewPrunePass(roots...)
+	return p
+}
+
+// Run the pipeline over some module
+func (p *Pipeline) Run(m *ir.Module) {
+	for i := 0; i < maxIterations; i++ {
+		changed := false
+		for _, pass := range p.Passes {
+			log.Timed(fmt.Sprintf("Pass %s", pass.Name()), func() {
+				if pass.Run(m) {
+					changed = true
+				}
+			})
+		}
+		if !changed {
+			break
+		}
+	}
+
+	if p.Prune != nil {
+		log.Timed(fmt.Sprintf("Pass %s", p.Prune.Name()), func() {
+			p.Prune.Run(m)
+		})
+	}
+}
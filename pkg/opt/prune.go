@@ -0,0 +1,156 @@
+package opt
+
+import (
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/value"
+)
+
+// internalPrefixes are the name prefixes of symbols that the compiler
+// generated itself. Nothing outside of the module can reference these
+// by name, so they are safe to remove when unused.
+var internalPrefixes = []string{
+	"_X",         // mangled functions
+	"_V",         // mangled global variables
+	".str.",      // string literals
+	"type_info_", // info(T) globals
+}
+
+// IsInternal returns if a symbol name was generated by the compiler
+func IsInternal(name string) bool {
+	for _, prefix := range internalPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// PrunePass removes functions and globals that are never referenced.
+// Declarations are always removed when unused. Definitions are only
+// removed if they have an internal name, as a linked c file might
+// reference anything else.
+type PrunePass struct {
+	Roots map[string]bool
+}
+
+// NewPrunePass constructs a prune pass that keeps the given roots alive
+func NewPrunePass(roots ...string) *PrunePass {
+	p := &PrunePass{}
+	p.Roots = make(map[string]bool)
+	for _, root := range roots {
+		p.Roots[root] = true
+	}
+	return p
+}
+
+// Name implements Pass.Name
+func (p *PrunePass) Name() string { return "unused symbol pruning" }
+
+func (p *PrunePass) isRoot(name string, declaration bool) bool {
+	if p.Roots[name] {
+		return true
+	}
+	return !declaration && !IsInternal(name)
+}
+
+// Run implements Pass.Run
+func (p *PrunePass) Run(m *ir.Module) bool {
+	live := make(map[value.Value]bool)
+	work := make([]value.Value, 0)
+
+	mark := func(v value.Value) {
+		if !live[v] {
+			live[v] = true
+			work = append(work, v)
+		}
+	}
+
+	for _, f := range m.Funcs {
+		if p.isRoot(f.Name(), len(f.Blocks) == 0) {
+			mark(f)
+		}
+	}
+	for _, g := range m.Globals {
+		if p.isRoot(g.Name(), g.Init == nil) {
+			mark(g)
+		}
+	}
+
+	for len(work) > 0 {
+		v := work[len(work)-1]
+		work = work[:len(work)-1]
+
+		switch v := v.(type) {
+		case *ir.Func:
+			for _, blk := range v.Blocks {
+				for _, inst := range blk.Insts {
+					markReferences(inst, mark)
+				}
+				if blk.Term != nil {
+					markReferences(blk.Term, mark)
+				}
+			}
+		case *ir.Global:
+			if v.Init != nil {
+				markConstant(v.Init, mark)
+			}
+		}
+	}
+
+	changed := false
+
+	funcs := m.Funcs[:0]
+	for _, f := range m.Funcs {
+		if live[f] {
+			funcs = append(funcs, f)
+		} else {
+			changed = true
+		}
+	}
+	m.Funcs = funcs
+
+	globals := m.Globals[:0]
+	for _, g := range m.Globals {
+		if live[g] {
+			globals = append(globals, g)
+		} else {
+			changed = true
+		}
+	}
+	m.Globals = globals
+
+	return changed
+}
+
+// markReferences marks every function and global an instruction uses
+func markReferences(inst interface{}, mark func(value.Value)) {
+	visitOperands(inst, func(op *value.Value) {
+		markConstant(*op, mark)
+	})
+}
+
+func markConstant(v value.Value, mark func(value.Value)) {
+	switch v := v.(type) {
+	case *ir.Func, *ir.Global:
+		mark(v)
+		return
+	case *constant.Array:
+		for _, elem := range v.Elems {
+			markConstant(elem, mark)
+		}
+		return
+	case *constant.Struct:
+		for _, field := range v.Fields {
+			markConstant(field, mark)
+		}
+		return
+	}
+	if isConstantExpr(v) {
+		visitConstants(v, func(c constant.Constant) {
+			markConstant(c, mark)
+		})
+	}
+}
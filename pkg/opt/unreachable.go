@@ -0,0 +1,85 @@
+package opt
+
+import (
+	"github.com/llir/llvm/ir"
+)
+
+// UnreachablePass removes basic blocks that cannot be reached from the
+// entry block of their function. Codegen leaves these behind after a
+// return in the middle of a block, or when both branches of an if return.
+type UnreachablePass struct{}
+
+// Name implements Pass.Name
+func (p *UnreachablePass) Name() string { return "unreachable block removal" }
+
+// Run implements Pass.Run
+func (p *UnreachablePass) Run(m *ir.Module) bool {
+	changed := false
+	for _, f := range m.Funcs {
+		if removeUnreachable(f) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// ReachableBlocks returns the set of blocks in a function
+// that can be reached from the function's entry block
+func ReachableBlocks(f *ir.Func) map[*ir.Block]bool {
+	reachable := make(map[*ir.Block]bool)
+	if len(f.Blocks) == 0 {
+		return reachable
+	}
+
+	work := []*ir.Block{f.Blocks[0]}
+	for len(work) > 0 {
+		blk := work[len(work)-1]
+		work = work[:len(work)-1]
+		if reachable[blk] {
+			continue
+		}
+		reachable[blk] = true
+		if blk.Term == nil {
+			continue
+		}
+		for _, succ := range blk.Term.Succs() {
+			work = append(work, succ)
+		}
+	}
+	return reachable
+}
+
+func removeUnreachable(f *ir.Func) bool {
+	if len(f.Blocks) == 0 {
+		return false
+	}
+
+	reachable := ReachableBlocks(f)
+	if len(reachable) == len(f.Blocks) {
+		return false
+	}
+
+	blocks := make([]*ir.Block, 0, len(reachable))
+	for _, blk := range f.Blocks {
+		if reachable[blk] {
+			blocks = append(blocks, blk)
+		}
+	}
+	f.Blocks = blocks
+
+	// Phi nodes can't have incoming values from blocks that no longer exist
+	for _, blk := range f.Blocks {
+		for _, inst := range blk.Insts {
+			if phi, ok := inst.(*ir.InstPhi); ok {
+				incs := phi.Incs[:0]
+				for _, inc := range phi.Incs {
+					if pred, ok := inc.Pred.(*ir.Block); ok && reachable[pred] {
+						incs = append(incs, inc)
+					}
+				}
+				phi.Incs = incs
+			}
+		}
+	}
+	return true
+}
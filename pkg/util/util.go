@@ -74,6 +74,12 @@ func StdLibDir() string {
 	return libpath
 }
 
+// FileExists returns if there is a file at a path
+func FileExists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
 // StdLibFile takes a path in the stdlib and
 // joins it to the directory path
 func StdLibFile(p string) string {
@@ -0,0 +1,15 @@
+# argument errors
+is main
+
+func add(int a, int b) int = a + b;
+
+func id(A? val) A = val;
+
+func main int {
+	int x = add(1); # ERROR: incorrect number of arguments .* expected 2, given 1
+	int y = add(1, 2, 3); # ERROR: expected 2, given 3
+	int z = id(); # ERROR: function main:id. expected 1, given 0
+	int w = id(1, 2); # ERROR: expected 1, given 2
+	B v = 3; # ERROR: unknown type "B"
+	return x + y + z + w;
+}
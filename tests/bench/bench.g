@@ -0,0 +1,29 @@
+# geode bench runs the bench blocks of a package and prints how long each
+# iteration takes, in the format benchstat reads. --run picks the ones to run
+is main
+
+include "io"
+
+func sum(int n) int {
+	int total = 0;
+	for int i = 0; i < n; i += 1 {
+		total += i;
+	}
+	return total;
+}
+
+func main int {
+	io:print("%d\n", sum(10));
+	return 0;
+}
+
+bench "sum" {
+	sum(100);
+}
+
+bench "sum big" {
+	sum(10000);
+}
+
+bench "nothing" {
+}
@@ -0,0 +1,9 @@
+# circular class 1
+is main
+
+class Node {
+	int value;
+	Node next; # ERROR: circular reference
+}
+
+func main int = 0;
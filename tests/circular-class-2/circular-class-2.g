@@ -0,0 +1,12 @@
+# circular class 2
+is main
+
+class Left {
+	Right right;
+}
+
+class Right {
+	Left left; # ERROR: circular reference through field left
+}
+
+func main int = 0;
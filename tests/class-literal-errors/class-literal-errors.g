@@ -0,0 +1,36 @@
+# class literal errors
+is main
+
+func two int {
+	return 2;
+}
+
+class Point {
+	int x = 1;
+	int y = two(); # ERROR: the default of field y of class Point must be a constant, not two\(\)
+	string name = nil; # ERROR: cannot use nil as string, which can't be nil
+	int z = "zed"; # ERROR: cannot use "zed" as the default of field z of type int
+	Point*? next;
+}
+
+class Link {
+	int n = 0;
+	Link* next;
+}
+
+class Chain {
+	int n;
+	Link first;
+}
+
+func main int {
+	Point a = Point{x: 1, colour: 2}; # ERROR: class main:Point has no field colour
+	Point b = Point{x: 1, x: 2}; # ERROR: field x is given more than once in class literal
+	Point c = Point{name: 3.5}; # ERROR: cannot use a value of type float as string
+	Link d = Link{next: nil}; # ERROR: cannot use nil as Link\*, which can't be nil
+	int e = int{}; # ERROR: int is not a class, so it can't be made with a class literal
+	Point f = Shape{x: 1}; # ERROR: unknown type "Shape" in class literal
+	Link g = Link{n: 1}; # ERROR: class literal must give field next, as Link\* can't be nil
+	Chain h = Chain{n: 1}; # ERROR: class literal must give field first, as its field next is a Link\* that can't be nil
+	return a.x + b.x + c.x + d.n + e + f.x + g.n + h.n;
+}
@@ -0,0 +1,59 @@
+# class literals and field defaults
+is main
+
+include "std:io"
+
+class Point {
+	int x = 1;
+	int y = -2;
+	float scale = 1.5;
+	string name = "origin";
+	byte mark = 'p';
+	byte*? note = nil;
+}
+
+class Line {
+	Point from;
+	Point to;
+	int width;
+}
+
+Point home;
+
+func show(Point p) {
+	io:print("%s: %d %d %.1f %c\n", p.name, p.x, p.y, p.scale, p.mark);
+}
+
+func twice(int n) int {
+	io:print("twice %d\n", n);
+	return n * 2;
+}
+
+func main int {
+	# declarations and globals start out as the defaults
+	Point p;
+	show(p);
+	show(home);
+
+	# literals give some fields and leave the rest as their defaults
+	q := Point{x: 10, name: "q"};
+	show(q);
+	show(Point{});
+	show(Point{mark: 'z', y: q.x * 3});
+
+	# the values are worked out in the order they are written
+	show(Point{y: twice(2), x: twice(1)});
+
+	# classes in classes have their defaults too
+	Line l = Line{to: Point{x: 5}, width: 3};
+	show(l.from);
+	show(l.to);
+	io:print("width %d\n", l.width);
+
+	l.from = Point{
+		name: "moved",
+		scale: 0.5
+	};
+	show(l.from);
+	return 0;
+}
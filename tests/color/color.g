@@ -0,0 +1,27 @@
+# color:hsv_to_rgb converts a hue, saturation and value to 0-255 RGB
+is main
+
+include "io"
+include "color"
+
+func show(float h, float s, float v) {
+	color:RGB c = color:hsv_to_rgb(h, s, v);
+	io:print("%.0f %.0f %.0f\n", c.r, c.g, c.b);
+}
+
+func main int {
+	show(0.0, 1.0, 1.0);
+	show(120.0, 1.0, 1.0);
+	show(240.0, 1.0, 0.5);
+	show(30.0, 0.0, 1.0);
+	color:RGB a = color:hsv_to_rgb(0.0, 1.0, 1.0);
+	color:RGB b = color:hsv_to_rgb(360.0, 1.0, 1.0);
+	color:RGB c = color:hsv_to_rgb(120.0, 1.0, 1.0);
+	if color:equal(a, b) {
+		io:print("0 and 360 are the same hue\n");
+	}
+	if !color:equal(a, c) {
+		io:print("red isn't green\n");
+	}
+	return 0;
+}
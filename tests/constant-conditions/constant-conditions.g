@@ -0,0 +1,31 @@
+# constant operands to && and ||
+is main
+
+include "std:io"
+
+func main int {
+	x := 3;
+	if true && x > 2 {
+		io:print("true && x\n");
+	}
+	if false && x > 2 {
+		io:print("false && x\n");
+	}
+	if false || x > 2 {
+		io:print("false || x\n");
+	}
+	if true || x > 2 {
+		io:print("true || x\n");
+	}
+	if x > 2 && true {
+		io:print("x && true\n");
+	}
+	if x > 5 || false {
+		io:print("x || false\n");
+	}
+	bool b = true && x < 2;
+	if b {
+		io:print("b\n");
+	}
+	return 0;
+}
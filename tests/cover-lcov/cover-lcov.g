@@ -0,0 +1,25 @@
+# geode cover report turns coverage profiles into an html page or an lcov
+# file. The profile next to this file is the one a run of it writes
+is main
+
+include "io"
+
+func classify(int n) int {
+	if n % 2 == 0 {
+		return 0;
+	}
+	return 1;
+}
+
+func never int {
+	return 7;
+}
+
+func main int {
+	int odd = 0;
+	for int i = 0; i < 5; i += 1 {
+		odd += classify(i);
+	}
+	io:print("%d odd\n", odd);
+	return 0;
+}
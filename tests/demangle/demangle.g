@@ -0,0 +1,23 @@
+# geode demangle turns the mangled symbols of a program, like the ones nm
+# lists, back into the functions they are
+is main
+
+class Point {
+	int x;
+	int y;
+
+	func sum int {
+		return this.x + this.y;
+	}
+}
+
+func scale(Point* p, float by) float {
+	return p.x * by;
+}
+
+func main int {
+	Point p;
+	p.x = 1;
+	p.y = 2;
+	return p.sum() + (scale(&p, 2.0) as int) - 5;
+}
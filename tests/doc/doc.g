@@ -0,0 +1,35 @@
+# geode doc renders the comments above declarations as documentation
+is main
+
+include "io"
+
+# The number of shapes drawn so far
+int drawn = 0;
+
+# A rectangle with its corner at the origin
+class Rect {
+	# How wide the rectangle is
+	int w;
+	# How tall the rectangle is
+	int h;
+
+	# Returns the area of the rectangle
+	func area int {
+		return this.w * this.h;
+	}
+}
+
+# Draws a rectangle and counts it in main:drawn. Uses
+# io:print to show its area
+func draw(Rect r) {
+	io:print("%d\n", r.area());
+	drawn = drawn + 1;
+}
+
+func main int {
+	Rect r;
+	r.w = 2;
+	r.h = 3;
+	draw(r);
+	return 0;
+}
@@ -0,0 +1,10 @@
+is main
+
+include "io"
+
+func main int {
+	int x = 1;
+	io:print("x is at %p\n", &x);
+	assert("x is 2", x == 2);
+	return 0;
+}
@@ -0,0 +1,14 @@
+#include <stdio.h>
+
+#include "export.h"
+
+int main(void) {
+  Vec a = {1, 2};
+  Vec b = {3, 4};
+  Vec sum;
+  vec_add(&a, &b, &sum);
+  printf("%ld %ld\n", (long)sum.x, (long)sum.y);
+  printf("%ld\n", (long)vec_dot(&a, &b));
+  printf("%s\n", greeting("c"));
+  return 0;
+}
@@ -0,0 +1,23 @@
+# a library built with --lib exports its functions to C under their own
+# names, and declares them and its classes in a header
+is export
+
+include "str"
+
+export class Vec {
+	long x;
+	long y;
+}
+
+export func vec_add(Vec* a, Vec* b, Vec* sum) {
+	sum.x = a.x + b.x;
+	sum.y = a.y + b.y;
+}
+
+export func vec_dot(Vec* a, Vec* b) long {
+	return a.x * b.x + a.y * b.y;
+}
+
+export func greeting(string name) string {
+	return str:concat("hello, ", name);
+}
@@ -0,0 +1,15 @@
+# geode fmt --check lists the files that aren't formatted, and --diff shows
+# what formatting them changes. This file is left unformatted on purpose
+is main
+
+include "io"
+
+func add(int a,int b) int {
+    return a+b;
+}
+
+func main int {
+	int x=add(1, 2);
+	if x>2 { io:print("%d\n", x); }
+	return 0;
+}
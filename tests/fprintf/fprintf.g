@@ -0,0 +1,10 @@
+# io:fprintf formats to any open file, like stderr
+is main
+
+include "io"
+
+func main int {
+	io:fprintf(io:stderr, "%d %s\n", 42, "to stderr");
+	io:fprintf(io:stdout, "%d %s\n", 7, "to stdout");
+	return 0;
+}
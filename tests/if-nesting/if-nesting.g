@@ -1,7 +1,5 @@
 is main
 include "io"
-include "mem"
-include "math"
 
 
 func main(int argc) int {
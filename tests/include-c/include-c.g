@@ -0,0 +1,18 @@
+# include c binds a C header as a package named after it, with its
+# functions, structs, enums and constant macros under geode names
+is main
+
+include "io"
+include c "shapes.h"
+link "shapes.c"
+
+func main int {
+	shapes:Rect r;
+	r.width = 3;
+	r.height = 4;
+	io:print("%d\n", shapes:rect_area(&r));
+	shapes:rect_grow(&r, shapes:shapes_sides);
+	io:print("%d %d\n", r.width, r.height);
+	io:print("%d %d %d\n", shapes:shapes_scale, shapes:shape_square, shapes:shape_circle);
+	return 0;
+}
@@ -0,0 +1,8 @@
+#include "shapes.h"
+
+long rect_area(rect *r) { return r->width * r->height; }
+
+void rect_grow(rect *r, long by) {
+  r->width += by;
+  r->height += by;
+}
@@ -0,0 +1,17 @@
+#ifndef SHAPES_H
+#define SHAPES_H
+
+#define SHAPES_SIDES 4
+#define SHAPES_SCALE (SHAPES_SIDES * 2)
+
+enum shape_kind { SHAPE_SQUARE, SHAPE_CIRCLE = 5 };
+
+typedef struct {
+  long width;
+  long height;
+} rect;
+
+long rect_area(rect *r);
+void rect_grow(rect *r, long by);
+
+#endif
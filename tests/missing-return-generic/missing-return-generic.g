@@ -0,0 +1,19 @@
+# missing returns in generic functions
+is main
+
+func forever(int n) int {
+	while true {
+		return n;
+	}
+}
+
+# Generic bodies are only checked once they are compiled for some type
+func pick(T? v, int n) T {
+	if n > 0 {
+		return v;
+	}
+} # ERROR: missing return on some path in function pick
+
+func main int {
+	return forever(1) + pick(3, 0);
+}
@@ -0,0 +1,38 @@
+# missing returns and unreachable code
+is main
+
+func sign(int n) int {
+	if n > 0 {
+		return 1;
+	}
+	if n < 0 {
+		return -1;
+	}
+} # ERROR: missing return on some path in function sign
+
+func both(int n) int {
+	if n > 0 {
+		return 1;
+	} else {
+		return 0;
+	}
+}
+
+func spin(int n) int {
+	while true {
+		n += 1;
+		if n > 10 {
+			return n;
+		}
+	}
+}
+
+func after(int n) int {
+	return n;
+	n += 1; # ERROR: unreachable code after return
+	return n;
+}
+
+func main int {
+	return sign(1) + both(1) + spin(1) + after(1);
+}
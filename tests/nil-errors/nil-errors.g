@@ -0,0 +1,104 @@
+# nil errors
+is main
+
+class Node {
+	int val;
+	Node*? next;
+	Node* self;
+}
+
+class List {
+	int size;
+	Node head;
+}
+
+Node* first; # ERROR: first must be given a value, as Node\* can't be nil \(declare it as Node\*\? to allow nil\)
+Node*? last;
+
+func find(Node*? n, int val) Node*? {
+	while n != nil {
+		if n.val == val {
+			return n;
+		}
+		n = n.next;
+	}
+	return nil;
+}
+
+func bad(Node*? n) int {
+	int a = n.val; # ERROR: n may be nil and is used to access the field val here \(check that n != nil first\)
+	Node* m = n; # ERROR: n may be nil and cannot be used as Node\*
+	Node* z = nil; # ERROR: cannot use nil as Node\*, which can't be nil
+	int b = n.next.val; # ERROR: may be nil and is used to access the field
+	return a + b + m.val + z.val;
+}
+
+func good(Node*? n) int {
+	if n == nil {
+		return 0;
+	}
+	int a = n.val;
+	Node*? next = n.next;
+	if next != nil && next.val > 0 {
+		a = a + next.val;
+	}
+	if next == nil || next.val == 0 {
+		return a;
+	}
+	return a + next.val;
+}
+
+func fields(Node* n) int {
+	int a = n.next.val; # ERROR: n.next may be nil .*copy it into a local variable
+	n.self = nil; # ERROR: cannot use nil as main:Node\*, which can.t be nil
+	n.next = nil;
+	return a;
+}
+
+func loops(Node*? n) int {
+	int total = 0;
+	Node*? p = n;
+	if p != nil {
+		while total < 10 {
+			total = total + p.val; # ERROR: p may be nil
+			p = p.next; # ERROR: p may be nil
+		}
+	}
+	return total;
+}
+
+func ret(Node*? n) Node* {
+	return n; # ERROR: n may be nil and cannot be used as Node\*
+}
+
+func takes(Node* n) int = n.val;
+
+func args(Node*? n) int {
+	int a = takes(n); # ERROR: n may be nil and cannot be used as Node\*
+	int b = takes(nil); # ERROR: cannot use nil as Node\*
+	if n != nil {
+		a = takes(n);
+	}
+	return a + b + find(n, 3).val; # ERROR: find\(n, 3\) may be nil
+}
+
+func ints(int*? p) int {
+	int a = *p; # ERROR: p may be nil and is dereferenced here
+	int b = p[1]; # ERROR: p may be nil and is indexed here
+	return a + b;
+}
+
+func unset int {
+	Node* q; # ERROR: q must be given a value, as Node\* can't be nil
+	Node*? r;
+	List l; # ERROR: l must be given a value, as its field head.self is a Node\* that can't be nil
+	if r != nil {
+		return r.val;
+	}
+	return q.val + l.size;
+}
+
+func main int {
+	Node n; # ERROR: n must be given a value, as its field self is a Node\* that can't be nil
+	return good(&n) + bad(nil) + fields(&n) + loops(&n) + ret(&n).val + args(&n) + ints(nil) + unset();
+}
@@ -0,0 +1,38 @@
+is main
+
+include "io"
+
+class Node {
+	int val;
+	Node*? next;
+}
+
+func length(Node*? n) int {
+	int count = 0;
+	while n != nil {
+		count = count + 1;
+		n = n.next;
+	}
+	return count;
+}
+
+# unchecked says a node is never nil, which the compiler has to believe
+func unchecked(Node*? n) Node* = n as Node*;
+
+func second(Node* n) int {
+	Node* next = unchecked(n.next);
+	return next.val;
+}
+
+func main int {
+	Node a;
+	Node b;
+	a.val = 1;
+	b.val = 2;
+	a.next = &b;
+	b.next = nil;
+	io:print("%d\n", length(&a));
+	io:print("%d\n", second(&a));
+	io:print("%d\n", second(&b));
+	return 0;
+}
@@ -0,0 +1,16 @@
+is main
+
+include "io"
+
+func half(int n) int {
+	if n % 2 != 0 {
+		panic("%d is odd"(n));
+	}
+	return n / 2;
+}
+
+func main int {
+	io:print("%d\n", half(4));
+	io:print("%d\n", half(3));
+	return 0;
+}
@@ -0,0 +1,45 @@
+# result errors
+is main
+
+func half(int n) int! {
+	if n % 2 != 0 {
+		return errorf("%d is odd", n);
+	}
+	return n / 2;
+}
+
+func done(int n) void! {
+	if n == 0 {
+		return 1; # ERROR: function done returns void! so it can only return an error, not a value of type long
+	}
+}
+
+func wrong(int n) int! {
+	if n == 0 {
+		return "zero"; # ERROR: incorrect return value for function wrong. expected: int or an error, given: string
+	}
+	return; # ERROR: function wrong must return a value of type int or an error
+}
+
+func note(int n) {}
+
+func plain(int n) int {
+	half(n); # ERROR: the error returned by half\(n\) is ignored \(pass it on with try, or handle it with or\)
+	int a = try half(n); # ERROR: try can only be used in a function that returns a result, but plain returns int
+	int b = half(n); # ERROR: cannot use a value of type int! as int
+	int c = n or 0; # ERROR: the left side of or must be a result, not a value of type int
+	int d = half(n) or note(n); # ERROR: note\(n\) has no value to use in place of the int of half\(n\)
+	int e = half(n) or half(n + 1); # ERROR: cannot use a value of type int! as int
+	int f = half(n) or panic("odd");
+	done(n) or done(n + 1); # ERROR: the error returned by done\(n \+ 1\) is ignored
+	return a + b + c + d + e + f;
+}
+
+func passes(int n) int! {
+	int a = try n; # ERROR: try needs a result to pass on the error of, not a value of type int
+	return a;
+}
+
+func main int {
+	return 0;
+}
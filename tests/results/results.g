@@ -0,0 +1,72 @@
+is main
+
+include "io"
+include "c"
+
+# a class can hold a result, like any other value
+class Box {
+	int! v;
+	int n;
+}
+
+func half(int n) int! {
+	if n % 2 != 0 {
+		return errorf("%d is odd", n);
+	}
+	return n / 2;
+}
+
+# quarter passes on the error of either half
+func quarter(int n) int! {
+	int h = try half(n);
+	return try half(h);
+}
+
+func positive(int n) void! {
+	if n <= 0 {
+		return errorf("%d is not positive", n);
+	}
+}
+
+func roundtrip(c:FILE* f) long! {
+	try io:write(f, "hello", 5);
+	c:rewind(f);
+	byte* buf = xmalloc(16);
+	return try io:read(f, buf, 16);
+}
+
+func main int {
+	io:print("%d\n", half(4) or -1);
+	io:print("%d\n", half(3) or -1);
+	io:print("%d\n", quarter(8) or -1);
+	io:print("%d\n", quarter(6) or -1);
+
+	r := quarter(6);
+	Error*? e = r.error;
+	if e != nil {
+		io:print("error: %s\n", e.message);
+	}
+
+	positive(1) or io:print("not printed\n");
+	positive(-1) or io:print("-1 is not positive\n");
+
+	f := io:open("/nonexistent/results", "r");
+	e = f.error;
+	if e != nil {
+		io:print("%s (%d)\n", e.message, e.code);
+	}
+
+	c:FILE*? t = c:tmpfile();
+	if t != nil {
+		io:print("read %d bytes\n", roundtrip(t) or -1);
+		io:close(t) or panic("unable to close the file");
+	}
+
+	Box b;
+	b.n = 5;
+	b.v = half(b.n);
+	io:print("%d %d\n", b.v or -1, b.n);
+	b.v = half(10);
+	io:print("%d\n", b.v or -1);
+	return 0;
+}
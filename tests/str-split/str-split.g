@@ -0,0 +1,23 @@
+# str:split breaks a string on any of a set of bytes, leaving out the
+# empty parts, and ends the parts with nil
+is main
+
+include "io"
+include "str"
+
+func show(string s, string sset) {
+	string* parts = str:split(s, sset);
+	for int i = 0; parts[i] != nil; i += 1 {
+		io:print("[%s]", parts[i]);
+	}
+	io:print("\n");
+}
+
+func main int {
+	show("a,b,c", ",");
+	show(",,one,,two,", ",");
+	show("key=value; other=thing", "=; ");
+	show("", ",");
+	show("nothing to split", ",");
+	return 0;
+}
@@ -0,0 +1,7 @@
+# an explicit --target geode has no datalayout for is an error, unlike a
+# toolchain whose own target it doesn't know
+is main
+
+func main int {
+	return 0;
+}
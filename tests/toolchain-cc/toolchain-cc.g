@@ -0,0 +1,10 @@
+# --toolchain=cc builds the ir with llc, and the C files and the program
+# with the C compiler
+is main
+
+include "io"
+
+func main int {
+	io:print("built with cc\n");
+	return 0;
+}
@@ -0,0 +1,7 @@
+# a toolchain file with a key geode doesn't know is rejected, so a typo in
+# it doesn't go unnoticed
+is main
+
+func main int {
+	return 0;
+}
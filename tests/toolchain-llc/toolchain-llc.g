@@ -0,0 +1,10 @@
+# --toolchain=llc builds with llc, a C compiler and ld, even when clang is
+# installed
+is main
+
+include "io"
+
+func main int {
+	io:print("built with llc\n");
+	return 0;
+}
@@ -0,0 +1,6 @@
+# --toolchain only takes the toolchains geode knows how to build with
+is main
+
+func main int {
+	return 0;
+}
@@ -0,0 +1,38 @@
+# type errors are found by the checker before any code is generated
+is main
+
+class Point {
+	int x;
+	int y;
+}
+
+func nothing {
+	return;
+}
+
+func half(float f) float {
+	return f / 2.0;
+}
+
+func count int {
+	return "three"; # ERROR: incorrect return value for function count. expected: int, given: string
+}
+
+func main int {
+	n := 4;
+	Point pt;
+	n = pt; # ERROR: cannot use a value of type main:Point as long
+	v := nothing(); # ERROR: unable to infer the type of v from a void value
+	u = nothing(); # ERROR: unable to assign a void value to u
+	Pointer p; # ERROR: unknown type "Pointer" in declaration of p
+	pt.z = 1; # ERROR: class main:Point has no field z
+	int m = nn; # ERROR: unknown identifier nn \(did you mean n\?\)
+	float f = half(1.0) ^ 2.0; # ERROR: operator \^ is not defined on floating point values
+	int i = n[0]; # ERROR: unable to index into a value of type long
+	if pt { # ERROR: if condition must be a number or a pointer, not main:Point
+		n = 1;
+	}
+	int d = *n; # ERROR: attempt to dereference a non-pointer value of type long
+	undefined(); # ERROR: unknown function undefined
+	return n + m + (f as int) + i + d + count() + pt.x + v + p;
+}
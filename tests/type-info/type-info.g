@@ -0,0 +1,39 @@
+# info gives the layout of a type as the target lays it out, which for
+# x86_64 matches C
+is main
+
+include "io"
+
+class Mixed {
+	byte flag;
+	long count;
+	int small;
+	byte* name;
+	short tiny;
+}
+
+class Packed {
+	byte a;
+	byte b;
+	short c;
+}
+
+func show(string label, TypeInfo* t) {
+	io:print("%s: size %d, align %d\n", label, t.size, t.align);
+	for int i = 0; i < t.fieldcount; i += 1 {
+		io:print("\t%s at %d\n", t.fieldnames[i], t.offsets[i]);
+	}
+}
+
+func main int {
+	show("byte", info(byte));
+	show("short", info(short));
+	show("int", info(int));
+	show("long", info(long));
+	show("float", info(float));
+	show("byte*", info(byte*));
+	show("int!", info(int!));
+	show("Mixed", info(Mixed));
+	show("Packed", info(Packed));
+	return 0;
+}
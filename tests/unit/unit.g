@@ -0,0 +1,27 @@
+# geode test --unit builds the test blocks of a package and runs each of
+# them on its own, so the one that fails doesn't stop the others
+is main
+
+include "io"
+
+func double(int x) int {
+	return x * 2;
+}
+
+func main int {
+	io:print("%d\n", double(21));
+	return 0;
+}
+
+test "double doubles" {
+	assert("double of 2", double(2) == 4);
+	assert("double of -3", double(-3) == -6);
+}
+
+test "double isn't triple" {
+	assert("double of 3", double(3) == 9);
+}
+
+test "test blocks can print" {
+	io:print("a passing test's output isn't shown\n");
+}
@@ -0,0 +1,23 @@
+# --Werror makes warnings fail the build like errors do, but the ones
+# silenced with a geode:ignore comment still aren't reported
+is main
+
+include "io"
+include "str" # ERROR: package "str" is included but never used \[unused-include\]
+include "math" # geode:ignore
+
+func main int {
+	int unused = 1; # ERROR: unused declared but never used \[unused-variable\]
+	int quiet = 2; # geode:ignore unused-variable
+	int x = 3;
+	if x > 2 {
+		int x = 4; # ERROR: declaration of x shadows the variable declared at .*warnings-werror.g:12:6 \[shadow\]
+		io:print("%d\n", x);
+	}
+	if x > 1 {
+		int x = 5; # geode:ignore shadow
+		io:print("%d\n", x);
+	}
+	int wrong = 6; # geode:ignore shadow # ERROR: wrong declared but never used
+	return 0;
+}
@@ -0,0 +1,23 @@
+# warnings don't stop a program from compiling, unless they are
+# silenced with a geode:ignore comment they are printed
+is main
+
+include "io"
+include "str"
+include "math" # geode:ignore unused-include
+
+func main int {
+	int unused = 1;
+	int quiet = 2; # geode:ignore
+	int x = 3;
+	if x > 2 {
+		int x = 4;
+		io:print("%d\n", x);
+	}
+	if x > 1 {
+		int x = 5; # geode:ignore shadow
+		io:print("%d\n", x);
+	}
+	int wrong = 6; # geode:ignore shadow
+	return 0;
+}
@@ -0,0 +1,31 @@
+# --target=wasm32-wasi builds a wasm module, which geode runs with a wasm
+# runtime. Pointers are 32 bits wide, and the runtime allocates with malloc.
+# Building it takes clang, wasm-ld and a wasi sysroot
+is main
+
+include "io"
+include "str"
+
+class Mixed {
+	byte flag;
+	long count;
+	int small;
+	byte* name;
+	short tiny;
+}
+
+func show(string label, TypeInfo* t) {
+	io:print("%s: size %d, align %d\n", label, t.size, t.align);
+	for int i = 0; i < t.fieldcount; i += 1 {
+		io:print("\t%s at %d\n", t.fieldnames[i], t.offsets[i]);
+	}
+}
+
+func main int {
+	show("long", info(long));
+	show("byte*", info(byte*));
+	show("Mixed", info(Mixed));
+	string s = str:concat("hello ", "wasi");
+	io:print("%s\n", s);
+	return 0;
+}